@@ -0,0 +1,309 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"go-api/api/imaging"
+	"go-api/api/storage"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 批量上传默认配置
+const (
+	defaultBatchMaxFiles    = 50
+	defaultBatchMaxMB       = 500
+	defaultBatchConcurrency = 4
+)
+
+// reasonTooManyFiles 批量上传的文件数超出限制
+const reasonTooManyFiles = "too_many_files"
+
+// batchFields 批量上传接受的文件字段
+var batchFields = map[string]bool{
+	"files[]": true,
+	"files":   true,
+}
+
+// zipSignatures zip压缩包的文件签名，后者为空压缩包
+var zipSignatures = [][]byte{
+	[]byte("PK\x03\x04"),
+	[]byte("PK\x05\x06"),
+}
+
+// BatchUpload 批量上传图片
+// 接受 multipart 中可重复的 files[] 字段，zip压缩包会展开后逐个上传；
+// 文件先写入临时文件，再以有限并发写入存储，单个文件失败不影响其他文件
+func BatchUpload(c *gin.Context) {
+	opts, err := parseUploadOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if maxSize := envInt64("UPLOAD_BATCH_MAX_MB", defaultBatchMaxMB) * 1024 * 1024; maxSize > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+	}
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请选择要上传的文件"})
+		return
+	}
+
+	store, err := storage.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "存储服务配置错误: " + err.Error()})
+		return
+	}
+
+	batch := newUploadBatch(c.Request.Context(), store, opts)
+	defer batch.cleanup()
+
+	// 边读取请求边上传已接收的文件；读取中断时停止接收，已上传的文件仍在结果中返回
+	var streamErr *uploadError
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			streamErr = batchStreamError(err)
+			break
+		}
+		if !batchFields[part.FormName()] || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		err = batch.addPart(part)
+		part.Close()
+		if err != nil {
+			streamErr = batchStreamError(err)
+			batch.fail(part.FileName(), streamErr)
+			break
+		}
+	}
+
+	results := batch.wait()
+	if len(results) == 0 {
+		if streamErr != nil {
+			c.JSON(streamErr.Status, streamErr.response())
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请选择要上传的文件"})
+		return
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result["success"] == true {
+			succeeded++
+		}
+	}
+	response := gin.H{
+		"results":   results,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"truncated": batch.truncated,
+	}
+	if streamErr != nil {
+		// 请求未读取完整，之后的文件未接收，需要客户端重新上传
+		response["incomplete"] = true
+		for field, value := range streamErr.response() {
+			response[field] = value
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// batchStreamError 读取请求体中断的原因，超出大小限制时带有 reason
+func batchStreamError(err error) *uploadError {
+	if _, _, ok := asLimitError(err); ok {
+		return newUploadError(http.StatusBadRequest, err)
+	}
+	return &uploadError{Status: http.StatusBadRequest, Message: "读取上传文件失败"}
+}
+
+// uploadBatch 一次批量上传中的文件和结果
+type uploadBatch struct {
+	ctx      context.Context
+	store    storage.ObjectStore
+	opts     uploadOptions
+	limits   imaging.Limits
+	maxFiles int
+
+	semaphore chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	results   []gin.H
+	truncated bool // 文件数超出限制，剩余文件已忽略
+	tempFiles []*os.File
+}
+
+// newUploadBatch 创建批量上传，并发数和文件数上限由环境变量 UPLOAD_BATCH_CONCURRENCY、UPLOAD_BATCH_MAX_FILES 配置
+func newUploadBatch(ctx context.Context, store storage.ObjectStore, opts uploadOptions) *uploadBatch {
+	concurrency := envInt64("UPLOAD_BATCH_CONCURRENCY", defaultBatchConcurrency)
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	return &uploadBatch{
+		ctx:       ctx,
+		store:     store,
+		opts:      opts,
+		limits:    uploadLimits(),
+		maxFiles:  int(envInt64("UPLOAD_BATCH_MAX_FILES", defaultBatchMaxFiles)),
+		semaphore: make(chan struct{}, concurrency),
+	}
+}
+
+// addPart 将文件写入临时文件后加入上传队列，zip压缩包展开为其中的每个文件
+func (b *uploadBatch) addPart(part *multipart.Part) error {
+	file, err := os.CreateTemp("", "batch-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	b.tempFiles = append(b.tempFiles, file)
+
+	size, err := io.Copy(file, part)
+	if err != nil {
+		return err
+	}
+
+	if isZipArchive(file) {
+		b.addZip(file, size, part.FileName())
+		return nil
+	}
+	b.add(part.FileName(), part.FileName(), func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(file, 0, size)), nil
+	})
+	return nil
+}
+
+// addZip 展开zip压缩包，跳过目录和隐藏文件（如 __MACOSX/）
+// 解压后的大小由上传限制逐个检查
+func (b *uploadBatch) addZip(file *os.File, size int64, name string) {
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		b.fail(name, &uploadError{Status: http.StatusBadRequest, Message: "无效的zip压缩包"})
+		return
+	}
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() || isHiddenPath(entry.Name) {
+			continue
+		}
+		if !b.add(name+"/"+entry.Name, path.Base(entry.Name), entry.Open) {
+			return
+		}
+	}
+}
+
+// add 加入上传任务，返回 false 表示文件数已超出限制
+func (b *uploadBatch) add(name, filename string, open func() (io.ReadCloser, error)) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.truncated {
+		return false
+	}
+	index := len(b.results)
+	b.results = append(b.results, gin.H{"filename": name})
+	if b.maxFiles > 0 && index >= b.maxFiles {
+		b.truncated = true
+		b.results[index] = batchFailure(name, &uploadError{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("单次最多上传%d个文件，剩余文件已忽略", b.maxFiles),
+			Reason:  reasonTooManyFiles,
+		})
+		return false
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.semaphore <- struct{}{}
+		defer func() { <-b.semaphore }()
+
+		result := b.upload(name, filename, open)
+		b.mu.Lock()
+		b.results[index] = result
+		b.mu.Unlock()
+	}()
+	return true
+}
+
+// upload 上传单个文件并构建结果
+func (b *uploadBatch) upload(name, filename string, open func() (io.ReadCloser, error)) gin.H {
+	reader, err := open()
+	if err != nil {
+		return batchFailure(name, &uploadError{Status: http.StatusBadRequest, Message: "读取文件失败: " + err.Error()})
+	}
+	defer reader.Close()
+
+	response, uploadErr := saveImage(b.ctx, b.store, reader, filename, b.opts, b.limits)
+	if uploadErr != nil {
+		return batchFailure(name, uploadErr)
+	}
+	response["filename"] = name
+	response["success"] = true
+	return response
+}
+
+// fail 记录无法上传的文件
+func (b *uploadBatch) fail(name string, err *uploadError) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.results = append(b.results, batchFailure(name, err))
+}
+
+// wait 等待所有上传完成并返回按接收顺序排列的结果
+func (b *uploadBatch) wait() []gin.H {
+	b.wg.Wait()
+	return b.results
+}
+
+// cleanup 删除临时文件
+func (b *uploadBatch) cleanup() {
+	for _, file := range b.tempFiles {
+		file.Close()
+		os.Remove(file.Name())
+	}
+}
+
+// batchFailure 构建单个文件的失败结果
+func batchFailure(name string, err *uploadError) gin.H {
+	result := err.response()
+	result["filename"] = name
+	result["success"] = false
+	return result
+}
+
+// isZipArchive 根据文件签名判断是否为zip压缩包
+func isZipArchive(file io.ReaderAt) bool {
+	header := make([]byte, 4)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return false
+	}
+	for _, signature := range zipSignatures {
+		if bytes.Equal(header, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHiddenPath 检查路径中是否有以 . 或 __ 开头的部分
+func isHiddenPath(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") || strings.HasPrefix(segment, "__") {
+			return true
+		}
+	}
+	return false
+}
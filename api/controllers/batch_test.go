@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// batchResponse 批量上传响应
+type batchResponse struct {
+	Results []struct {
+		Filename string `json:"filename"`
+		Success  bool   `json:"success"`
+		Reason   string `json:"reason"`
+	} `json:"results"`
+	Succeeded  int    `json:"succeeded"`
+	Failed     int    `json:"failed"`
+	Truncated  bool   `json:"truncated"`
+	Incomplete bool   `json:"incomplete"`
+	Error      string `json:"error"`
+}
+
+// zipBytes 返回包含指定文件的zip压缩包
+func zipBytes(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, data := range files {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.Write(data)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// decodeBatch 解析批量上传响应
+func decodeBatch(t *testing.T, body []byte) batchResponse {
+	t.Helper()
+	var response batchResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("%v: %s", err, body)
+	}
+	return response
+}
+
+func TestBatchUpload(t *testing.T) {
+	t.Setenv("STORAGE_CONTENT_ADDRESSED", "false")
+	newTestStore(t)
+	archive := zipBytes(t, map[string][]byte{
+		"dir/c.png":         pngBytes(t, 8, 8),
+		"__MACOSX/._c.png":  []byte("resource fork"),
+		".hidden.png":       pngBytes(t, 8, 8),
+		"dir/not-image.txt": []byte("text"),
+	})
+	recorder := postFiles(t, BatchUpload, "/api/uploads/batch?variants=false",
+		formFile{"files[]", "a.png", pngBytes(t, 8, 8)},
+		formFile{"other", "ignored.png", pngBytes(t, 8, 8)},
+		formFile{"files[]", "b.txt", []byte("not an image")},
+		formFile{"files[]", "album.zip", archive},
+	)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", recorder.Code, recorder.Body)
+	}
+	response := decodeBatch(t, recorder.Body.Bytes())
+	want := map[string]bool{"a.png": true, "b.txt": false, "album.zip/dir/c.png": true, "album.zip/dir/not-image.txt": false}
+	if len(response.Results) != len(want) {
+		t.Fatalf("results = %+v", response.Results)
+	}
+	for _, result := range response.Results {
+		if success, ok := want[result.Filename]; !ok || success != result.Success {
+			t.Errorf("result %+v", result)
+		}
+	}
+	if response.Results[0].Filename != "a.png" || response.Results[1].Filename != "b.txt" {
+		t.Errorf("results not in receive order: %+v", response.Results)
+	}
+	if response.Succeeded != 2 || response.Failed != 2 || response.Truncated || response.Incomplete {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestBatchUploadMaxFiles(t *testing.T) {
+	t.Setenv("UPLOAD_BATCH_MAX_FILES", "1")
+	newTestStore(t)
+	recorder := postFiles(t, BatchUpload, "/api/uploads/batch?variants=false",
+		formFile{"files[]", "a.png", pngBytes(t, 8, 8)},
+		formFile{"files[]", "b.png", pngBytes(t, 8, 8)},
+		formFile{"files[]", "c.png", pngBytes(t, 8, 8)},
+	)
+	response := decodeBatch(t, recorder.Body.Bytes())
+	if !response.Truncated || len(response.Results) != 2 || response.Results[1].Reason != reasonTooManyFiles {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestBatchUploadInterrupted(t *testing.T) {
+	newTestStore(t)
+	body, contentType := multipartBody(t,
+		formFile{"files[]", "a.png", pngBytes(t, 8, 8)},
+		formFile{"files[]", "b.png", pngBytes(t, 8, 8)},
+	)
+	// 请求体在第二个文件中途中断
+	second := bytes.LastIndex(body, []byte(`filename="b.png"`))
+	truncated := body[:second+60]
+
+	recorder := postBody(BatchUpload, "/api/uploads/batch?variants=false", truncated, contentType)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", recorder.Code, recorder.Body)
+	}
+	response := decodeBatch(t, recorder.Body.Bytes())
+	if !response.Incomplete || response.Error == "" {
+		t.Errorf("response = %+v", response)
+	}
+	if len(response.Results) != 2 || !response.Results[0].Success || response.Results[1].Success {
+		t.Errorf("results = %+v", response.Results)
+	}
+
+	// 请求体格式错误、尚未读到任何文件时直接返回错误
+	recorder = postBody(BatchUpload, "/api/uploads/batch", []byte("--broken"), contentType)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, body = %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestIsHiddenPath(t *testing.T) {
+	tests := map[string]bool{
+		"a.png":            false,
+		"dir/a.png":        false,
+		".DS_Store":        true,
+		"dir/.a.png":       true,
+		"__MACOSX/a.png":   true,
+		"dir/__init__.png": true,
+	}
+	for name, want := range tests {
+		if got := isHiddenPath(name); got != want {
+			t.Errorf("isHiddenPath(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-api/api/generator"
+	"go-api/api/tasks"
+	"go-api/api/webhooks"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// generationCallbackURL 返回提交给生成服务的回调地址，共享密钥通过 token 查询参数传递
+// 未配置 GENERATION_CALLBACK_URL 时返回空字符串，由提交者自行接收回调
+func generationCallbackURL() (string, error) {
+	callbackURL := os.Getenv("GENERATION_CALLBACK_URL")
+	if callbackURL == "" {
+		return "", nil
+	}
+	secret := os.Getenv("GENERATION_CALLBACK_SECRET")
+	if secret == "" {
+		return "", fmt.Errorf("已配置 GENERATION_CALLBACK_URL 但缺少 GENERATION_CALLBACK_SECRET")
+	}
+
+	parsed, err := url.Parse(callbackURL)
+	if err != nil {
+		return "", fmt.Errorf("回调地址无效: %v", err)
+	}
+	query := parsed.Query()
+	query.Set("token", secret)
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
+
+// GenerationCallback 接收生成服务推送的任务结果
+// 按回调内容更新任务记录并保存生成结果，任务首次结束时通过webhook通知提交者的回调地址
+func GenerationCallback(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取回调内容失败"})
+		return
+	}
+
+	imageGenerator, err := generator.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "生成服务配置错误: " + err.Error()})
+		return
+	}
+	parser, ok := imageGenerator.(generator.CallbackParser)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "生成服务不支持回调"})
+		return
+	}
+	task, err := parser.ParseCallback(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的回调内容: " + err.Error()})
+		return
+	}
+
+	store, err := tasks.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "任务存储配置错误: " + err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	record, err := store.Update(ctx, task.ID, func(record *tasks.Record) error {
+		record.Apply(task)
+		return nil
+	})
+	if errors.Is(err, tasks.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务记录失败: " + err.Error()})
+		return
+	}
+
+	notifyTaskDone(ctx, hostTaskResults(ctx, record))
+
+	c.JSON(http.StatusOK, generationResponse(gin.H{"taskId": task.ID}))
+}
+
+// notifyTaskDone 任务首次结束时通过webhook通知提交者的回调地址，返回最新记录
+// 只在服务自身接收生成服务回调时通知，否则生成服务已直接回调提交者
+func notifyTaskDone(ctx context.Context, record *tasks.Record) *tasks.Record {
+	if !record.Done() || record.CallbackURL == "" || record.NotifiedAt != nil || os.Getenv("GENERATION_CALLBACK_URL") == "" {
+		return record
+	}
+	store, err := tasks.Default()
+	if err != nil {
+		return record
+	}
+	// 先标记为已通知，避免并发的查询和回调重复投递
+	claimed := false
+	claimedRecord, err := store.Update(ctx, record.ID, func(record *tasks.Record) error {
+		if record.NotifiedAt == nil {
+			now := time.Now().UTC()
+			record.NotifiedAt = &now
+			claimed = true
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("更新任务记录失败: %s: %v", record.ID, err)
+		return record
+	}
+	if !claimed {
+		return claimedRecord
+	}
+
+	delivery, dispatchErr := notifyWebhook(ctx, webhooks.EventGenerationCompleted, record.CallbackURL, generationResponse(taskInfo(claimedRecord.Task())))
+	updated, err := store.Update(ctx, record.ID, func(record *tasks.Record) error {
+		if dispatchErr != nil {
+			// 未创建投递记录，下次查询或回调时重试
+			record.NotifiedAt = nil
+			return nil
+		}
+		record.DeliveryID = delivery.ID
+		return nil
+	})
+	if err != nil {
+		log.Printf("更新任务记录失败: %s: %v", record.ID, err)
+		return claimedRecord
+	}
+	return updated
+}
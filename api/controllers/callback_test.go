@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go-api/api/generator"
+	"go-api/api/tasks"
+	"go-api/api/webhooks"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// callbackReceiver 记录提交者回调地址收到的请求
+type callbackReceiver struct {
+	mu     sync.Mutex
+	bodies [][]byte
+}
+
+func (r *callbackReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	r.bodies = append(r.bodies, body)
+	r.mu.Unlock()
+}
+
+func (r *callbackReceiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.bodies)
+}
+
+// newCallbackTest 在 newGenerationTest 基础上由服务接收生成服务回调，并将通知投递到本地的回调接收方
+func newCallbackTest(t *testing.T) (*tasks.MemoryStore, *webhooks.MemoryStore, *callbackReceiver, string) {
+	t.Helper()
+	_, store := newGenerationTest(t)
+	t.Setenv("GENERATION_CALLBACK_URL", "https://api.example.com/api/callbacks/generation")
+	t.Setenv("GENERATION_CALLBACK_SECRET", "s3cret")
+
+	receiver := &callbackReceiver{}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	deliveries := webhooks.NewMemoryStore()
+	dispatcher := webhooks.NewDispatcher(deliveries)
+	// 测试服务监听回环地址，不使用只连接公网地址的客户端
+	dispatcher.Client = server.Client()
+	webhooks.SetDefault(dispatcher)
+	return store, deliveries, receiver, server.URL
+}
+
+// postCallback 发送生成服务回调
+func postCallback(body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/callbacks/generation", bytes.NewReader([]byte(body)))
+	GenerationCallback(c)
+	return recorder
+}
+
+func TestGenerationCallbackURL(t *testing.T) {
+	t.Setenv("GENERATION_CALLBACK_URL", "")
+	if callbackURL, err := generationCallbackURL(); callbackURL != "" || err != nil {
+		t.Errorf("unconfigured = %q, %v", callbackURL, err)
+	}
+
+	t.Setenv("GENERATION_CALLBACK_URL", "https://api.example.com/api/callbacks/generation?v=1")
+	t.Setenv("GENERATION_CALLBACK_SECRET", "")
+	if _, err := generationCallbackURL(); err == nil {
+		t.Error("callback URL without secret should fail")
+	}
+
+	t.Setenv("GENERATION_CALLBACK_SECRET", "a&b")
+	callbackURL, err := generationCallbackURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := url.Parse(callbackURL)
+	if parsed.Query().Get("token") != "a&b" || parsed.Query().Get("v") != "1" {
+		t.Errorf("callback URL = %s", callbackURL)
+	}
+}
+
+func TestGenerateImageWithServiceCallback(t *testing.T) {
+	newCallbackTest(t)
+
+	// 由服务接收回调时提交者可以不提供回调地址
+	recorder := serveJSON(GenerateImage, http.MethodPost, "/api/generate-image", gin.H{
+		"imageUrls": []string{"https://example.com/in.png"},
+		"prompt":    "a cat",
+		"size":      "1:1",
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestGenerationCallback(t *testing.T) {
+	store, deliveries, receiver, callbackURL := newCallbackTest(t)
+	ctx := context.Background()
+	request := generator.Request{Prompt: "a cat", CallbackURL: callbackURL}
+	if err := store.Create(ctx, tasks.NewRecord(generator.ProviderMock, request, &generator.Task{ID: "t1", Status: generator.StatusGenerating}, "alice")); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"taskId":"t1","status":"succeeded","progress":1,"resultUrls":["https://example.com/r.png"]}`
+	recorder := postCallback(body)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", recorder.Code, recorder.Body)
+	}
+
+	record, err := store.Get(ctx, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != generator.StatusSucceeded || len(record.ResultURLs) != 1 || record.NotifiedAt == nil || record.DeliveryID == "" {
+		t.Fatalf("record = %+v", record)
+	}
+
+	delivery, err := deliveries.Get(ctx, record.DeliveryID)
+	if err != nil || delivery.Status != webhooks.StatusSucceeded || delivery.Event != webhooks.EventGenerationCompleted {
+		t.Fatalf("delivery = %+v, %v", delivery, err)
+	}
+	if receiver.count() != 1 {
+		t.Fatalf("callback received %d times, want 1", receiver.count())
+	}
+	var payload struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(receiver.bodies[0], &payload)
+	if payload.Data["taskId"] != "t1" || payload.Data["status"] != taskStatusSuccess {
+		t.Errorf("payload = %s", receiver.bodies[0])
+	}
+
+	// 重复的回调不再通知提交者
+	if recorder := postCallback(body); recorder.Code != http.StatusOK {
+		t.Fatalf("repeated callback status = %d", recorder.Code)
+	}
+	if receiver.count() != 1 {
+		t.Errorf("callback received %d times after repeat, want 1", receiver.count())
+	}
+}
+
+func TestGenerationCallbackErrors(t *testing.T) {
+	newCallbackTest(t)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"invalid body", `not json`, http.StatusBadRequest},
+		{"missing status", `{"taskId":"t1"}`, http.StatusBadRequest},
+		{"unknown task", `{"taskId":"missing","status":"succeeded"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if recorder := postCallback(tt.body); recorder.Code != tt.want {
+				t.Errorf("status = %d, want %d, body %s", recorder.Code, tt.want, recorder.Body)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package controllers
 
 import (
+	"go-api/api/generator"
+	"go-api/api/storage"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,3 +18,34 @@ func Ping(c *gin.Context) {
 		"message": "pong",
 	})
 }
+
+// Health 返回服务健康状态和存储配置检查结果，存储配置无效时返回503
+// 生成服务的配置检查结果只作为参考，不影响状态
+func Health(c *gin.Context) {
+	report := storage.ValidateConfig()
+	generatorReport := generator.ValidateConfig()
+	if !report.OK() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":    "misconfigured",
+			"storage":   report,
+			"generator": generatorReport,
+		})
+		return
+	}
+
+	if _, err := storage.Default(); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":    "unavailable",
+			"storage":   report,
+			"generator": generatorReport,
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "ok",
+		"storage":   report,
+		"generator": generatorReport,
+	})
+}
@@ -0,0 +1,15 @@
+package controllers
+
+import (
+	"os"
+	"strconv"
+)
+
+// envInt64 读取整数环境变量，未配置或无效时返回默认值
+func envInt64(name string, defaultValue int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(name), 10, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,289 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-api/api/generator"
+	"go-api/api/tasks"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImageRequest 定义请求结构
+type ImageRequest struct {
+	ImageUrls   []string `json:"imageUrls" binding:"required"` // 线上图片URL列表
+	Prompt      string   `json:"prompt" binding:"required"`    // 提示词
+	Size        string   `json:"size" binding:"required"`      // 图片尺寸
+	CallbackURL string   `json:"callBackUrl"`                  // 回调地址，配置 GENERATION_CALLBACK_URL 时可省略
+}
+
+// 任务详情中兼容kieai的状态
+const (
+	taskStatusGenerating = "GENERATING"
+	taskStatusSuccess    = "SUCCESS"
+	taskStatusFailed     = "GENERATE_FAILED"
+	taskStatusCanceled   = "CANCELED"
+)
+
+// GenerateImage 处理图片生成请求
+// 通过 IMAGE_GENERATOR 配置的生成服务提交任务，响应保持kieai接口的格式
+func GenerateImage(c *gin.Context) {
+	var req ImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+
+	// 验证图片URL列表
+	if len(req.ImageUrls) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "至少需要一张图片"})
+		return
+	}
+
+	// 配置了服务自身的回调地址时由服务接收回调，否则提交者必须提供回调地址
+	callbackURL, err := generationCallbackURL()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "回调配置错误: " + err.Error()})
+		return
+	}
+	if callbackURL == "" && req.CallbackURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "回调地址不能为空"})
+		return
+	}
+	if err := validateCallbackURL(req.CallbackURL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	imageGenerator, err := generator.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "生成服务配置错误: " + err.Error()})
+		return
+	}
+
+	request := generator.Request{
+		ImageURLs:   req.ImageUrls,
+		Prompt:      req.Prompt,
+		Size:        req.Size,
+		CallbackURL: req.CallbackURL,
+	}
+	providerRequest := request
+	if callbackURL != "" {
+		providerRequest.CallbackURL = callbackURL
+	}
+	task, err := imageGenerator.Submit(c.Request.Context(), providerRequest)
+	if err != nil {
+		writeGeneratorError(c, err)
+		return
+	}
+
+	// 记录任务，失败时不影响已提交的任务
+	createTaskRecord(c.Request.Context(), tasks.NewRecord(imageGenerator.Name(), request, task, callerID(c)))
+
+	c.JSON(http.StatusOK, generationResponse(gin.H{"taskId": task.ID}))
+}
+
+// GetTaskInfo 获取任务信息
+// 已结束的任务直接从任务记录返回；未结束时向生成服务查询并更新记录，生成服务已清除的任务从记录返回。
+// 任务成功后结果保存到存储后端，resultUrls 返回永久地址
+func GetTaskInfo(c *gin.Context) {
+	// 获取任务ID
+	taskId := c.Query("taskId")
+	if taskId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "任务ID不能为空"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	record := getTaskRecord(ctx, taskId)
+	if record != nil && record.Done() {
+		record = notifyTaskDone(ctx, hostTaskResults(ctx, record))
+		c.JSON(http.StatusOK, generationResponse(taskInfo(record.Task())))
+		return
+	}
+
+	imageGenerator, err := generator.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "生成服务配置错误: " + err.Error()})
+		return
+	}
+
+	task, err := imageGenerator.Status(ctx, taskId)
+	if errors.Is(err, generator.ErrTaskNotFound) && record != nil {
+		c.JSON(http.StatusOK, generationResponse(taskInfo(record.Task())))
+		return
+	}
+	if err != nil {
+		writeGeneratorError(c, err)
+		return
+	}
+
+	if record != nil {
+		if updated := updateTaskRecord(ctx, taskId, task); updated != nil {
+			task = notifyTaskDone(ctx, hostTaskResults(ctx, updated)).Task()
+		}
+	}
+	c.JSON(http.StatusOK, generationResponse(taskInfo(task)))
+}
+
+// GetTask 返回任务状态和结果，不包含提示词、回调地址和提交者等提交参数
+func GetTask(c *gin.Context) {
+	store, err := tasks.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "任务存储配置错误: " + err.Error()})
+		return
+	}
+
+	record, err := store.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, tasks.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取任务记录失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, record.Task())
+}
+
+// CancelTask 取消生成任务
+func CancelTask(c *gin.Context) {
+	taskId := c.Query("taskId")
+	if taskId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "任务ID不能为空"})
+		return
+	}
+
+	imageGenerator, err := generator.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "生成服务配置错误: " + err.Error()})
+		return
+	}
+
+	if err := imageGenerator.Cancel(c.Request.Context(), taskId); err != nil {
+		writeGeneratorError(c, err)
+		return
+	}
+	if record := updateTaskRecord(c.Request.Context(), taskId, &generator.Task{ID: taskId, Status: generator.StatusCanceled}); record != nil {
+		notifyTaskDone(c.Request.Context(), record)
+	}
+
+	c.JSON(http.StatusOK, generationResponse(gin.H{"taskId": taskId}))
+}
+
+// generationResponse 按kieai接口的格式包装响应，保持与原有客户端兼容
+func generationResponse(data interface{}) gin.H {
+	return gin.H{"code": http.StatusOK, "msg": "success", "data": data}
+}
+
+// taskInfo 构建兼容kieai任务详情格式的数据
+func taskInfo(task *generator.Task) gin.H {
+	status, successFlag := taskStatusGenerating, 0
+	switch task.Status {
+	case generator.StatusSucceeded:
+		status, successFlag = taskStatusSuccess, 1
+	case generator.StatusFailed:
+		status, successFlag = taskStatusFailed, 2
+	case generator.StatusCanceled:
+		status, successFlag = taskStatusCanceled, 2
+	}
+
+	resultURLs := task.ResultURLs
+	if resultURLs == nil {
+		resultURLs = []string{}
+	}
+	info := gin.H{
+		"taskId":       task.ID,
+		"status":       status,
+		"successFlag":  successFlag,
+		"progress":     fmt.Sprintf("%.2f", task.Progress),
+		"response":     gin.H{"resultUrls": resultURLs},
+		"errorMessage": task.Error,
+	}
+	if !task.CreatedAt.IsZero() {
+		info["createTime"] = task.CreatedAt.UnixMilli()
+	}
+	if !task.CompletedAt.IsZero() {
+		info["completeTime"] = task.CompletedAt.UnixMilli()
+	}
+	return info
+}
+
+// writeGeneratorError 按错误类型返回生成服务的错误响应
+// 生成服务返回的业务错误保持kieai的 code、msg 格式
+func writeGeneratorError(c *gin.Context, err error) {
+	var providerErr *generator.ProviderError
+	switch {
+	case errors.Is(err, generator.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, generator.ErrCancelNotSupported):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	case errors.As(err, &providerErr):
+		status := providerErr.StatusCode
+		if status < http.StatusOK {
+			status = http.StatusBadGateway
+		}
+		c.JSON(status, gin.H{"code": providerErr.Code, "msg": providerErr.Message})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": "请求生成服务失败: " + err.Error()})
+	}
+}
+
+// callerID 返回提交者标识，取自 X-Uploader 请求头，未提供时为客户端IP
+func callerID(c *gin.Context) string {
+	if caller := c.GetHeader("X-Uploader"); caller != "" {
+		return caller
+	}
+	return c.ClientIP()
+}
+
+// createTaskRecord 写入任务记录，失败时只记录日志
+func createTaskRecord(ctx context.Context, record *tasks.Record) {
+	store, err := tasks.Default()
+	if err != nil {
+		log.Printf("任务存储配置错误: %v", err)
+		return
+	}
+	if err := store.Create(ctx, record); err != nil {
+		log.Printf("写入任务记录失败: %s: %v", record.ID, err)
+	}
+}
+
+// getTaskRecord 读取任务记录，不存在或读取失败时返回 nil
+func getTaskRecord(ctx context.Context, id string) *tasks.Record {
+	store, err := tasks.Default()
+	if err != nil {
+		log.Printf("任务存储配置错误: %v", err)
+		return nil
+	}
+	record, err := store.Get(ctx, id)
+	if err != nil {
+		if !errors.Is(err, tasks.ErrNotFound) {
+			log.Printf("读取任务记录失败: %s: %v", id, err)
+		}
+		return nil
+	}
+	return record
+}
+
+// updateTaskRecord 按生成服务返回的状态更新任务记录，记录不存在或更新失败时返回 nil
+func updateTaskRecord(ctx context.Context, id string, task *generator.Task) *tasks.Record {
+	store, err := tasks.Default()
+	if err != nil {
+		return nil
+	}
+	record, err := store.Update(ctx, id, func(record *tasks.Record) error {
+		record.Apply(task)
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, tasks.ErrNotFound) {
+			log.Printf("更新任务记录失败: %s: %v", id, err)
+		}
+		return nil
+	}
+	return record
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"go-api/api/generator"
+	"go-api/api/tasks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newGenerationTest 使用模拟生成服务和进程内任务存储，不保存生成结果、不接收回调
+func newGenerationTest(t *testing.T) (*generator.Mock, *tasks.MemoryStore) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("GENERATION_CALLBACK_URL", "")
+	t.Setenv("GENERATION_REHOST", "false")
+
+	mock := generator.NewMock()
+	mock.ResultURL = "https://example.com/result.png"
+	generator.SetDefault(mock)
+	store := tasks.NewMemoryStore()
+	tasks.SetDefault(store)
+	return mock, store
+}
+
+// generationData 解析兼容kieai格式的响应中的 data
+func generationData(t *testing.T, body []byte) map[string]interface{} {
+	t.Helper()
+	var response struct {
+		Code int                    `json:"code"`
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("invalid response %s: %v", body, err)
+	}
+	if response.Code != http.StatusOK {
+		t.Fatalf("code = %d, body %s", response.Code, body)
+	}
+	return response.Data
+}
+
+func TestGenerateImageWithMock(t *testing.T) {
+	_, store := newGenerationTest(t)
+
+	recorder := serveJSON(GenerateImage, http.MethodPost, "/api/generate-image", gin.H{
+		"imageUrls":   []string{"https://example.com/in.png"},
+		"prompt":      "a cat",
+		"size":        "1:1",
+		"callBackUrl": "https://example.com/callback",
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", recorder.Code, recorder.Body)
+	}
+	taskID, _ := generationData(t, recorder.Body.Bytes())["taskId"].(string)
+	if taskID == "" {
+		t.Fatalf("no taskId in %s", recorder.Body)
+	}
+
+	statuses := []string{taskStatusGenerating, taskStatusSuccess}
+	var info map[string]interface{}
+	for _, want := range statuses {
+		recorder = serveJSON(GetTaskInfo, http.MethodGet, "/api/getTaskInfo?taskId="+taskID, nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", recorder.Code, recorder.Body)
+		}
+		info = generationData(t, recorder.Body.Bytes())
+		if info["status"] != want {
+			t.Fatalf("task status = %v, want %s", info["status"], want)
+		}
+	}
+	urls := info["response"].(map[string]interface{})["resultUrls"].([]interface{})
+	if len(urls) != 1 || urls[0] != "https://example.com/result.png" {
+		t.Errorf("resultUrls = %v", urls)
+	}
+
+	record, err := store.Get(context.Background(), taskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Prompt != "a cat" || record.Status != generator.StatusSucceeded || len(record.History) != 2 {
+		t.Errorf("record = %+v", record)
+	}
+}
+
+func TestGenerateImageValidation(t *testing.T) {
+	newGenerationTest(t)
+
+	tests := []struct {
+		name string
+		body gin.H
+	}{
+		{"missing prompt", gin.H{"imageUrls": []string{"https://example.com/in.png"}, "size": "1:1", "callBackUrl": "https://example.com/cb"}},
+		{"no images", gin.H{"imageUrls": []string{}, "prompt": "p", "size": "1:1", "callBackUrl": "https://example.com/cb"}},
+		{"no callback", gin.H{"imageUrls": []string{"https://example.com/in.png"}, "prompt": "p", "size": "1:1"}},
+		{"invalid callback", gin.H{"imageUrls": []string{"https://example.com/in.png"}, "prompt": "p", "size": "1:1", "callBackUrl": "ftp://example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serveJSON(GenerateImage, http.MethodPost, "/api/generate-image", tt.body)
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400, body %s", recorder.Code, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestGetTaskInfoFailedTask(t *testing.T) {
+	newGenerationTest(t)
+
+	recorder := serveJSON(GenerateImage, http.MethodPost, "/api/generate-image", gin.H{
+		"imageUrls":   []string{"https://example.com/in.png"},
+		"prompt":      "[fail]",
+		"size":        "1:1",
+		"callBackUrl": "https://example.com/callback",
+	})
+	taskID := generationData(t, recorder.Body.Bytes())["taskId"].(string)
+
+	var info map[string]interface{}
+	for range 2 {
+		recorder = serveJSON(GetTaskInfo, http.MethodGet, "/api/getTaskInfo?taskId="+taskID, nil)
+		info = generationData(t, recorder.Body.Bytes())
+	}
+	if info["status"] != taskStatusFailed || info["successFlag"] != float64(2) || info["errorMessage"] == "" {
+		t.Errorf("task info = %v", info)
+	}
+
+	recorder = serveJSON(GetTaskInfo, http.MethodGet, "/api/getTaskInfo?taskId=missing", nil)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("missing task status = %d, want 404", recorder.Code)
+	}
+}
+
+func TestGetTask(t *testing.T) {
+	_, store := newGenerationTest(t)
+	record := tasks.NewRecord(generator.ProviderMock, generator.Request{Prompt: "secret prompt"}, &generator.Task{ID: "t1", Status: generator.StatusGenerating}, "alice")
+	if err := store.Create(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.GET("/api/tasks/:id", GetTask)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/tasks/t1", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", recorder.Code, recorder.Body)
+	}
+	var task generator.Task
+	if err := json.Unmarshal(recorder.Body.Bytes(), &task); err != nil || task.ID != "t1" || task.Status != generator.StatusGenerating {
+		t.Errorf("task = %+v, %v", task, err)
+	}
+	// 不返回提示词和提交者
+	if strings.Contains(recorder.Body.String(), "secret prompt") || strings.Contains(recorder.Body.String(), "alice") {
+		t.Errorf("response exposes submission: %s", recorder.Body)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/tasks/missing", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("missing task status = %d, want 404", recorder.Code)
+	}
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRemoveBackgroundURLRejectsInternalAddresses(t *testing.T) {
+	newTestStore(t)
+	t.Setenv("PHOTOROOM_API_KEY", "test")
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		imageURL   string
+		wantStatus int
+		wantError  string
+	}{
+		{"unsupported scheme", "file:///etc/passwd", http.StatusBadRequest, "无效的地址"},
+		{"loopback", server.URL + "/a.png", http.StatusInternalServerError, "不允许访问内网或保留地址"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serveJSON(RemoveBackground, http.MethodPost, "/api/remove-background", map[string]string{"imageUrl": tt.imageURL})
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if !strings.Contains(recorder.Body.String(), tt.wantError) {
+				t.Errorf("body = %s, want %q", recorder.Body, tt.wantError)
+			}
+		})
+	}
+	if requested {
+		t.Error("内网地址不应被请求")
+	}
+}
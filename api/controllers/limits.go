@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"errors"
+	"go-api/api/imaging"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上传限制默认值
+const (
+	defaultUploadMaxMB    = 100
+	defaultImageMaxPixels = 50000000 // 约5000万像素，可容纳4800万像素的手机照片
+	defaultImageMaxFrames = 300
+
+	// multipartOverhead 请求体中文件以外的表单字段和分隔符所占空间
+	multipartOverhead = 1 << 20
+)
+
+// uploadLimits 读取上传和处理图片的限制
+// 环境变量 UPLOAD_MAX_MB、IMAGE_MAX_PIXELS、IMAGE_MAX_FRAMES，设为 0 表示不限制
+func uploadLimits() imaging.Limits {
+	return imaging.Limits{
+		MaxBytes:  envInt64("UPLOAD_MAX_MB", defaultUploadMaxMB) * 1024 * 1024,
+		MaxPixels: envInt64("IMAGE_MAX_PIXELS", defaultImageMaxPixels),
+		MaxFrames: int(envInt64("IMAGE_MAX_FRAMES", defaultImageMaxFrames)),
+	}
+}
+
+// limitRequestBody 限制请求体大小，超出时读取请求体返回 *http.MaxBytesError
+func limitRequestBody(c *gin.Context, limits imaging.Limits) {
+	if limits.MaxBytes > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBytes+multipartOverhead)
+	}
+}
+
+// asLimitError 判断错误是否由超出限制引起，返回错误详情和响应状态码
+// 文件过大返回413，尺寸、帧数超限或无法读取尺寸返回422
+func asLimitError(err error) (*imaging.LimitError, int, bool) {
+	var limitErr *imaging.LimitError
+	if errors.As(err, &limitErr) {
+		if limitErr.Reason == imaging.ReasonFileTooLarge {
+			return limitErr, http.StatusRequestEntityTooLarge, true
+		}
+		return limitErr, http.StatusUnprocessableEntity, true
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		limitErr = &imaging.LimitError{Reason: imaging.ReasonFileTooLarge, Limit: maxBytesErr.Limit - multipartOverhead}
+		return limitErr, http.StatusRequestEntityTooLarge, true
+	}
+	return nil, 0, false
+}
+
+// writeLimitError 超出限制时写入带 reason 的错误响应，返回是否已写入
+func writeLimitError(c *gin.Context, err error) bool {
+	limitErr, status, ok := asLimitError(err)
+	if !ok {
+		return false
+	}
+	c.JSON(status, gin.H{"error": limitErr.Error(), "reason": limitErr.Reason})
+	return true
+}
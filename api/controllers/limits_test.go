@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-api/api/imaging"
+	"net/http"
+	"testing"
+)
+
+func TestAsLimitError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantReason string
+	}{
+		{"file too large", &imaging.LimitError{Reason: imaging.ReasonFileTooLarge}, http.StatusRequestEntityTooLarge, imaging.ReasonFileTooLarge},
+		{"too many pixels", fmt.Errorf("写入失败: %w", &imaging.LimitError{Reason: imaging.ReasonTooManyPixels}), http.StatusUnprocessableEntity, imaging.ReasonTooManyPixels},
+		{"request body too large", &http.MaxBytesError{Limit: multipartOverhead + 10}, http.StatusRequestEntityTooLarge, imaging.ReasonFileTooLarge},
+		{"other error", errors.New("其他错误"), 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limitErr, status, ok := asLimitError(tt.err)
+			if ok != (tt.wantReason != "") || status != tt.wantStatus {
+				t.Fatalf("asLimitError() = %v, %d, %v", limitErr, status, ok)
+			}
+			if ok && limitErr.Reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", limitErr.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestUploadImageLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantStatus int
+		wantReason string
+	}{
+		{"within limits", nil, http.StatusOK, ""},
+		{"too many pixels", map[string]string{"IMAGE_MAX_PIXELS": "100"}, http.StatusUnprocessableEntity, imaging.ReasonTooManyPixels},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			newTestStore(t)
+			recorder := postFiles(t, UploadImage, "/api/uploadImg?variants=false", formFile{"file", "a.png", pngBytes(t, 20, 10)})
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			var response struct {
+				Reason string `json:"reason"`
+			}
+			json.Unmarshal(recorder.Body.Bytes(), &response)
+			if response.Reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", response.Reason, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"go-api/api/imaging"
+	"io"
+)
+
+// defaultKeepMetadata 默认保留的元数据，ICC不含隐私信息且影响颜色显示
+const defaultKeepMetadata = imaging.KeepICC
+
+// strippedImage 在后台清理元数据的图片，通过管道读取清理后的内容
+type strippedImage struct {
+	*io.PipeReader
+	done   chan struct{}
+	report *imaging.MetadataReport
+	err    error
+}
+
+// stripMetadata 从可随机读取的图片中删除元数据并按EXIF方向旋转，onDone 在处理结束后调用
+// 读取方提前关闭时后台处理随之结束
+func stripMetadata(src io.ReadSeeker, format imaging.Format, policy imaging.MetadataPolicy, onDone func()) *strippedImage {
+	reader, writer := io.Pipe()
+	image := &strippedImage{PipeReader: reader, done: make(chan struct{})}
+	// 按方向旋转时需要解码，与上传使用相同的像素限制
+	if policy.MaxPixels == 0 {
+		policy.MaxPixels = uploadLimits().MaxPixels
+	}
+	go func() {
+		defer close(image.done)
+		if onDone != nil {
+			defer onDone()
+		}
+		image.report, image.err = imaging.Sanitize(writer, src, format, policy)
+		writer.CloseWithError(image.err)
+	}()
+	return image
+}
+
+// stripStream 将流式内容写入变体缓存的临时文件后清理元数据，临时文件由缓存负责删除
+// 元数据解析需要随机读取，无法在单次流式读取中完成；变体和感知哈希也从同一文件生成
+func stripStream(buffer *variantBuffer, r io.Reader, format imaging.Format, policy imaging.MetadataPolicy) (*strippedImage, error) {
+	if err := buffer.spool(r); err != nil {
+		return nil, err
+	}
+	return stripMetadata(buffer.reader(), format, policy, nil), nil
+}
+
+// Report 关闭管道并等待处理结束后返回结果，应在读取完成或放弃读取后调用
+func (s *strippedImage) Report() (*imaging.MetadataReport, error) {
+	s.Close()
+	<-s.done
+	return s.report, s.err
+}
@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"errors"
+	"go-api/api/storage"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/gen2brain/heic"
+	"github.com/gin-gonic/gin"
+	_ "golang.org/x/image/webp"
+)
+
+// 对象列举默认参数
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// ListObjects 分页列举存储中的对象
+// 支持 prefix、delimiter、token（分页令牌）、limit、sort（key/size/lastModified）和 order（asc/desc）参数。
+// 分页按存储后端的键顺序进行，排序仅作用于当前页，不能得到跨页的全局顺序；
+// 只返回列举结果中的信息，不逐个读取对象，后端列举不含Content-Type时（如R2）contentType 为空
+func ListObjects(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultListLimit)))
+	if err != nil || limit <= 0 || limit > maxListLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit 必须在 1 到 1000 之间"})
+		return
+	}
+
+	sortBy := c.DefaultQuery("sort", "key")
+	order := c.DefaultQuery("order", "asc")
+	less, ok := objectLessFuncs[sortBy]
+	if !ok || (order != "asc" && order != "desc") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的排序参数"})
+		return
+	}
+
+	store, err := storage.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "存储服务配置错误: " + err.Error()})
+		return
+	}
+
+	result, err := store.List(c.Request.Context(), storage.ListOptions{
+		Prefix:            c.Query("prefix"),
+		Delimiter:         c.Query("delimiter"),
+		ContinuationToken: c.Query("token"),
+		MaxKeys:           limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "列举对象失败"})
+		return
+	}
+
+	sort.SliceStable(result.Objects, func(i, j int) bool {
+		if order == "desc" {
+			return less(result.Objects[j], result.Objects[i])
+		}
+		return less(result.Objects[i], result.Objects[j])
+	})
+
+	folders := result.CommonPrefixes
+	if folders == nil {
+		folders = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"objects":     result.Objects,
+		"folders":     folders,
+		"nextToken":   result.NextContinuationToken,
+		"isTruncated": result.IsTruncated,
+	})
+}
+
+// ObjectMetaResponse 定义对象元数据响应结构
+type ObjectMetaResponse struct {
+	Key              string            `json:"key"`
+	URL              string            `json:"url"`
+	Size             int64             `json:"size"`
+	ContentType      string            `json:"contentType"`
+	LastModified     time.Time         `json:"lastModified"`
+	Width            int               `json:"width,omitempty"`
+	Height           int               `json:"height,omitempty"`
+	OriginalFilename string            `json:"originalFilename,omitempty"`
+	Uploader         string            `json:"uploader,omitempty"`
+	Source           string            `json:"source,omitempty"`
+	SHA256           string            `json:"sha256,omitempty"`
+	InputSHA256      string            `json:"inputSha256,omitempty"`
+	InputURL         string            `json:"inputUrl,omitempty"`
+	ExpiresAt        string            `json:"expiresAt,omitempty"`
+	BlurHash         string            `json:"blurhash,omitempty"`
+	DominantColor    string            `json:"dominantColor,omitempty"`
+	AverageColor     string            `json:"averageColor,omitempty"`
+	Metadata         map[string]string `json:"metadata"`
+}
+
+// GetObject 对象详情路由，目前支持 GET /api/objects/<key>/meta
+func GetObject(c *gin.Context) {
+	key, ok := strings.CutSuffix(objectKeyParam(c), "/meta")
+	if !ok || key == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "接口不存在"})
+		return
+	}
+	getObjectMeta(c, key)
+}
+
+// getObjectMeta 返回对象的来源追踪元数据、大小、尺寸和类型
+func getObjectMeta(c *gin.Context, key string) {
+	store, err := storage.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "存储服务配置错误: " + err.Error()})
+		return
+	}
+
+	reader, info, err := store.Get(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对象不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取对象失败"})
+		return
+	}
+	defer reader.Close()
+
+	metadata := info.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	response := ObjectMetaResponse{
+		Key:              info.Key,
+		URL:              store.PublicURL(info.Key),
+		Size:             info.Size,
+		ContentType:      info.ContentType,
+		LastModified:     info.LastModified,
+		OriginalFilename: storage.UnescapeMetadata(metadata[storage.MetaOriginalFilename]),
+		Uploader:         storage.UnescapeMetadata(metadata[storage.MetaUploader]),
+		Source:           metadata[storage.MetaSource],
+		SHA256:           metadata[storage.MetaSHA256],
+		InputSHA256:      metadata[storage.MetaInputSHA256],
+		InputURL:         storage.UnescapeMetadata(metadata[storage.MetaInputURL]),
+		ExpiresAt:        metadata[storage.MetaExpiresAt],
+		BlurHash:         metadata[storage.MetaBlurHash],
+		DominantColor:    metadata[storage.MetaDominantColor],
+		AverageColor:     metadata[storage.MetaAverageColor],
+		Metadata:         metadata,
+	}
+	// 上传的文件本身即为输入
+	if response.InputSHA256 == "" && response.Source == storage.SourceUpload {
+		response.InputSHA256 = response.SHA256
+	}
+
+	// 优先使用上传时记录的尺寸（已按EXIF方向旋转），否则只解码图片头部获取尺寸
+	response.Width, _ = strconv.Atoi(metadata[storage.MetaWidth])
+	response.Height, _ = strconv.Atoi(metadata[storage.MetaHeight])
+	if response.Width == 0 || response.Height == 0 {
+		if config, _, err := image.DecodeConfig(reader); err == nil {
+			response.Width = config.Width
+			response.Height = config.Height
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// BatchDeleteRequest 定义批量删除请求结构
+type BatchDeleteRequest struct {
+	Keys []string `json:"keys" binding:"required"` // 待删除的对象键，最多1000个
+}
+
+// DeleteObject 删除单个对象及其变体、变换缓存和感知哈希索引
+func DeleteObject(c *gin.Context) {
+	key := objectKeyParam(c)
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "对象键不能为空"})
+		return
+	}
+
+	store, err := storage.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "存储服务配置错误: " + err.Error()})
+		return
+	}
+
+	failures, err := storage.DeleteWithDerived(c.Request.Context(), store, []string{key})
+	if err != nil || len(failures) > 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除对象失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": key})
+}
+
+// BatchDeleteObjects 批量删除对象及其派生内容，单个对象删除失败不影响其他对象
+func BatchDeleteObjects(c *gin.Context) {
+	var req BatchDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Keys) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	if len(req.Keys) > storage.MaxBatchDelete {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "单次最多删除1000个对象"})
+		return
+	}
+
+	store, err := storage.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "存储服务配置错误: " + err.Error()})
+		return
+	}
+
+	failures, err := storage.DeleteWithDerived(c.Request.Context(), store, req.Keys)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "批量删除对象失败"})
+		return
+	}
+
+	deleted := make([]string, 0, len(req.Keys))
+	for _, key := range req.Keys {
+		if _, failed := failures[key]; !failed {
+			deleted = append(deleted, key)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deleted": deleted,
+		"errors":  failures,
+	})
+}
+
+// SweepExpiredObjects 删除所有已过期的对象，可由定时任务调用
+func SweepExpiredObjects(c *gin.Context) {
+	store, err := storage.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "存储服务配置错误: " + err.Error()})
+		return
+	}
+
+	result, err := storage.SweepExpired(c.Request.Context(), store, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "清理过期对象失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// objectKeyParam 读取路由中的对象键，对象键可包含 /
+func objectKeyParam(c *gin.Context) string {
+	return strings.TrimPrefix(c.Param("key"), "/")
+}
+
+// objectLessFuncs 支持的排序字段
+var objectLessFuncs = map[string]func(a, b storage.ObjectInfo) bool{
+	"key": func(a, b storage.ObjectInfo) bool {
+		return a.Key < b.Key
+	},
+	"size": func(a, b storage.ObjectInfo) bool {
+		return a.Size < b.Size
+	},
+	"lastModified": func(a, b storage.ObjectInfo) bool {
+		return a.LastModified.Before(b.LastModified)
+	},
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"go-api/api/storage"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listResponse ListObjects 的响应
+type listResponse struct {
+	Objects     []storage.ObjectInfo `json:"objects"`
+	Folders     []string             `json:"folders"`
+	NextToken   string               `json:"nextToken"`
+	IsTruncated bool                 `json:"isTruncated"`
+}
+
+// listObjects 调用 ListObjects 并解析响应
+func listObjects(t *testing.T, query string) (int, listResponse) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/objects?"+query, nil)
+	ListObjects(c)
+
+	var response listResponse
+	if recorder.Code == http.StatusOK {
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return recorder.Code, response
+}
+
+// objectKeys 返回对象键列表
+func objectKeys(objects []storage.ObjectInfo) []string {
+	keys := []string{}
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	return keys
+}
+
+// putObjects 写入内容长度不同的对象
+func putObjects(t *testing.T, store storage.ObjectStore, contents map[string]string) {
+	t.Helper()
+	for key, content := range contents {
+		if _, err := store.Put(context.Background(), key, strings.NewReader(content), storage.PutOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListObjects(t *testing.T) {
+	store := newTestStore(t)
+	putObjects(t, store, map[string]string{
+		"2025/a.png": "aaaa",
+		"2025/b.png": "b",
+		"2026/c.jpg": "ccc",
+		"d.webp":     "dd",
+	})
+
+	tests := []struct {
+		name        string
+		query       string
+		wantKeys    []string
+		wantFolders []string
+		truncated   bool
+	}{
+		{"all", "", []string{"2025/a.png", "2025/b.png", "2026/c.jpg", "d.webp"}, []string{}, false},
+		{"prefix", "prefix=2025/", []string{"2025/a.png", "2025/b.png"}, []string{}, false},
+		{"delimiter", "delimiter=/", []string{"d.webp"}, []string{"2025/", "2026/"}, false},
+		{"limit", "limit=2", []string{"2025/a.png", "2025/b.png"}, []string{}, true},
+		{"sort by size", "sort=size", []string{"2025/b.png", "d.webp", "2026/c.jpg", "2025/a.png"}, []string{}, false},
+		{"sort desc within page", "limit=2&sort=size&order=desc", []string{"2025/a.png", "2025/b.png"}, []string{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response := listObjects(t, tt.query)
+			if status != http.StatusOK {
+				t.Fatalf("status = %d", status)
+			}
+			if got := objectKeys(response.Objects); !reflect.DeepEqual(got, tt.wantKeys) {
+				t.Errorf("keys = %v, want %v", got, tt.wantKeys)
+			}
+			if !reflect.DeepEqual(response.Folders, tt.wantFolders) {
+				t.Errorf("folders = %v, want %v", response.Folders, tt.wantFolders)
+			}
+			if response.IsTruncated != tt.truncated {
+				t.Errorf("isTruncated = %v, want %v", response.IsTruncated, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestListObjectsPagination(t *testing.T) {
+	store := newTestStore(t)
+	putObjects(t, store, map[string]string{"a": "1", "b": "2", "c": "3", "d": "4", "e": "5"})
+
+	var keys []string
+	token := ""
+	for page := 0; page < 5; page++ {
+		status, response := listObjects(t, "limit=2&token="+token)
+		if status != http.StatusOK {
+			t.Fatalf("status = %d", status)
+		}
+		keys = append(keys, objectKeys(response.Objects)...)
+		if !response.IsTruncated {
+			break
+		}
+		token = response.NextToken
+	}
+	if want := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %v, want %v", keys, want)
+	}
+}
+
+func TestListObjectsInvalidParams(t *testing.T) {
+	newTestStore(t)
+	for _, query := range []string{"limit=0", "limit=1001", "limit=x", "sort=name", "order=up"} {
+		t.Run(query, func(t *testing.T) {
+			if status, _ := listObjects(t, query); status != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", status)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"go-api/api/imaging"
+	"go-api/api/storage"
+	"image"
+	"log"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// describeImage 计算尺寸、BlurHash、LQIP和主色调，返回随对象一次写入的元数据和需要合并到响应中的字段
+// 需要在写入存储前调用，内容已全部读入缓存；无法解码时只返回文件头中的尺寸；LQIP较大，只在响应中返回
+func describeImage(buffer *variantBuffer, format imaging.Format, header imaging.Info, report *imaging.MetadataReport) (map[string]string, gin.H) {
+	var img image.Image
+	if !buffer.overflow {
+		img, _ = buffer.decode(format)
+	}
+	if img == nil {
+		width, height := header.Width, header.Height
+		// 文件头中是旋转前的尺寸
+		if report != nil && report.Rotated && report.Orientation >= 5 {
+			width, height = height, width
+		}
+		if width == 0 || height == 0 {
+			return nil, nil
+		}
+		return map[string]string{
+			storage.MetaWidth:  strconv.Itoa(width),
+			storage.MetaHeight: strconv.Itoa(height),
+		}, gin.H{"width": width, "height": height}
+	}
+
+	placeholder, err := imaging.NewPlaceholder(img)
+	if err != nil {
+		log.Printf("生成占位图失败: %v", err)
+		return nil, nil
+	}
+	return map[string]string{
+		storage.MetaWidth:         strconv.Itoa(placeholder.Width),
+		storage.MetaHeight:        strconv.Itoa(placeholder.Height),
+		storage.MetaBlurHash:      placeholder.BlurHash,
+		storage.MetaDominantColor: placeholder.DominantColor,
+		storage.MetaAverageColor:  placeholder.AverageColor,
+	}, gin.H{
+		"width":         placeholder.Width,
+		"height":        placeholder.Height,
+		"blurhash":      placeholder.BlurHash,
+		"lqip":          placeholder.LQIP,
+		"dominantColor": placeholder.DominantColor,
+		"averageColor":  placeholder.AverageColor,
+	}
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go-api/api/storage"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// countingStore 记录写入和服务端复制次数
+type countingStore struct {
+	*storage.LocalStore
+	puts   []string
+	copies []string
+}
+
+// Put 记录写入的对象键
+func (s *countingStore) Put(ctx context.Context, key string, body io.Reader, opts storage.PutOptions) (*storage.ObjectInfo, error) {
+	s.puts = append(s.puts, key)
+	return s.LocalStore.Put(ctx, key, body, opts)
+}
+
+// Copy 记录复制的目标键
+func (s *countingStore) Copy(ctx context.Context, srcKey, dstKey string, opts storage.PutOptions) error {
+	s.copies = append(s.copies, dstKey)
+	return s.LocalStore.Copy(ctx, srcKey, dstKey, opts)
+}
+
+// formFile multipart 表单中的一个文件
+type formFile struct {
+	Field    string
+	Filename string
+	Data     []byte
+}
+
+// multipartBody 按顺序构建 multipart 请求体，返回请求体和Content-Type
+func multipartBody(t *testing.T, files ...formFile) ([]byte, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, file := range files {
+		part, err := writer.CreateFormFile(file.Field, file.Filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(file.Data)
+	}
+	writer.Close()
+	return body.Bytes(), writer.FormDataContentType()
+}
+
+// postBody 以指定的请求体调用处理函数
+func postBody(handler gin.HandlerFunc, path string, body []byte, contentType string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", contentType)
+	handler(c)
+	return recorder
+}
+
+// postFiles 以 multipart 表单调用处理函数
+func postFiles(t *testing.T, handler gin.HandlerFunc, path string, files ...formFile) *httptest.ResponseRecorder {
+	t.Helper()
+	body, contentType := multipartBody(t, files...)
+	return postBody(handler, path, body, contentType)
+}
+
+func TestUploadImageWritesImageInfoOnce(t *testing.T) {
+	t.Setenv("STORAGE_CONTENT_ADDRESSED", "false")
+	store := &countingStore{LocalStore: newTestStore(t)}
+	storage.SetDefault(store)
+
+	recorder := postFiles(t, UploadImage, "/api/uploadImg?variants=false", formFile{"file", "photo.png", pngBytes(t, 40, 30)})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", recorder.Code, recorder.Body)
+	}
+	var response struct {
+		URL      string `json:"url"`
+		Width    int    `json:"width"`
+		Height   int    `json:"height"`
+		BlurHash string `json:"blurhash"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Width != 40 || response.Height != 30 || response.BlurHash == "" {
+		t.Errorf("response = %+v", response)
+	}
+
+	// 图片信息随对象一次写入，不再复制对象更新元数据
+	var objects []string
+	for _, key := range store.puts {
+		if !storage.IsInternalKey(key) {
+			objects = append(objects, key)
+		}
+	}
+	if len(objects) != 1 || len(store.copies) != 0 {
+		t.Fatalf("puts = %v, copies = %v", store.puts, store.copies)
+	}
+	info, err := store.Head(context.Background(), objects[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		storage.MetaWidth:    "40",
+		storage.MetaHeight:   "30",
+		storage.MetaBlurHash: response.BlurHash,
+	}
+	for name, value := range want {
+		if info.Metadata[name] != value {
+			t.Errorf("metadata %s = %q, want %q", name, info.Metadata[name], value)
+		}
+	}
+	if !strings.HasSuffix(response.URL, objects[0]) {
+		t.Errorf("url = %q, key = %q", response.URL, objects[0])
+	}
+}
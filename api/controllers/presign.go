@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-api/api/imaging"
+	"go-api/api/storage"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 浏览器直传默认配置
+const (
+	defaultPresignMaxUploadMB      = 500
+	defaultPresignExpiresInMinutes = 15
+)
+
+// completeGracePeriod 预签名地址过期后仍可完成上传的时间，用于上传在过期前开始、完成稍晚的情况
+const completeGracePeriod = time.Hour
+
+// errInvalidUploadToken 完成直传时的令牌无效或已过期
+var errInvalidUploadToken = errors.New("上传令牌无效或已过期")
+
+// PresignUploadRequest 定义预签名上传请求结构
+type PresignUploadRequest struct {
+	Filename    string `json:"filename" binding:"required"`    // 原文件名
+	ContentType string `json:"contentType" binding:"required"` // 文件类型
+	Size        int64  `json:"size"`                           // 可选：文件大小，指定后PUT上传必须与之一致
+}
+
+// CompleteUploadRequest 定义完成直传请求结构
+type CompleteUploadRequest struct {
+	Key   string `json:"key" binding:"required"`   // 预签名时返回的对象键
+	Token string `json:"token" binding:"required"` // 预签名时返回的上传令牌
+}
+
+// PresignUpload 生成浏览器直传存储桶的预签名PUT地址和POST表单策略
+func PresignUpload(c *gin.Context) {
+	var req PresignUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+
+	// 实际内容在完成上传时根据文件签名校验
+	format, ok := imaging.ByContentType(req.ContentType)
+	if !ok || !isSupportedFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errUnsupportedFormat.Error()})
+		return
+	}
+	if err := format.CheckExtension(req.Filename); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	maxSize := presignMaxUploadSize()
+	if req.Size < 0 || req.Size > maxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件大小超出限制"})
+		return
+	}
+
+	secret := os.Getenv("PRESIGN_SECRET")
+	if secret == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "未配置 PRESIGN_SECRET"})
+		return
+	}
+
+	presigner, ok := presignerFromStore(c)
+	if !ok {
+		return
+	}
+
+	// 与普通上传使用相同的对象键模板
+	uploadOpts, err := parseUploadOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key, err := uploadOpts.objectKey(storage.SourceUpload, req.Filename)
+	if errors.Is(err, errReservedKey) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "对象键模板配置错误"})
+		return
+	}
+	expires := time.Duration(envInt64("PRESIGN_EXPIRES_MINUTES", defaultPresignExpiresInMinutes)) * time.Minute
+	expiresAt := time.Now().Add(expires).UTC()
+	// 来源元数据和对象过期时间纳入签名，与普通上传一致；过期时间从生成预签名时开始计算
+	metadata := uploadOpts.metadata(storage.SourceUpload, req.Filename)
+	if uploadOpts.TTL > 0 {
+		metadata[storage.MetaExpiresAt] = time.Now().Add(uploadOpts.TTL).UTC().Format(time.RFC3339)
+	}
+	opts := storage.PresignOptions{
+		ContentType: req.ContentType,
+		Size:        req.Size,
+		MaxSize:     maxSize,
+		Expires:     expires,
+		Metadata:    metadata,
+	}
+
+	put, err := presigner.PresignPut(c.Request.Context(), key, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成预签名上传地址失败"})
+		return
+	}
+	post, err := presigner.PresignPost(c.Request.Context(), key, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成预签名上传策略失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"key":       key,
+		"token":     signUploadToken(secret, key, expiresAt),
+		"expiresAt": expiresAt,
+		"put":       put,
+		"post":      post,
+	})
+}
+
+// CompleteUpload 校验直传的对象并返回公开访问URL
+// 只接受预签名时签发的对象键，令牌不匹配时不读取也不删除对象；
+// 校验通过后补充内容哈希，设置了有效期的对象写入过期索引
+func CompleteUpload(c *gin.Context) {
+	var req CompleteUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+
+	secret := os.Getenv("PRESIGN_SECRET")
+	if secret == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "未配置 PRESIGN_SECRET"})
+		return
+	}
+	if storage.IsReservedKey(req.Key) || !verifyUploadToken(secret, req.Key, req.Token, time.Now()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errInvalidUploadToken.Error()})
+		return
+	}
+
+	store, err := storage.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "存储服务配置错误: " + err.Error()})
+		return
+	}
+
+	// 通过HeadObject确认对象已上传
+	info, err := store.Head(c.Request.Context(), req.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对象不存在，请先完成上传"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取对象信息失败"})
+		return
+	}
+
+	// 校验上传内容，不符合要求的对象直接删除
+	if err := checkUploadedImage(c.Request.Context(), store, info); err != nil {
+		store.Delete(c.Request.Context(), req.Key)
+		if writeLimitError(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "上传的文件不符合要求: " + err.Error()})
+		return
+	}
+	if err := finishDirectUpload(c.Request.Context(), store, info); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新对象元数据失败: " + err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"key":         info.Key,
+		"size":        info.Size,
+		"contentType": info.ContentType,
+		"url":         store.PublicURL(info.Key),
+	}
+	if expiresAt, ok := storage.ExpiresAt(info.Metadata); ok {
+		response["expiresAt"] = expiresAt
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// finishDirectUpload 为直传对象补充SHA-256元数据并登记过期时间，重复调用时不会重复写入
+// 直传对象由客户端写入，无法在写入时计算哈希，只能在确认后通过服务端复制更新元数据
+func finishDirectUpload(ctx context.Context, store storage.ObjectStore, info *storage.ObjectInfo) error {
+	if info.Metadata[storage.MetaSHA256] == "" {
+		sum, err := hashObject(ctx, store, info.Key)
+		if err != nil {
+			return err
+		}
+		if err := storage.UpdateMetadata(ctx, store, info, map[string]string{storage.MetaSHA256: sum}); err != nil {
+			return err
+		}
+	}
+	if expiresAt, ok := storage.ExpiresAt(info.Metadata); ok {
+		return storage.RecordExpiry(ctx, store, info.Key, expiresAt)
+	}
+	return nil
+}
+
+// hashObject 读取对象并计算SHA-256
+func hashObject(ctx context.Context, store storage.ObjectStore, key string) (string, error) {
+	body, _, err := store.Get(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("读取对象失败: %v", err)
+	}
+	defer body.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, body); err != nil {
+		return "", fmt.Errorf("读取对象失败: %v", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// checkUploadedImage 校验直传对象的大小和像素数，并根据文件签名确认实际格式与声明的Content-Type一致
+// 只读取文件头，不下载完整对象
+func checkUploadedImage(ctx context.Context, store storage.ObjectStore, info *storage.ObjectInfo) error {
+	if info.Size > presignMaxUploadSize() {
+		return errors.New("文件大小超出限制")
+	}
+
+	body, _, err := store.Get(ctx, info.Key)
+	if err != nil {
+		return fmt.Errorf("读取对象失败: %v", err)
+	}
+	defer body.Close()
+
+	image, err := sniffImage(body, info.Key)
+	if err != nil {
+		return err
+	}
+	if declared, ok := imaging.ByContentType(info.ContentType); !ok || declared.Name != image.Format.Name {
+		return fmt.Errorf("Content-Type %s 与实际格式 %s 不符", info.ContentType, image.Format.ContentType)
+	}
+
+	// 只读取文件头检查像素数，避免后续生成变体或变换时解码超大图片
+	guard, err := imaging.NewGuard(image, image.Format, imaging.Limits{MaxPixels: uploadLimits().MaxPixels})
+	if err != nil {
+		return err
+	}
+	guard.Close()
+	return nil
+}
+
+// signUploadToken 签发完成直传时使用的令牌：<过期时间Unix秒>.<HMAC-SHA256(key + "\n" + 过期时间)>
+// 令牌与对象键绑定，客户端无法用于其他对象
+func signUploadToken(secret, key string, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expires + "." + uploadTokenSignature(secret, key, expires)
+}
+
+// verifyUploadToken 校验令牌与对象键匹配，且未超过过期时间加宽限期
+func verifyUploadToken(secret, key, token string, now time.Time) bool {
+	expires, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.After(time.Unix(expiresAt, 0).Add(completeGracePeriod)) {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(uploadTokenSignature(secret, key, expires)))
+}
+
+// uploadTokenSignature 计算令牌签名
+func uploadTokenSignature(secret, key, expires string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// presignerFromStore 获取支持预签名的存储后端，不支持时直接写入错误响应
+func presignerFromStore(c *gin.Context) (storage.Presigner, bool) {
+	store, err := storage.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "存储服务配置错误: " + err.Error()})
+		return nil, false
+	}
+
+	presigner, ok := store.(storage.Presigner)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "当前存储后端不支持预签名上传"})
+		return nil, false
+	}
+	return presigner, true
+}
+
+// presignMaxUploadSize 直传允许的最大文件大小（字节）
+func presignMaxUploadSize() int64 {
+	return envInt64("PRESIGN_MAX_UPLOAD_MB", defaultPresignMaxUploadMB) * 1024 * 1024
+}
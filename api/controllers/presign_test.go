@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go-api/api/storage"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestStore 使用临时目录作为本地存储并设为共享存储
+func newTestStore(t *testing.T) *storage.LocalStore {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("LOCAL_STORAGE_ROOT", t.TempDir())
+	store, err := storage.NewLocalStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.SetDefault(store)
+	return store
+}
+
+// pngBytes 返回指定尺寸的PNG图片
+func pngBytes(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// serveJSON 调用处理函数并返回响应
+func serveJSON(handler gin.HandlerFunc, method, path string, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(method, path, bytes.NewReader(data))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler(c)
+	return recorder
+}
+
+func TestVerifyUploadToken(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	token := signUploadToken("secret", "a.png", now.Add(15*time.Minute))
+	tests := []struct {
+		name  string
+		key   string
+		token string
+		now   time.Time
+		want  bool
+	}{
+		{"valid", "a.png", token, now, true},
+		{"within grace period", "a.png", token, now.Add(15*time.Minute + completeGracePeriod - time.Second), true},
+		{"expired", "a.png", token, now.Add(15*time.Minute + completeGracePeriod + time.Second), false},
+		{"other key", "b.png", token, now, false},
+		{"tampered expiry", "a.png", "9999999999" + token[strings.Index(token, "."):], now, false},
+		{"malformed", "a.png", "not-a-token", now, false},
+		{"empty", "a.png", "", now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyUploadToken("secret", tt.key, tt.token, tt.now); got != tt.want {
+				t.Errorf("verifyUploadToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleteUpload(t *testing.T) {
+	t.Setenv("PRESIGN_SECRET", "secret")
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	// 模拟预签名上传写入的对象，元数据由签名约束
+	metadata := map[string]string{
+		storage.MetaUploader:  "alice",
+		storage.MetaSource:    storage.SourceUpload,
+		storage.MetaExpiresAt: expiresAt.Format(time.RFC3339),
+	}
+	tests := []struct {
+		name        string
+		key         string
+		body        []byte
+		contentType string
+		metadata    map[string]string
+		token       string
+		wantStatus  int
+		wantExpiry  bool
+		wantDeleted bool
+	}{
+		{"completed", "direct.png", pngBytes(t, 4, 3), "image/png", metadata, "", http.StatusOK, true, false},
+		{"no ttl", "forever.png", pngBytes(t, 4, 3), "image/png", map[string]string{storage.MetaUploader: "bob"}, "", http.StatusOK, false, false},
+		{"invalid token", "direct.png", pngBytes(t, 4, 3), "image/png", metadata, "1.bad", http.StatusForbidden, false, false},
+		{"not uploaded", "", nil, "", nil, "", http.StatusNotFound, false, false},
+		{"not an image", "fake.png", []byte("plain text"), "image/png", metadata, "", http.StatusBadRequest, false, true},
+		{"wrong content type", "mismatch.jpg", pngBytes(t, 4, 3), "image/jpeg", metadata, "", http.StatusBadRequest, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestStore(t)
+			key := tt.key
+			if key == "" {
+				key = "missing.png"
+			} else if _, err := store.Put(ctx, key, bytes.NewReader(tt.body), storage.PutOptions{ContentType: tt.contentType, Metadata: tt.metadata}); err != nil {
+				t.Fatal(err)
+			}
+			token := tt.token
+			if token == "" {
+				token = signUploadToken("secret", key, time.Now().Add(time.Minute))
+			}
+
+			response := serveJSON(CompleteUpload, http.MethodPost, "/api/uploads/complete", CompleteUploadRequest{Key: key, Token: token})
+			if response.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", response.Code, tt.wantStatus, response.Body)
+			}
+
+			_, err := store.Head(ctx, key)
+			if deleted := err != nil; tt.key != "" && deleted != tt.wantDeleted {
+				t.Errorf("deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			info, err := store.Head(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := storage.ContentKey(tt.body, ""); info.Metadata[storage.MetaSHA256] != want {
+				t.Errorf("sha256 = %q, want %q", info.Metadata[storage.MetaSHA256], want)
+			}
+			if info.Metadata[storage.MetaUploader] != tt.metadata[storage.MetaUploader] {
+				t.Errorf("uploader = %q, want %q", info.Metadata[storage.MetaUploader], tt.metadata[storage.MetaUploader])
+			}
+			markers, err := store.List(ctx, storage.ListOptions{Prefix: "_expiry/"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(markers.Objects) == 1 && strings.HasSuffix(markers.Objects[0].Key, "/"+key); got != tt.wantExpiry {
+				t.Errorf("expiry markers = %v, want recorded %v", markers.Objects, tt.wantExpiry)
+			}
+		})
+	}
+}
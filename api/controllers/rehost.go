@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-api/api/safehttp"
+	"go-api/api/storage"
+	"go-api/api/tasks"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"time"
+)
+
+// 保存生成结果的重试限制
+const (
+	maxHostAttempts   = 3               // 最多尝试次数，之后只返回生成服务的原始地址
+	hostRetryInterval = 5 * time.Minute // 两次尝试的最短间隔，也是单次保存占用任务的最长时间
+)
+
+// errHostingSkipped 结果已保存、正由其他请求保存、尚未到重试时间或已达到尝试次数上限
+var errHostingSkipped = errors.New("跳过保存生成结果")
+
+// rehostEnabled 是否将生成结果保存到存储后端，GENERATION_REHOST=false 时关闭
+func rehostEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("GENERATION_REHOST"))
+	return err != nil || enabled
+}
+
+// generationResultHosts 允许下载生成结果的域名，为空时只限制为公网地址
+func generationResultHosts() []string {
+	return safehttp.ParseHosts(os.Getenv("GENERATION_RESULT_HOSTS"))
+}
+
+// hostTaskResults 下载已成功任务的生成结果并保存到存储后端，将永久地址写入任务记录
+// 保存前通过 HostingAt 占用任务，并发的查询和回调中只有一个会保存；
+// 失败时记录原因，间隔 hostRetryInterval 后的查询或回调再重试，最多 maxHostAttempts 次
+func hostTaskResults(ctx context.Context, record *tasks.Record) *tasks.Record {
+	if !rehostEnabled() || !record.NeedsHosting() {
+		return record
+	}
+	store, err := tasks.Default()
+	if err != nil {
+		return record
+	}
+
+	claimed, err := store.Update(ctx, record.ID, func(record *tasks.Record) error {
+		now := time.Now().UTC()
+		if !record.NeedsHosting() || record.HostAttempts >= maxHostAttempts ||
+			(record.HostingAt != nil && now.Sub(*record.HostingAt) < hostRetryInterval) {
+			return errHostingSkipped
+		}
+		record.HostingAt = &now
+		record.HostAttempts++
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, errHostingSkipped) {
+			log.Printf("更新任务记录失败: %s: %v", record.ID, err)
+		}
+		return record
+	}
+
+	hostedURLs, hostErr := saveGeneratedImages(ctx, claimed)
+	if hostErr != nil {
+		log.Printf("保存生成结果失败（第 %d 次）: %s: %v", claimed.HostAttempts, record.ID, hostErr)
+	}
+	// 请求已结束时仍需记录结果，否则要等到重试间隔后才能再次保存
+	updated, err := store.Update(context.WithoutCancel(ctx), record.ID, func(record *tasks.Record) error {
+		if hostErr != nil {
+			// 保留 HostingAt，作为下次重试的起算时间
+			record.HostError = hostErr.Error()
+			return nil
+		}
+		// 并发保存时保留先写入的地址
+		if len(record.HostedURLs) == 0 {
+			record.HostedURLs = hostedURLs
+		}
+		record.HostError = ""
+		record.HostingAt = nil
+		return nil
+	})
+	if err != nil {
+		log.Printf("更新任务记录失败: %s: %v", record.ID, err)
+		return record
+	}
+	return updated
+}
+
+// saveGeneratedImages 依次下载并保存生成结果，任一结果失败时返回错误
+// 结果地址必须为 http/https，并在配置了 GENERATION_RESULT_HOSTS 时属于其中的域名
+func saveGeneratedImages(ctx context.Context, record *tasks.Record) ([]string, error) {
+	hosts := generationResultHosts()
+	for _, resultURL := range record.ResultURLs {
+		if err := safehttp.CheckURL(resultURL, hosts); err != nil {
+			return nil, err
+		}
+	}
+
+	objectStore, err := storage.Default()
+	if err != nil {
+		return nil, fmt.Errorf("创建存储客户端失败: %v", err)
+	}
+
+	// 只连接公网地址，重定向后的域名同样需要在允许列表中
+	client := safehttp.NewClient(30*time.Second, hosts)
+	opts := uploadOptions{Uploader: record.Caller}
+	hostedURLs := make([]string, 0, len(record.ResultURLs))
+	for _, resultURL := range record.ResultURLs {
+		info, err := saveGeneratedImage(ctx, objectStore, client, resultURL, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", resultURL, err)
+		}
+		hostedURLs = append(hostedURLs, info.URL)
+	}
+	return hostedURLs, nil
+}
+
+// saveGeneratedImage 下载一张生成结果，按 generation 的对象键模板保存并写入占位信息
+func saveGeneratedImage(ctx context.Context, objectStore storage.ObjectStore, client *http.Client, resultURL string, opts uploadOptions) (*storage.ObjectInfo, error) {
+	body, image, err := downloadImage(ctx, client, resultURL, uploadLimits())
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	defer image.Close()
+
+	filename := generatedFilename(resultURL, image.Format.Extension())
+	key, err := opts.objectKey(storage.SourceGeneration, filename)
+	if err != nil {
+		return nil, err
+	}
+	metadata := opts.metadata(storage.SourceGeneration, filename)
+	metadata[storage.MetaInputURL] = storage.EscapeMetadata(resultURL)
+
+	buffer := newVariantBuffer()
+	defer buffer.Close()
+	content, _ := buffer.prefetch(image)
+	if buffer.complete {
+		// 尺寸、占位图等图片信息随对象一次写入
+		described, _ := describeImage(buffer, image.Format, image.Info, nil)
+		maps.Copy(metadata, described)
+	}
+	info, err := storage.Save(ctx, objectStore, content, storage.SaveOptions{
+		PutOptions: storage.PutOptions{
+			ContentType: image.Format.ContentType,
+			Metadata:    metadata,
+		},
+		Key:      key,
+		Filename: filename,
+	})
+	if err != nil {
+		if limitErr := image.Err(); limitErr != nil {
+			return nil, limitErr
+		}
+		return nil, fmt.Errorf("上传到存储服务失败: %v", err)
+	}
+
+	return info, nil
+}
+
+// generatedFilename 取结果地址中的文件名，并将扩展名替换为实际格式的扩展名
+func generatedFilename(resultURL, ext string) string {
+	name := "generated"
+	if parsed, err := url.Parse(resultURL); err == nil {
+		if base := path.Base(parsed.Path); base != "." && base != "/" {
+			name = base
+		}
+	}
+	return name[:len(name)-len(path.Ext(name))] + ext
+}
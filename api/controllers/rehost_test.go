@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"context"
+	"go-api/api/generator"
+	"go-api/api/storage"
+	"go-api/api/tasks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRehostTest 在 newGenerationTest 基础上开启保存生成结果，并写入一条已成功的任务记录
+func newRehostTest(t *testing.T, resultURL string) *tasks.MemoryStore {
+	t.Helper()
+	_, store := newGenerationTest(t)
+	newTestStore(t)
+	t.Setenv("GENERATION_REHOST", "")
+
+	record := tasks.NewRecord(generator.ProviderMock, generator.Request{Prompt: "a cat"}, &generator.Task{ID: "t1", Status: generator.StatusGenerating}, "alice")
+	record.Apply(&generator.Task{Status: generator.StatusSucceeded, ResultURLs: []string{resultURL}})
+	if err := store.Create(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestSaveGeneratedImage(t *testing.T) {
+	objectStore := newTestStore(t)
+	image := pngBytes(t, 20, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(image)
+	}))
+	defer server.Close()
+
+	// 测试服务监听回环地址，使用普通客户端
+	resultURL := server.URL + "/files/result.webp?sig=abc"
+	info, err := saveGeneratedImage(context.Background(), objectStore, server.Client(), resultURL, uploadOptions{Uploader: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(info.Key, ".png") {
+		t.Errorf("key = %q, want .png extension from the actual format", info.Key)
+	}
+
+	head, err := objectStore.Head(context.Background(), info.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		storage.MetaSource:   storage.SourceGeneration,
+		storage.MetaUploader: "alice",
+		storage.MetaInputURL: storage.EscapeMetadata(resultURL),
+		storage.MetaWidth:    "20",
+		storage.MetaHeight:   "10",
+	}
+	for name, value := range want {
+		if head.Metadata[name] != value {
+			t.Errorf("metadata %s = %q, want %q", name, head.Metadata[name], value)
+		}
+	}
+}
+
+func TestSaveGeneratedImageRejectsNonImage(t *testing.T) {
+	objectStore := newTestStore(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>expired</html>"))
+	}))
+	defer server.Close()
+
+	if _, err := saveGeneratedImage(context.Background(), objectStore, server.Client(), server.URL+"/r.png", uploadOptions{}); err == nil {
+		t.Error("saving a non-image result should fail")
+	}
+}
+
+func TestHostTaskResultsRetryLimits(t *testing.T) {
+	// 只连接公网地址，回环地址的结果保存失败
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached loopback server")
+	}))
+	defer server.Close()
+	store := newRehostTest(t, server.URL+"/r.png")
+	ctx := context.Background()
+
+	record, _ := store.Get(ctx, "t1")
+	record = hostTaskResults(ctx, record)
+	if record.HostAttempts != 1 || record.HostError == "" || record.HostingAt == nil || len(record.HostedURLs) != 0 {
+		t.Fatalf("after failed attempt = %+v", record)
+	}
+	if urls := record.Task().ResultURLs; urls[0] != server.URL+"/r.png" {
+		t.Errorf("ResultURLs = %v, want provider URL while not hosted", urls)
+	}
+
+	// 未到重试间隔时不再尝试
+	hostTaskResults(ctx, record)
+	if stored, _ := store.Get(ctx, "t1"); stored.HostAttempts != 1 {
+		t.Errorf("retried before interval: attempts = %d", stored.HostAttempts)
+	}
+
+	// 超过重试间隔后重试，达到次数上限后不再尝试
+	for attempt := 2; attempt <= maxHostAttempts+1; attempt++ {
+		store.Update(ctx, "t1", func(record *tasks.Record) error {
+			past := time.Now().Add(-hostRetryInterval - time.Second)
+			record.HostingAt = &past
+			return nil
+		})
+		record, _ = store.Get(ctx, "t1")
+		hostTaskResults(ctx, record)
+	}
+	if stored, _ := store.Get(ctx, "t1"); stored.HostAttempts != maxHostAttempts {
+		t.Errorf("attempts = %d, want %d", stored.HostAttempts, maxHostAttempts)
+	}
+}
+
+func TestHostTaskResultsChecksAllowedHosts(t *testing.T) {
+	store := newRehostTest(t, "https://files.other.com/r.png")
+	t.Setenv("GENERATION_RESULT_HOSTS", "provider.example")
+	ctx := context.Background()
+
+	record, _ := store.Get(ctx, "t1")
+	record = hostTaskResults(ctx, record)
+	if !strings.Contains(record.HostError, "files.other.com") || len(record.HostedURLs) != 0 {
+		t.Errorf("record = %+v", record)
+	}
+}
+
+func TestHostTaskResultsDisabled(t *testing.T) {
+	store := newRehostTest(t, "https://files.other.com/r.png")
+	t.Setenv("GENERATION_REHOST", "false")
+	ctx := context.Background()
+
+	record, _ := store.Get(ctx, "t1")
+	if record = hostTaskResults(ctx, record); record.HostAttempts != 0 {
+		t.Errorf("hosting attempted while disabled: %+v", record)
+	}
+}
+
+func TestGetTaskInfoReturnsHostedURLs(t *testing.T) {
+	store := newRehostTest(t, "https://files.other.com/r.png")
+	store.Update(context.Background(), "t1", func(record *tasks.Record) error {
+		record.HostedURLs = []string{"/images/generated/r.png"}
+		return nil
+	})
+
+	recorder := serveJSON(GetTaskInfo, http.MethodGet, "/api/getTaskInfo?taskId=t1", nil)
+	info := generationData(t, recorder.Body.Bytes())
+	urls := info["response"].(map[string]interface{})["resultUrls"].([]interface{})
+	if len(urls) != 1 || urls[0] != "/images/generated/r.png" {
+		t.Errorf("resultUrls = %v, want hosted URL", urls)
+	}
+}
+
+func TestGeneratedFilename(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/files/result.webp?sig=abc", "result.png"},
+		{"https://example.com/files/result", "result.png"},
+		{"https://example.com/", "generated.png"},
+		{"https://example.com", "generated.png"},
+	}
+	for _, tt := range tests {
+		if got := generatedFilename(tt.url, ".png"); got != tt.want {
+			t.Errorf("generatedFilename(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
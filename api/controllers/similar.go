@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-api/api/imaging"
+	"go-api/api/storage"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上传时的相似图片检查方式
+const (
+	similarOff    = "off"    // 只记录感知哈希，不检查
+	similarWarn   = "warn"   // 存在相似图片时在响应中列出
+	similarReject = "reject" // 存在相似图片时拒绝上传
+)
+
+// defaultSimilarDistance 判定为相似图片的默认汉明距离
+const defaultSimilarDistance = 5
+
+// reasonNearDuplicate 已存在相似图片，拒绝上传
+const reasonNearDuplicate = "near_duplicate"
+
+// parseSimilarOptions 解析相似图片检查选项
+// 环境变量 IMAGE_SIMILAR_MODE、IMAGE_SIMILAR_DISTANCE 设置默认值，查询参数 similar、similarDistance 可覆盖
+func (o *uploadOptions) parseSimilarOptions(c *gin.Context) error {
+	o.Similar = similarOff
+	if mode := strings.ToLower(strings.TrimSpace(os.Getenv("IMAGE_SIMILAR_MODE"))); mode != "" {
+		o.Similar = mode
+	}
+	if mode := c.Query("similar"); mode != "" {
+		o.Similar = strings.ToLower(mode)
+	}
+	switch o.Similar {
+	case similarOff, similarWarn, similarReject:
+	default:
+		return errors.New("similar 必须为 off、warn 或 reject")
+	}
+
+	o.SimilarDistance = int(envInt64("IMAGE_SIMILAR_DISTANCE", defaultSimilarDistance))
+	if distance := c.Query("similarDistance"); distance != "" {
+		value, err := parseSimilarDistance(distance)
+		if err != nil {
+			return err
+		}
+		o.SimilarDistance = value
+	}
+	o.SimilarDistance = max(0, min(o.SimilarDistance, storage.MaxSimilarDistance))
+	return nil
+}
+
+// parseSimilarDistance 解析汉明距离参数
+func parseSimilarDistance(value string) (int, error) {
+	distance, err := strconv.Atoi(value)
+	if err != nil || distance < 0 || distance > storage.MaxSimilarDistance {
+		return 0, fmt.Errorf("distance 必须为 0 到 %d 之间的整数", storage.MaxSimilarDistance)
+	}
+	return distance, nil
+}
+
+// prefetch 在写入存储前读取全部内容到临时文件，返回用于写入的 Reader
+// 读取失败时返回先读出已读取内容再返回该错误的 Reader，由写入过程处理错误；第二个返回值表示是否可以解码
+// 清理元数据时临时文件中已是原图，清理后的内容另写入一个临时文件
+func (b *variantBuffer) prefetch(r io.Reader) (io.Reader, bool) {
+	if !b.spooled {
+		if err := b.spool(r); err != nil {
+			b.overflow = true
+			return io.MultiReader(b.reader(), errorReader{err}), false
+		}
+		b.complete = true
+		return b.reader(), !b.overflow
+	}
+
+	staged, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		log.Printf("创建临时文件失败: %v", err)
+		return r, false
+	}
+	b.staged = staged
+	size, err := io.Copy(staged, r)
+	content := io.NewSectionReader(staged, 0, size)
+	if err != nil {
+		return io.MultiReader(content, errorReader{err}), false
+	}
+	b.complete = true
+	return content, !b.overflow
+}
+
+// errorReader 读取时返回指定错误
+type errorReader struct {
+	err error
+}
+
+// Read 返回指定错误
+func (r errorReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// imageHash 计算缓存图片的感知哈希，无法解码时返回 false
+func imageHash(buffer *variantBuffer, format imaging.Format) (uint64, bool) {
+	if buffer.overflow {
+		return 0, false
+	}
+	img, err := buffer.decode(format)
+	if err != nil {
+		if !errors.Is(err, imaging.ErrCannotDecode) {
+			log.Printf("计算感知哈希失败: %v", err)
+		}
+		return 0, false
+	}
+	return imaging.PHash(img), true
+}
+
+// rejectSimilar 拒绝模式下在写入前检查是否已存在相似图片
+// 缓存中没有完整内容或无法解码时不检查
+func rejectSimilar(ctx context.Context, store storage.ObjectStore, buffer *variantBuffer, format imaging.Format, opts uploadOptions) *uploadError {
+	hash, ok := imageHash(buffer, format)
+	if !ok {
+		return nil
+	}
+	similar, err := storage.FindSimilar(ctx, store, hash, opts.SimilarDistance, "")
+	if err != nil {
+		log.Printf("查找相似图片失败: %v", err)
+		return nil
+	}
+	if len(similar) == 0 {
+		return nil
+	}
+	return &uploadError{
+		Status:  http.StatusConflict,
+		Message: "已存在相似的图片",
+		Reason:  reasonNearDuplicate,
+		Details: gin.H{"phash": imaging.FormatHash(hash), "similar": similar},
+	}
+}
+
+// indexSimilar 写入后记录感知哈希，警告模式下查找相似图片，返回需要合并到响应中的字段
+// 记录失败不影响上传
+func indexSimilar(ctx context.Context, store storage.ObjectStore, info *storage.ObjectInfo, buffer *variantBuffer, format imaging.Format, opts uploadOptions) gin.H {
+	hash, ok := imageHash(buffer, format)
+	if !ok {
+		if opts.Similar == similarOff {
+			return nil
+		}
+		return gin.H{"similarError": "无法计算感知哈希，未检查相似图片"}
+	}
+
+	result := gin.H{"phash": imaging.FormatHash(hash)}
+	if opts.Similar == similarWarn {
+		similar, err := storage.FindSimilar(ctx, store, hash, opts.SimilarDistance, info.Key)
+		if err != nil {
+			log.Printf("查找相似图片失败: %s: %v", info.Key, err)
+			result["similarError"] = "查找相似图片失败"
+		} else {
+			result["similar"] = similar
+		}
+	}
+	if err := storage.IndexPHash(ctx, store, info.Key, hash); err != nil {
+		log.Printf("记录感知哈希失败: %s: %v", info.Key, err)
+	}
+	return result
+}
+
+// SimilarImages 查找与指定对象相似的图片，需要管理令牌
+// 对象尚未记录感知哈希时（如启用前上传的图片）读取原图计算并补录
+func SimilarImages(c *gin.Context) {
+	key := strings.TrimPrefix(c.Query("key"), "/")
+	if key == "" || storage.IsInternalKey(key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少有效的 key 参数"})
+		return
+	}
+	distance := defaultSimilarDistance
+	if value := c.Query("distance"); value != "" {
+		parsed, err := parseSimilarDistance(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		distance = parsed
+	}
+
+	store, err := storage.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "存储服务配置错误: " + err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+
+	if _, err := store.Head(ctx, key); errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对象不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取对象信息失败: " + err.Error()})
+		return
+	}
+
+	hash, err := storage.LookupPHash(ctx, store, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		hash, err = hashStoredImage(ctx, store, key)
+		if writeLimitError(c, err) {
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取感知哈希失败: " + err.Error()})
+		return
+	}
+
+	similar, err := storage.FindSimilar(ctx, store, hash, distance, key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"key":      key,
+		"phash":    imaging.FormatHash(hash),
+		"distance": distance,
+		"similar":  similar,
+	})
+}
+
+// hashStoredImage 读取已存储的图片计算感知哈希并写入索引，解码前检查像素数
+func hashStoredImage(ctx context.Context, store storage.ObjectStore, key string) (uint64, error) {
+	body, _, err := store.Get(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("读取对象失败: %v", err)
+	}
+	defer body.Close()
+
+	image, err := imaging.NewReader(body)
+	if err != nil {
+		return 0, fmt.Errorf("对象不是有效图片: %v", err)
+	}
+	guard, err := imaging.NewGuard(image, image.Format, imaging.Limits{MaxPixels: uploadLimits().MaxPixels})
+	if err != nil {
+		return 0, err
+	}
+	defer guard.Close()
+
+	img, err := imaging.Decode(guard, image.Format)
+	if err != nil {
+		return 0, err
+	}
+	hash := imaging.PHash(img)
+	if err := storage.IndexPHash(ctx, store, key, hash); err != nil {
+		log.Printf("记录感知哈希失败: %s: %v", key, err)
+	}
+	return hash, nil
+}
@@ -0,0 +1,382 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-api/api/imaging"
+	"go-api/api/storage"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 按需变换默认配置
+const (
+	defaultTransformMaxMB      = 20
+	defaultTransformMaxSize    = 4000
+	defaultTransformMaxPixels  = 24000000 // 约2400万像素，原图解码和输出的像素上限，低于上传限制
+	defaultTransformMaxDerived = 100      // 每张原图最多缓存的变换结果数量
+	defaultTransformQuality    = 85
+	defaultTransformStep       = 50
+	transformQualityStep       = 5
+	transformCacheControl      = "public, max-age=31536000, immutable"
+	transformFormatAuto        = "auto"
+	transformCacheHeader       = "X-Cache"
+	transformCacheHit          = "HIT"
+	transformCacheMiss         = "MISS"
+)
+
+// transformRequest 解析后的变换请求
+type transformRequest struct {
+	Options imaging.TransformOptions
+	Format  imaging.Format // 输出格式，零值表示沿用原图格式
+	Auto    bool           // 根据Accept请求头选择WebP
+	Quality int            // JPEG质量
+}
+
+// TransformImage 按查询参数对存储中的图片进行缩放、裁剪、旋转和模糊
+// 变换结果缓存在 _derived/ 下，再次请求相同参数时直接返回缓存
+func TransformImage(c *gin.Context) {
+	key := objectKeyParam(c)
+	if key == "" || storage.IsInternalKey(key) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对象不存在"})
+		return
+	}
+
+	req, err := parseTransformRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store, err := storage.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "存储服务配置错误: " + err.Error()})
+		return
+	}
+
+	// 先读取原图信息：输出格式默认根据存储的Content-Type确定，
+	// 原图版本（ETag或修改时间）参与缓存键和ETag，覆盖原图后不会返回旧的变换结果
+	ctx := c.Request.Context()
+	info, err := store.Head(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对象不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取对象信息失败"})
+		return
+	}
+
+	format := req.Format
+	if req.Auto {
+		c.Header("Vary", "Accept")
+		if strings.Contains(c.GetHeader("Accept"), imaging.WebP.ContentType) {
+			format = imaging.WebP
+		}
+	}
+	if format.Name == "" {
+		var ok bool
+		if format, ok = imaging.ByContentType(info.ContentType); !ok {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": imaging.ErrUnknownFormat.Error()})
+			return
+		}
+		if !imaging.CanEncode(format) {
+			format = imaging.JPEG
+		}
+	}
+
+	// 命中缓存时直接返回
+	version := objectVersion(info)
+	variant := transformVariant(req, format)
+	derivedKey := storage.DerivedKey(key, variant+"_v"+version, format.Extension())
+	etag := `"` + shortHash(derivedKey) + `"`
+	if c.GetHeader("If-None-Match") == etag {
+		setTransformCacheHeaders(c, etag)
+		c.Status(http.StatusNotModified)
+		return
+	}
+	if cached, info, err := store.Get(ctx, derivedKey); err == nil {
+		defer cached.Close()
+		setTransformCacheHeaders(c, etag)
+		c.Header(transformCacheHeader, transformCacheHit)
+		c.DataFromReader(http.StatusOK, info.Size, format.ContentType, cached, nil)
+		return
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("读取变换缓存失败: %s: %v", derivedKey, err)
+	}
+
+	// 每张原图的缓存数量有限，达到上限后拒绝新的参数组合，避免通过不断变换参数反复解码和写满缓存目录
+	if reached, err := derivedLimitReached(ctx, store, key); err != nil {
+		log.Printf("检查变换缓存数量失败: %s: %v", key, err)
+	} else if reached {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": errTooManyDerived.Error()})
+		return
+	}
+
+	originalBody, original, current, err := openOriginal(c, store, key)
+	if err != nil {
+		return
+	}
+	defer originalBody.Close()
+
+	data, err := transformImage(original, req, format)
+	if errors.Is(err, imaging.ErrCannotDecode) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, errTransformTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, errTransformOutputTooLarge) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if writeLimitError(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "图片处理失败"})
+		return
+	}
+
+	if objectVersion(current) != version {
+		// 读取时原图已被覆盖，返回新内容的处理结果但不写入旧版本的缓存
+		c.Header("Cache-Control", "no-store")
+		c.Header(transformCacheHeader, transformCacheMiss)
+		c.Data(http.StatusOK, format.ContentType, data)
+		return
+	}
+
+	// 缓存写入失败不影响本次响应
+	_, err = store.Put(ctx, derivedKey, bytes.NewReader(data), storage.PutOptions{
+		ContentType: format.ContentType,
+		Metadata: map[string]string{
+			storage.MetaVariantOf: storage.EscapeMetadata(key),
+			storage.MetaVariant:   variant,
+		},
+	})
+	if err != nil {
+		log.Printf("写入变换缓存失败: %s: %v", derivedKey, err)
+	}
+
+	setTransformCacheHeaders(c, etag)
+	c.Header(transformCacheHeader, transformCacheMiss)
+	c.Data(http.StatusOK, format.ContentType, data)
+}
+
+// setTransformCacheHeaders 设置长期缓存响应头，变换结果由原对象键、原图版本和参数唯一确定
+func setTransformCacheHeaders(c *gin.Context, etag string) {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", transformCacheControl)
+}
+
+// errTransformTooLarge 原图超过按需变换的大小上限
+var errTransformTooLarge = errors.New("原图过大，无法按需处理")
+
+// errTransformOutputTooLarge 变换后的尺寸超过像素上限
+var errTransformOutputTooLarge = errors.New("变换后的图片过大")
+
+// errTooManyDerived 原图的变换缓存数量已达上限
+var errTooManyDerived = errors.New("该图片的变换结果数量已达上限")
+
+// transformMaxPixels 按需变换的像素上限，取 IMAGE_TRANSFORM_MAX_PIXELS 和上传限制中较小的一个，0 表示不限制
+func transformMaxPixels() int64 {
+	limit := envInt64("IMAGE_TRANSFORM_MAX_PIXELS", defaultTransformMaxPixels)
+	if uploadLimit := uploadLimits().MaxPixels; uploadLimit > 0 && (limit <= 0 || uploadLimit < limit) {
+		limit = uploadLimit
+	}
+	return limit
+}
+
+// derivedLimitReached 原图的变换缓存是否已达到 IMAGE_TRANSFORM_MAX_DERIVED 个（不超过1000），设为 0 表示不限制
+// 只列举一页，不读取缓存内容
+func derivedLimitReached(ctx context.Context, store storage.ObjectStore, key string) (bool, error) {
+	limit := int(min(envInt64("IMAGE_TRANSFORM_MAX_DERIVED", defaultTransformMaxDerived), maxListLimit))
+	if limit <= 0 {
+		return false, nil
+	}
+	page, err := store.List(ctx, storage.ListOptions{Prefix: storage.DerivedPrefix + key + "/", MaxKeys: limit})
+	if err != nil {
+		return false, err
+	}
+	return len(page.Objects) >= limit, nil
+}
+
+// openOriginal 读取原图并识别格式，返回读取时的对象信息，失败时直接写入错误响应
+func openOriginal(c *gin.Context, store storage.ObjectStore, key string) (io.ReadCloser, *imaging.Reader, *storage.ObjectInfo, error) {
+	body, info, err := store.Get(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对象不存在"})
+		return nil, nil, nil, err
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取对象失败"})
+		return nil, nil, nil, err
+	}
+	if info.Size > envInt64("IMAGE_TRANSFORM_MAX_MB", defaultTransformMaxMB)*1024*1024 {
+		body.Close()
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": errTransformTooLarge.Error()})
+		return nil, nil, nil, errTransformTooLarge
+	}
+
+	image, err := imaging.NewReader(body)
+	if err != nil {
+		body.Close()
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return nil, nil, nil, err
+	}
+	return body, image, info, nil
+}
+
+// transformImage 解码、变换并编码图片
+func transformImage(original *imaging.Reader, req transformRequest, format imaging.Format) ([]byte, error) {
+	// 对象大小可能未知，读取时同样限制大小
+	limit := envInt64("IMAGE_TRANSFORM_MAX_MB", defaultTransformMaxMB) * 1024 * 1024
+	data, err := io.ReadAll(io.LimitReader(original, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取原图失败: %v", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, errTransformTooLarge
+	}
+
+	// 解码前只读取文件头检查原图和输出的像素数，直传或早期上传的对象未经过上传限制
+	maxPixels := transformMaxPixels()
+	guard, err := imaging.NewGuard(bytes.NewReader(data), original.Format, imaging.Limits{MaxPixels: maxPixels})
+	if err != nil {
+		return nil, err
+	}
+	guard.Close()
+	width, height := req.Options.OutputSize(guard.Info.Width, guard.Info.Height)
+	if maxPixels > 0 && int64(width)*int64(height) > maxPixels {
+		return nil, errTransformOutputTooLarge
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(data), original.Format)
+	if err != nil {
+		return nil, err
+	}
+	img = imaging.Transform(img, req.Options)
+
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, format, req.Quality); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseTransformRequest 解析查询参数
+// w、h：目标宽高；fit：cover、contain、fill；gravity：裁剪重心；q：JPEG质量；
+// fm：jpeg、png、gif、webp、auto；rotate：90、180、270；blur：模糊半径；enlarge：只指定一边时允许放大
+// 宽高向上取整到 IMAGE_TRANSFORM_STEP 的倍数，质量向上取整到5的倍数，模糊半径向上取整，限制可缓存的派生对象数量
+func parseTransformRequest(c *gin.Context) (transformRequest, error) {
+	req := transformRequest{Quality: defaultTransformQuality}
+	maxSize := int(envInt64("IMAGE_TRANSFORM_MAX_SIZE", defaultTransformMaxSize))
+	step := int(max(1, envInt64("IMAGE_TRANSFORM_STEP", defaultTransformStep)))
+
+	var err error
+	if req.Options.Width, err = queryInt(c, "w", 0, maxSize); err != nil {
+		return req, err
+	}
+	req.Options.Width = quantize(req.Options.Width, step, maxSize)
+	if req.Options.Height, err = queryInt(c, "h", 0, maxSize); err != nil {
+		return req, err
+	}
+	req.Options.Height = quantize(req.Options.Height, step, maxSize)
+	if req.Quality, err = queryInt(c, "q", 1, 100); err != nil {
+		return req, err
+	}
+	if req.Quality == 0 {
+		req.Quality = defaultTransformQuality
+	}
+	req.Quality = quantize(req.Quality, transformQualityStep, 100)
+	if req.Options.Rotate, err = queryInt(c, "rotate", -360, 360); err != nil {
+		return req, err
+	}
+	if blur := c.Query("blur"); blur != "" {
+		if req.Options.Blur, err = strconv.ParseFloat(blur, 64); err != nil {
+			return req, errors.New("blur 必须为数字")
+		}
+		req.Options.Blur = math.Ceil(req.Options.Blur)
+	}
+	if enlarge := c.Query("enlarge"); enlarge != "" {
+		if req.Options.Enlarge, err = strconv.ParseBool(enlarge); err != nil {
+			return req, errors.New("enlarge 必须为 true 或 false")
+		}
+	}
+	req.Options.Fit = strings.ToLower(c.Query("fit"))
+	if req.Options.Gravity, err = imaging.ParseGravity(c.Query("gravity")); err != nil {
+		return req, err
+	}
+	if err := req.Options.Validate(); err != nil {
+		return req, err
+	}
+
+	switch fm := strings.ToLower(c.Query("fm")); fm {
+	case "":
+	case transformFormatAuto:
+		req.Auto = true
+	default:
+		format, ok := imaging.ByExtension("." + fm)
+		if !ok || !imaging.CanEncode(format) {
+			return req, fmt.Errorf("不支持的输出格式: %s", fm)
+		}
+		req.Format = format
+	}
+	return req, nil
+}
+
+// queryInt 读取整数查询参数，未提供时返回 0
+func queryInt(c *gin.Context, name string, minValue, maxValue int) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < minValue || n > maxValue {
+		return 0, fmt.Errorf("%s 必须为 %d-%d 之间的整数", name, minValue, maxValue)
+	}
+	return n, nil
+}
+
+// quantize 将正数向上取整到 step 的倍数，不超过 maxValue
+func quantize(value, step, maxValue int) int {
+	if value <= 0 {
+		return value
+	}
+	return min(maxValue, (value+step-1)/step*step)
+}
+
+// objectVersion 返回原图版本的短哈希，优先使用ETag，没有ETag的后端使用修改时间和大小
+func objectVersion(info *storage.ObjectInfo) string {
+	if info.ETag != "" {
+		return shortHash(info.ETag)
+	}
+	return shortHash(fmt.Sprintf("%d-%d", info.LastModified.UnixNano(), info.Size))
+}
+
+// transformVariant 返回派生对象键中的变换参数部分，JPEG输出包含质量
+func transformVariant(req transformRequest, format imaging.Format) string {
+	variant := req.Options.CacheKey()
+	if format.Name == imaging.JPEG.Name {
+		variant += "_q" + strconv.Itoa(req.Quality)
+	}
+	return variant
+}
+
+// shortHash 返回字符串的短哈希，用于ETag
+func shortHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:8])
+}
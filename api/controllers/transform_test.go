@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"go-api/api/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseTransformRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		query       string
+		wantKey     string
+		wantQuality int
+		wantErr     bool
+	}{
+		{"", "original", defaultTransformQuality, false},
+		{"w=401", "w450", defaultTransformQuality, false},
+		{"w=4999", "", 0, true},
+		{"w=400&h=300&gravity=n&q=83", "w400_h300_fit-cover_g-north", 85, false},
+		{"rotate=-90&blur=2.3", "r270_blur3", defaultTransformQuality, false},
+		{"blur=51", "", 0, true},
+		{"fit=stretch", "", 0, true},
+		{"fm=bmp", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/img/a.png?"+tt.query, nil)
+			req, err := parseTransformRequest(c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTransformRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := req.Options.CacheKey(); got != tt.wantKey {
+				t.Errorf("CacheKey() = %q, want %q", got, tt.wantKey)
+			}
+			if req.Quality != tt.wantQuality {
+				t.Errorf("Quality = %d, want %d", req.Quality, tt.wantQuality)
+			}
+		})
+	}
+}
+
+// transformRouter 返回注册了按需变换路由的测试路由，原图为 100×50 的PNG
+func transformRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	store := newTestStore(t)
+	_, err := store.Put(context.Background(), "photo.png", bytes.NewReader(pngBytes(t, 100, 50)), storage.PutOptions{ContentType: "image/png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.GET("/api/img/*key", TransformImage)
+	return router
+}
+
+// getTransform 请求变换并返回响应
+func getTransform(router *gin.Engine, query string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/img/photo.png?"+query, nil))
+	return recorder
+}
+
+func TestTransformImageCache(t *testing.T) {
+	router := transformRouter(t)
+	for _, want := range []string{transformCacheMiss, transformCacheHit} {
+		recorder := getTransform(router, "w=40")
+		if recorder.Code != http.StatusOK || recorder.Header().Get(transformCacheHeader) != want {
+			t.Fatalf("status = %d, %s = %q, want %q", recorder.Code, transformCacheHeader, recorder.Header().Get(transformCacheHeader), want)
+		}
+	}
+	if recorder := getTransform(router, "w=40&blur=100"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("blur=100 status = %d, want 400", recorder.Code)
+	}
+}
+
+func TestTransformImagePixelBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxPixels string
+		query     string
+		want      int
+	}{
+		{"within budget", "", "w=50", http.StatusOK},
+		{"original too large", "4000", "w=50", http.StatusUnprocessableEntity},
+		{"output too large", "", "h=4000&enlarge=true", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("IMAGE_TRANSFORM_MAX_PIXELS", tt.maxPixels)
+			router := transformRouter(t)
+			if recorder := getTransform(router, tt.query); recorder.Code != tt.want {
+				t.Errorf("status = %d, want %d, body = %s", recorder.Code, tt.want, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestTransformImageDerivedLimit(t *testing.T) {
+	t.Setenv("IMAGE_TRANSFORM_MAX_DERIVED", "2")
+	router := transformRouter(t)
+	for _, query := range []string{"w=50", "h=50"} {
+		if recorder := getTransform(router, query); recorder.Code != http.StatusOK {
+			t.Fatalf("%s status = %d", query, recorder.Code)
+		}
+	}
+	if recorder := getTransform(router, "w=100&h=50"); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", recorder.Code)
+	}
+	// 已缓存的参数仍可访问
+	if recorder := getTransform(router, "w=50"); recorder.Code != http.StatusOK || recorder.Header().Get(transformCacheHeader) != transformCacheHit {
+		t.Errorf("cached status = %d, %s = %q", recorder.Code, transformCacheHeader, recorder.Header().Get(transformCacheHeader))
+	}
+}
@@ -0,0 +1,524 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-api/api/imaging"
+	"go-api/api/middleware"
+	"go-api/api/storage"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tus协议配置
+const (
+	defaultTusExpiresHours = 24
+	tusExtensions          = "creation,expiration,termination"
+	tusContentType         = "application/offset+octet-stream"
+	tusIDLength            = 32
+	tusProcessingTimeout   = 10 * time.Minute // 写入存储的最长时间，超时后允许重新写入
+)
+
+// 可续传上传的状态
+const (
+	tusStatusUploading  = "uploading"
+	tusStatusProcessing = "processing" // 已接收完整，正在写入存储
+	tusStatusCompleted  = "completed"
+	tusStatusFailed     = "failed"
+)
+
+// errTusConflict 上传状态已被其他请求修改
+var errTusConflict = &uploadError{Status: http.StatusConflict, Message: "上传正在被其他请求处理，请查询进度后重试"}
+
+// tusUpload 可续传上传的状态，保存在存储的 _tus/<上传ID>/info.json 中，多个实例之间共享
+// 更新时按读取时的版本条件写入，并发请求中只有一个能更新成功
+type tusUpload struct {
+	ID        string        `json:"id"`
+	Length    int64         `json:"length"`
+	Offset    int64         `json:"offset"`
+	Metadata  string        `json:"metadata,omitempty"` // 创建时的 Upload-Metadata 原文
+	Filename  string        `json:"filename"`
+	Chunks    []tusChunk    `json:"chunks"`
+	Options   uploadOptions `json:"options"` // 创建时的上传选项，完成后按相同选项写入
+	Status    string        `json:"status"`
+	Result    gin.H         `json:"result,omitempty"` // 完成后的上传结果或错误
+	CreatedAt time.Time     `json:"createdAt"`
+	ExpiresAt time.Time     `json:"expiresAt"`
+	// ProcessingAt 开始写入存储的时间，超过 tusProcessingTimeout 仍未完成时允许重新写入
+	ProcessingAt time.Time `json:"processingAt,omitempty"`
+
+	version *storage.ObjectInfo // 读取时的状态对象信息，用于条件写入
+}
+
+// tusChunk 已接收的分片
+type tusChunk struct {
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	Token  string `json:"token,omitempty"` // 写入令牌，避免并发请求写入同一分片键
+}
+
+// key 返回分片的对象键
+func (c tusChunk) key(id string) string {
+	return storage.TusChunkKey(id, c.Offset, c.Token)
+}
+
+// acceptsPatch 是否可以继续接收数据或重新写入存储
+// 写入存储失败（服务端错误）时状态保持 uploading，可在 Upload-Offset 等于文件大小时发送空 PATCH 重试
+func (u *tusUpload) acceptsPatch(now time.Time) bool {
+	switch u.Status {
+	case tusStatusUploading:
+		return true
+	case tusStatusProcessing:
+		return now.Sub(u.ProcessingAt) > tusProcessingTimeout
+	}
+	return false
+}
+
+// TusOptions 返回服务端支持的tus协议版本、扩展和文件大小上限
+func TusOptions(c *gin.Context) {
+	c.Header("Tus-Version", middleware.TusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	if maxSize := uploadLimits().MaxBytes; maxSize > 0 {
+		c.Header("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TusCreate 创建可续传上传（creation扩展），返回上传地址
+// 需要 Upload-Length 头，不支持延迟声明长度；Upload-Metadata 中的 filename 作为原文件名，
+// 查询参数与 /api/uploadImg 相同
+func TusCreate(c *gin.Context) {
+	opts, err := parseUploadOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Length 必须为正整数"})
+		return
+	}
+	if maxSize := uploadLimits().MaxBytes; maxSize > 0 && length > maxSize {
+		writeLimitError(c, &imaging.LimitError{Reason: imaging.ReasonFileTooLarge, Limit: maxSize, Actual: length})
+		return
+	}
+	metadata, err := parseTusMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store, err := storage.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "存储服务配置错误: " + err.Error()})
+		return
+	}
+
+	now := time.Now().UTC()
+	upload := &tusUpload{
+		ID:        newTusID(),
+		Length:    length,
+		Metadata:  c.GetHeader("Upload-Metadata"),
+		Filename:  tusFilename(metadata),
+		Chunks:    []tusChunk{},
+		Options:   opts,
+		Status:    tusStatusUploading,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(envInt64("TUS_EXPIRES_HOURS", defaultTusExpiresHours)) * time.Hour),
+	}
+	if err := saveTusUpload(c.Request.Context(), store, upload, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存上传状态失败"})
+		return
+	}
+
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+upload.ID)
+	c.Header("Upload-Expires", upload.ExpiresAt.Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// TusHead 返回已接收的字节数，客户端据此从断点继续上传
+func TusHead(c *gin.Context) {
+	_, upload, ok := loadTusUpload(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	c.Header("Upload-Expires", upload.ExpiresAt.Format(http.TimeFormat))
+	if upload.Metadata != "" {
+		c.Header("Upload-Metadata", upload.Metadata)
+	}
+	c.Status(http.StatusOK)
+}
+
+// TusPatch 从 Upload-Offset 处追加数据
+// 请求体先写入临时文件，连接中断时已接收的部分仍会保存为分片；
+// 接收完整后按 /api/uploadImg 的流程写入存储，结果URL通过 X-Upload-URL 头返回
+// 同一上传的并发请求只有一个能更新状态，其余返回 409
+func TusPatch(c *gin.Context) {
+	if c.ContentType() != tusContentType {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type 必须为 " + tusContentType})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Offset 必须为非负整数"})
+		return
+	}
+
+	store, upload, ok := loadTusUpload(c)
+	if !ok {
+		return
+	}
+	if !upload.acceptsPatch(time.Now()) || offset != upload.Offset {
+		c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload-Offset 与已接收的字节数不一致"})
+		return
+	}
+	remaining := upload.Length - upload.Offset
+	if c.Request.ContentLength > remaining {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "上传数据超出声明的文件大小"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	readErr, err := receiveTusChunk(ctx, store, upload, io.LimitReader(c.Request.Body, remaining))
+	if errors.Is(err, storage.ErrPreconditionFailed) {
+		c.JSON(errTusConflict.Status, errTusConflict.response())
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存上传数据失败"})
+		return
+	}
+	if readErr != nil {
+		// 客户端通常已断开，已接收的部分可通过HEAD查询后续传
+		log.Printf("接收上传数据中断: %s: %v", upload.ID, readErr)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取上传数据失败"})
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Expires", upload.ExpiresAt.Format(http.TimeFormat))
+	if upload.Offset < upload.Length {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	// 接收完整，写入存储
+	if uploadErr := finishTusUpload(ctx, store, upload); uploadErr != nil {
+		c.JSON(uploadErr.Status, uploadErr.response())
+		return
+	}
+	c.Header("X-Upload-URL", fmt.Sprint(upload.Result["url"]))
+	c.Status(http.StatusNoContent)
+}
+
+// TusDelete 终止上传并删除已接收的分片（termination扩展）
+func TusDelete(c *gin.Context) {
+	store, upload, ok := loadTusUpload(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	deleteTusChunks(ctx, store, upload)
+	if err := store.Delete(ctx, storage.TusInfoKey(upload.ID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除上传状态失败"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TusStatus 返回上传进度和完成后的结果，便于客户端在完成后获取访问URL
+func TusStatus(c *gin.Context) {
+	_, upload, ok := loadTusUpload(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":        upload.ID,
+		"filename":  upload.Filename,
+		"offset":    upload.Offset,
+		"length":    upload.Length,
+		"status":    upload.Status,
+		"result":    upload.Result,
+		"expiresAt": upload.ExpiresAt,
+	})
+}
+
+// loadTusUpload 读取路由中上传ID对应的状态，不存在或已过期时直接写入错误响应
+func loadTusUpload(c *gin.Context) (storage.ObjectStore, *tusUpload, bool) {
+	id := c.Param("id")
+	if !isTusID(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "上传不存在"})
+		return nil, nil, false
+	}
+
+	store, err := storage.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "存储服务配置错误: " + err.Error()})
+		return nil, nil, false
+	}
+
+	body, info, err := store.Get(c.Request.Context(), storage.TusInfoKey(id))
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "上传不存在"})
+		return nil, nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取上传状态失败"})
+		return nil, nil, false
+	}
+	defer body.Close()
+
+	var upload tusUpload
+	if err := json.NewDecoder(body).Decode(&upload); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取上传状态失败"})
+		return nil, nil, false
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "上传已过期"})
+		return nil, nil, false
+	}
+	upload.version = info
+	return store, &upload, true
+}
+
+// saveTusUpload 写入上传状态
+// 首次写入时登记过期时间，到期后由过期清理任务删除；之后的更新保留相同的过期时间，
+// 并且只在状态未被其他请求修改时写入，否则返回 storage.ErrPreconditionFailed
+func saveTusUpload(ctx context.Context, store storage.ObjectStore, upload *tusUpload, create bool) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+
+	key := storage.TusInfoKey(upload.ID)
+	if create {
+		_, err = storage.Save(ctx, store, bytes.NewReader(data), storage.SaveOptions{
+			PutOptions: storage.PutOptions{ContentType: "application/json"},
+			Key:        key,
+			FixedKey:   true,
+			TTL:        time.Until(upload.ExpiresAt),
+		})
+		return err
+	}
+	info, err := storage.PutIfMatch(ctx, store, key, bytes.NewReader(data), upload.version, storage.PutOptions{
+		ContentType: "application/json",
+		Metadata:    map[string]string{storage.MetaExpiresAt: upload.ExpiresAt.Format(time.RFC3339)},
+	})
+	if err != nil {
+		return err
+	}
+	upload.version = info
+	return nil
+}
+
+// receiveTusChunk 将请求体写入临时文件后保存为分片并更新状态
+// 返回读取请求体的错误和保存的错误，状态已被其他请求修改时删除本次分片并返回 storage.ErrPreconditionFailed
+func receiveTusChunk(ctx context.Context, store storage.ObjectStore, upload *tusUpload, body io.Reader) (readErr, err error) {
+	file, err := os.CreateTemp("", "tus-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	size, readErr := io.Copy(file, body)
+	if size == 0 {
+		return readErr, nil
+	}
+
+	// 客户端断开时请求的上下文已取消，仍需保存已接收的部分
+	ctx = context.WithoutCancel(ctx)
+	chunk := tusChunk{Offset: upload.Offset, Size: size, Token: newTusID()[:8]}
+	_, err = storage.Save(ctx, store, io.NewSectionReader(file, 0, size), storage.SaveOptions{
+		PutOptions: storage.PutOptions{ContentType: "application/octet-stream"},
+		Key:        chunk.key(upload.ID),
+		FixedKey:   true,
+		TTL:        time.Until(upload.ExpiresAt),
+	})
+	if err != nil {
+		log.Printf("保存上传分片失败: %s: %v", upload.ID, err)
+		return readErr, err
+	}
+
+	upload.Chunks = append(upload.Chunks, chunk)
+	upload.Offset += size
+	if err := saveTusUpload(ctx, store, upload, false); err != nil {
+		log.Printf("保存上传状态失败: %s: %v", upload.ID, err)
+		if deleteErr := store.Delete(ctx, chunk.key(upload.ID)); deleteErr != nil {
+			log.Printf("删除上传分片失败: %s: %v", upload.ID, deleteErr)
+		}
+		return readErr, err
+	}
+	return readErr, nil
+}
+
+// finishTusUpload 按顺序读取所有分片写入存储并记录结果
+// 写入前先将状态标记为处理中，避免并发请求重复写入；只在成功或图片不符合要求时删除分片，
+// 服务端错误时保留分片，客户端可以重试
+func finishTusUpload(ctx context.Context, store storage.ObjectStore, upload *tusUpload) *uploadError {
+	upload.Status = tusStatusProcessing
+	upload.ProcessingAt = time.Now().UTC()
+	if err := saveTusUpload(ctx, store, upload, false); err != nil {
+		if errors.Is(err, storage.ErrPreconditionFailed) {
+			return errTusConflict
+		}
+		log.Printf("保存上传状态失败: %s: %v", upload.ID, err)
+		return &uploadError{Status: http.StatusInternalServerError, Message: "保存上传状态失败"}
+	}
+
+	chunks := &tusChunkReader{ctx: ctx, store: store, upload: upload}
+	response, uploadErr := saveImage(ctx, store, chunks, upload.Filename, upload.Options, uploadLimits())
+	chunks.Close()
+
+	switch {
+	case uploadErr == nil:
+		upload.Status = tusStatusCompleted
+		upload.Result = response
+	case uploadErr.Status >= http.StatusInternalServerError:
+		upload.Status = tusStatusUploading
+		upload.Result = uploadErr.response()
+	default:
+		upload.Status = tusStatusFailed
+		upload.Result = uploadErr.response()
+	}
+	// 写入存储期间客户端可能已断开，仍需记录结果
+	ctx = context.WithoutCancel(ctx)
+	if err := saveTusUpload(ctx, store, upload, false); err != nil {
+		log.Printf("保存上传状态失败: %s: %v", upload.ID, err)
+		return uploadErr
+	}
+	if upload.Status != tusStatusUploading {
+		deleteTusChunks(ctx, store, upload)
+	}
+	return uploadErr
+}
+
+// deleteTusChunks 删除已接收的分片，失败时由过期清理任务删除
+func deleteTusChunks(ctx context.Context, store storage.ObjectStore, upload *tusUpload) {
+	keys := make([]string, 0, len(upload.Chunks))
+	for _, chunk := range upload.Chunks {
+		keys = append(keys, chunk.key(upload.ID))
+	}
+	if len(keys) == 0 {
+		return
+	}
+	if _, err := storage.DeleteMany(ctx, store, keys); err != nil {
+		log.Printf("删除上传分片失败: %s: %v", upload.ID, err)
+	}
+}
+
+// tusChunkReader 按顺序读取所有分片，读到某个分片时才打开
+type tusChunkReader struct {
+	ctx     context.Context
+	store   storage.ObjectStore
+	upload  *tusUpload
+	next    int
+	current io.ReadCloser
+}
+
+// Read 读取当前分片，读完后切换到下一个分片
+func (r *tusChunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if r.next >= len(r.upload.Chunks) {
+				return 0, io.EOF
+			}
+			chunk := r.upload.Chunks[r.next]
+			body, _, err := r.store.Get(r.ctx, chunk.key(r.upload.ID))
+			if err != nil {
+				return 0, fmt.Errorf("读取上传分片失败: %v", err)
+			}
+			r.current = body
+			r.next++
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Close 关闭正在读取的分片
+func (r *tusChunkReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
+
+// parseTusMetadata 解析 Upload-Metadata 头，格式为逗号分隔的 "键 Base64值"，值可以省略
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("Upload-Metadata 格式无效")
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("Upload-Metadata 中 %s 的值不是有效的Base64", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// tusFilename 读取客户端提供的文件名，tus-js-client 使用 filename，Uppy 使用 name
+func tusFilename(metadata map[string]string) string {
+	for _, key := range []string{"filename", "name"} {
+		if name := metadata[key]; name != "" {
+			return name
+		}
+	}
+	return "upload"
+}
+
+// newTusID 生成随机的上传ID
+func newTusID() string {
+	buf := make([]byte, tusIDLength/2)
+	if _, err := rand.Read(buf); err != nil {
+		// 系统随机数源不可用时无法安全生成ID
+		panic(fmt.Sprintf("生成随机数失败: %v", err))
+	}
+	return hex.EncodeToString(buf)
+}
+
+// isTusID 校验上传ID格式，避免拼接出其他对象键
+func isTusID(id string) bool {
+	if len(id) != tusIDLength {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
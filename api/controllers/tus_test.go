@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"go-api/api/middleware"
+	"go-api/api/storage"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTusUploadAcceptsPatch(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		upload tusUpload
+		want   bool
+	}{
+		{"uploading", tusUpload{Status: tusStatusUploading}, true},
+		{"processing", tusUpload{Status: tusStatusProcessing, ProcessingAt: now.Add(-time.Minute)}, false},
+		{"processing timed out", tusUpload{Status: tusStatusProcessing, ProcessingAt: now.Add(-tusProcessingTimeout - time.Second)}, true},
+		{"completed", tusUpload{Status: tusStatusCompleted}, false},
+		{"failed", tusUpload{Status: tusStatusFailed}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.upload.acceptsPatch(now); got != tt.want {
+				t.Errorf("acceptsPatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTusTestRouter 使用临时目录作为本地存储，注册tus路由
+func newTusTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	newTestStore(t)
+	router := gin.New()
+	tus := router.Group("/api/tus", middleware.TusResumable())
+	tus.POST("/", TusCreate)
+	tus.HEAD("/:id", TusHead)
+	tus.PATCH("/:id", TusPatch)
+	return router
+}
+
+// tusRequest 发送带 Tus-Resumable 头的请求
+func tusRequest(router *gin.Engine, method, path string, headers map[string]string, body []byte) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, bytes.NewReader(body))
+	request.Header.Set("Tus-Resumable", middleware.TusVersion)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestTusPatchOffset(t *testing.T) {
+	router := newTusTestRouter(t)
+	created := tusRequest(router, http.MethodPost, "/api/tus/", map[string]string{"Upload-Length": "10"}, nil)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", created.Code, created.Body)
+	}
+	location := created.Header().Get("Location")
+
+	// 依次发送的请求共享同一上传，未写入最后一个分片，不会触发写入存储
+	steps := []struct {
+		name       string
+		offset     string
+		body       string
+		wantStatus int
+		wantOffset string
+	}{
+		{"first chunk", "0", "abcd", http.StatusNoContent, "4"},
+		{"stale offset", "0", "abcd", http.StatusConflict, "4"},
+		{"offset ahead", "6", "ef", http.StatusConflict, "4"},
+		{"invalid offset", "-1", "ef", http.StatusBadRequest, ""},
+		{"exceeds length", "4", "efghijk", http.StatusRequestEntityTooLarge, ""},
+		{"second chunk", "4", "efg", http.StatusNoContent, "7"},
+		{"empty chunk", "7", "", http.StatusNoContent, "7"},
+	}
+	for _, step := range steps {
+		response := tusRequest(router, http.MethodPatch, location, map[string]string{
+			"Content-Type":  tusContentType,
+			"Upload-Offset": step.offset,
+		}, []byte(step.body))
+		if response.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d, body %s", step.name, response.Code, step.wantStatus, response.Body)
+		}
+		if got := response.Header().Get("Upload-Offset"); got != step.wantOffset {
+			t.Fatalf("%s: Upload-Offset = %q, want %q", step.name, got, step.wantOffset)
+		}
+	}
+
+	head := tusRequest(router, http.MethodHead, location, nil, nil)
+	if head.Code != http.StatusOK || head.Header().Get("Upload-Offset") != "7" || head.Header().Get("Upload-Length") != "10" {
+		t.Errorf("HEAD status = %d, Upload-Offset = %q, Upload-Length = %q", head.Code, head.Header().Get("Upload-Offset"), head.Header().Get("Upload-Length"))
+	}
+}
+
+func TestTusUploadComplete(t *testing.T) {
+	t.Setenv("STORAGE_CONTENT_ADDRESSED", "false")
+	router := newTusTestRouter(t)
+	data := pngBytes(t, 8, 8)
+	created := tusRequest(router, http.MethodPost, "/api/tus/?variants=false", map[string]string{
+		"Upload-Length":   strconv.Itoa(len(data)),
+		"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("photo.png")),
+	}, nil)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", created.Code, created.Body)
+	}
+	location := created.Header().Get("Location")
+
+	half := len(data) / 2
+	var response *httptest.ResponseRecorder
+	for _, chunk := range []struct {
+		offset int
+		data   []byte
+	}{{0, data[:half]}, {half, data[half:]}} {
+		response = tusRequest(router, http.MethodPatch, location, map[string]string{
+			"Content-Type":  tusContentType,
+			"Upload-Offset": strconv.Itoa(chunk.offset),
+		}, chunk.data)
+		if response.Code != http.StatusNoContent {
+			t.Fatalf("offset %d: status = %d, body %s", chunk.offset, response.Code, response.Body)
+		}
+	}
+
+	// 最后一个分片写入存储后返回访问URL
+	url := response.Header().Get("X-Upload-URL")
+	if !strings.HasSuffix(url, "photo.png") {
+		t.Fatalf("X-Upload-URL = %q", url)
+	}
+	store, err := storage.Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := url[strings.LastIndex(url, "/")+1:]
+	if info, err := store.Head(context.Background(), key); err != nil || info.Size != int64(len(data)) {
+		t.Errorf("Head(%q) = %+v, %v", key, info, err)
+	}
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go-api/api/imaging"
+	"go-api/api/storage"
+	"image"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultVariantMaxMB 生成变体时解码原图的默认大小上限
+const defaultVariantMaxMB = 20
+
+// VariantResponse 变体信息
+type VariantResponse struct {
+	Name   string `json:"name"`   // 预设名称
+	Format string `json:"format"` // 输出格式
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Size   int64  `json:"size"`
+}
+
+// variantPresets 读取 IMAGE_VARIANTS 变体预设，未配置时使用默认预设，配置为 off 时不生成变体
+func variantPresets() ([]imaging.Preset, error) {
+	spec, ok := os.LookupEnv("IMAGE_VARIANTS")
+	if !ok {
+		spec = imaging.DefaultPresets
+	}
+	if strings.EqualFold(strings.TrimSpace(spec), "off") {
+		return nil, nil
+	}
+	return imaging.ParsePresets(spec)
+}
+
+// variantWebPEnabled 是否额外生成WebP变体（IMAGE_VARIANT_WEBP，默认开启）
+func variantWebPEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("IMAGE_VARIANT_WEBP"))
+	return err != nil || enabled
+}
+
+// variantBuffer 将上传内容写入临时文件，用于写入存储、生成变体和计算感知哈希，超过上限后不再解码
+// 清理元数据时直接使用已写入临时文件的原图，解码后按清理时的方向旋转，与上传内容一致
+type variantBuffer struct {
+	file     *os.File
+	size     int64
+	limit    int64
+	overflow bool
+	spooled  bool     // 已通过 spool 写入完整原图
+	complete bool     // 已通过 prefetch 读出全部待写入内容，可在写入前计算图片信息
+	staged   *os.File // 拒绝相似图片时暂存的清理后内容
+
+	orientation int  // 解码后需要应用的EXIF方向
+	decoded     bool // 已解码，img 和 decodeErr 为解码结果
+	img         image.Image
+	decodeErr   error
+}
+
+// newVariantBuffer 按 IMAGE_VARIANT_MAX_MB 创建缓存
+func newVariantBuffer() *variantBuffer {
+	return &variantBuffer{limit: envInt64("IMAGE_VARIANT_MAX_MB", defaultVariantMaxMB) * 1024 * 1024}
+}
+
+// write 写入临时文件，首次写入时创建
+func (b *variantBuffer) write(p []byte) error {
+	if b.file == nil {
+		file, err := os.CreateTemp("", "upload-*")
+		if err != nil {
+			return fmt.Errorf("创建临时文件失败: %v", err)
+		}
+		b.file = file
+	}
+	n, err := b.file.Write(p)
+	b.size += int64(n)
+	return err
+}
+
+// spool 将全部内容写入临时文件，不受缓存上限限制，超过上限时只标记为不解码
+// 读取失败时返回读取错误，写入失败时返回包装后的错误
+func (b *variantBuffer) spool(r io.Reader) error {
+	b.spooled = true
+	if err := b.write(nil); err != nil {
+		return err
+	}
+	_, err := io.Copy(writerFunc(b.write), r)
+	b.overflow = b.size > b.limit
+	return err
+}
+
+// reader 返回从头读取临时文件的 Reader，多个 Reader 互不影响
+func (b *variantBuffer) reader() *io.SectionReader {
+	return io.NewSectionReader(b.file, 0, b.size)
+}
+
+// orient 清理元数据时已旋转像素的，记录方向供解码后旋转
+func (b *variantBuffer) orient(report *imaging.MetadataReport) {
+	if report != nil && report.Rotated {
+		b.orientation = report.Orientation
+	}
+}
+
+// decode 解码缓存的图片并按记录的方向旋转，多次调用只解码一次
+func (b *variantBuffer) decode(format imaging.Format) (image.Image, error) {
+	if !b.decoded {
+		b.decoded = true
+		b.img, b.decodeErr = imaging.Decode(b.reader(), format)
+		if b.decodeErr == nil {
+			b.img = imaging.Orient(b.img, b.orientation)
+		}
+	}
+	return b.img, b.decodeErr
+}
+
+// Close 删除临时文件
+func (b *variantBuffer) Close() {
+	for _, file := range []*os.File{b.file, b.staged} {
+		if file != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}
+	b.img = nil
+}
+
+// writerFunc 将写入函数转换为 io.Writer
+type writerFunc func(p []byte) error
+
+// Write 调用写入函数
+func (f writerFunc) Write(p []byte) (int, error) {
+	if err := f(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// uploadVariants 上传完成后按预设生成变体并写入 _variants/<原图键>/，返回需要合并到响应中的字段
+// 变体生成失败不影响原图上传，失败原因通过 variantsError 返回
+func uploadVariants(ctx context.Context, store storage.ObjectStore, original *storage.ObjectInfo, buffer *variantBuffer, format imaging.Format, opts uploadOptions) gin.H {
+	presets, err := variantPresets()
+	if err != nil {
+		log.Printf("变体预设配置错误: %v", err)
+		return gin.H{"variants": []VariantResponse{}, "variantsError": "变体预设配置错误"}
+	}
+	if len(presets) == 0 || !opts.Variants {
+		return nil
+	}
+	if buffer.overflow {
+		return gin.H{"variants": []VariantResponse{}, "variantsError": "文件过大，未生成变体"}
+	}
+
+	img, err := buffer.decode(format)
+	if err != nil {
+		if !errors.Is(err, imaging.ErrCannotDecode) {
+			log.Printf("生成变体失败: %s: %v", original.Key, err)
+		}
+		return gin.H{"variants": []VariantResponse{}, "variantsError": err.Error()}
+	}
+	variants, err := saveVariants(ctx, store, original, img, format, presets, opts)
+	result := gin.H{"variants": variants}
+	if err != nil {
+		log.Printf("生成变体失败: %s: %v", original.Key, err)
+		result["variantsError"] = "生成变体失败"
+	}
+	return result
+}
+
+// saveVariants 生成变体并逐个写入存储，返回已写入的变体
+func saveVariants(ctx context.Context, store storage.ObjectStore, original *storage.ObjectInfo, img image.Image, format imaging.Format, presets []imaging.Preset, opts uploadOptions) ([]VariantResponse, error) {
+	responses := []VariantResponse{}
+	variants, err := imaging.GenerateVariants(img, format, presets, variantWebPEnabled())
+	if err != nil {
+		return responses, err
+	}
+
+	for _, variant := range variants {
+		key := variantKey(original.Key, variant.Preset, variant.Format)
+		metadata := opts.metadata(storage.SourceUpload, path.Base(key))
+		metadata[storage.MetaVariantOf] = storage.EscapeMetadata(original.Key)
+		metadata[storage.MetaVariant] = variant.Preset
+
+		info, err := storage.Save(ctx, store, bytes.NewReader(variant.Data), storage.SaveOptions{
+			PutOptions: storage.PutOptions{
+				ContentType: variant.Format.ContentType,
+				Metadata:    metadata,
+			},
+			Key:      key,
+			Filename: path.Base(key),
+			TTL:      opts.TTL,
+			FixedKey: true,
+		})
+		if err != nil {
+			return responses, fmt.Errorf("写入变体 %s 失败: %v", key, err)
+		}
+		responses = append(responses, VariantResponse{
+			Name:   variant.Preset,
+			Format: variant.Format.Name,
+			URL:    info.URL,
+			Width:  variant.Width,
+			Height: variant.Height,
+			Size:   int64(len(variant.Data)),
+		})
+	}
+	return responses, nil
+}
+
+// variantKey 生成变体的对象键，如 photo.jpg → _variants/photo.jpg/thumb.webp
+func variantKey(originalKey, preset string, format imaging.Format) string {
+	return storage.VariantKey(originalKey, preset, format.Extension())
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-api/api/safehttp"
+	"go-api/api/webhooks"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 投递记录查询数量
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+// 每次扫描最多发送的重试数量，每次尝试最长10秒，默认值需小于无服务器函数的执行时限
+const (
+	defaultSweepLimit = 5
+	maxSweepLimit     = 50
+)
+
+// validateCallbackURL 检查回调地址，为空时不检查；配置 WEBHOOK_ALLOWED_HOSTS 时域名必须在其中
+// 内网地址在投递连接时拒绝
+func validateCallbackURL(callbackURL string) error {
+	if callbackURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(callbackURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("callbackUrl 必须为 http 或 https 地址")
+	}
+	if err := safehttp.CheckURL(callbackURL, webhooks.AllowedHosts()); err != nil {
+		return fmt.Errorf("callbackUrl 不可用: %v", err)
+	}
+	return nil
+}
+
+// queryLimit 读取查询参数 limit，默认 defaultLimit，不超过 maxLimit，无效时返回错误
+func queryLimit(c *gin.Context, defaultLimit, maxLimit int) (int, error) {
+	value := c.Query("limit")
+	if value == "" {
+		return defaultLimit, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		return 0, errors.New("limit 必须为正整数")
+	}
+	return min(parsed, maxLimit), nil
+}
+
+// notifyWebhook 通过共享投递器签名并立即尝试投递一次通知，失败后由重试扫描继续投递，错误只记录日志
+func notifyWebhook(ctx context.Context, event, callbackURL string, payload interface{}) (*webhooks.Delivery, error) {
+	dispatcher, err := webhooks.Default()
+	if err != nil {
+		log.Printf("webhook配置错误: %v", err)
+		return nil, err
+	}
+	delivery, err := dispatcher.Dispatch(ctx, event, callbackURL, payload)
+	if err != nil {
+		log.Printf("创建webhook投递失败: %s %s: %v", event, callbackURL, err)
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ListWebhookDeliveries 按创建时间倒序列出投递记录
+// 支持 event、status 过滤，limit 默认50，最大200
+func ListWebhookDeliveries(c *gin.Context) {
+	limit, err := queryLimit(c, defaultDeliveryLimit, maxDeliveryLimit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dispatcher, err := webhooks.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "webhook配置错误: " + err.Error()})
+		return
+	}
+	deliveries, err := dispatcher.Store.List(c.Request.Context(), webhooks.ListOptions{
+		Event:  c.Query("event"),
+		Status: c.Query("status"),
+		Limit:  limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取投递记录失败: " + err.Error()})
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*webhooks.Delivery{}
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// GetWebhookDelivery 返回单条投递记录及每次尝试的结果
+func GetWebhookDelivery(c *gin.Context) {
+	dispatcher, err := webhooks.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "webhook配置错误: " + err.Error()})
+		return
+	}
+	delivery, err := dispatcher.Store.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, webhooks.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "投递记录不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取投递记录失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// ReplayWebhookDelivery 使用原内容和当前密钥重新投递一次并返回最新记录，失败后由重试扫描继续投递
+func ReplayWebhookDelivery(c *gin.Context) {
+	dispatcher, err := webhooks.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "webhook配置错误: " + err.Error()})
+		return
+	}
+	delivery, err := dispatcher.Replay(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, webhooks.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "投递记录不存在"})
+		return
+	}
+	if errors.Is(err, webhooks.ErrInFlight) {
+		c.JSON(http.StatusConflict, gin.H{"error": "投递记录正在投递，请稍后重试"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重新投递失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// SweepWebhookDeliveries 发送到期的webhook重试，由定时任务调用
+// limit 为本次最多发送的数量，默认5，最大50
+func SweepWebhookDeliveries(c *gin.Context) {
+	limit, err := queryLimit(c, defaultSweepLimit, maxSweepLimit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dispatcher, err := webhooks.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "webhook配置错误: " + err.Error()})
+		return
+	}
+	result, err := dispatcher.SweepDue(c.Request.Context(), time.Now(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送webhook重试失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
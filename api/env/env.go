@@ -0,0 +1,31 @@
+// Package env 读取与部署环境相关的配置
+package env
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Production 是否运行在生产环境：APP_ENV=production，或 Vercel 的生产部署（VERCEL_ENV=production）
+func Production() bool {
+	for _, name := range []string{"APP_ENV", "VERCEL_ENV"} {
+		if strings.EqualFold(strings.TrimSpace(os.Getenv(name)), "production") {
+			return true
+		}
+	}
+	return false
+}
+
+// DataPath 返回环境变量 name 配置的数据文件路径
+// 未配置时开发环境使用临时目录下的 filename；生产环境返回错误，避免数据写入随实例回收而清空的临时目录
+func DataPath(name, filename string) (string, error) {
+	if path := os.Getenv(name); path != "" {
+		return path, nil
+	}
+	if Production() {
+		return "", fmt.Errorf("生产环境必须配置 %s", name)
+	}
+	return filepath.Join(os.TempDir(), filename), nil
+}
@@ -0,0 +1,196 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-api/api/lazy"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// 生成服务名称
+const (
+	ProviderKieAI = "kieai"
+	ProviderMock  = "mock"
+)
+
+// 任务状态
+const (
+	StatusGenerating = "generating" // 已提交，生成中
+	StatusSucceeded  = "succeeded"  // 生成成功
+	StatusFailed     = "failed"     // 生成失败
+	StatusCanceled   = "canceled"   // 已取消
+)
+
+var (
+	// ErrTaskNotFound 任务不存在
+	ErrTaskNotFound = errors.New("任务不存在")
+	// ErrCancelNotSupported 生成服务不支持取消任务
+	ErrCancelNotSupported = errors.New("生成服务不支持取消任务")
+)
+
+// Request 图片生成请求
+type Request struct {
+	ImageURLs   []string // 参考图片URL
+	Prompt      string   // 提示词
+	Size        string   // 图片比例，如 1:1、3:2
+	CallbackURL string   // 任务完成后生成服务回调的地址
+}
+
+// Task 生成任务状态
+type Task struct {
+	ID          string    `json:"taskId"`
+	Status      string    `json:"status"`               // 见 Status* 常量
+	Progress    float64   `json:"progress"`             // 进度，0～1
+	ResultURLs  []string  `json:"resultUrls,omitempty"` // 生成结果，生成服务提供的地址可能会过期
+	Error       string    `json:"error,omitempty"`      // 失败原因
+	CreatedAt   time.Time `json:"createdAt,omitempty"`
+	CompletedAt time.Time `json:"completedAt,omitempty"`
+}
+
+// Done 任务是否已结束
+func (t *Task) Done() bool {
+	return t.Status == StatusSucceeded || t.Status == StatusFailed || t.Status == StatusCanceled
+}
+
+// ProviderError 生成服务返回的错误
+type ProviderError struct {
+	Provider   string
+	StatusCode int    // HTTP状态码
+	Code       int    // 生成服务的业务错误码
+	Message    string // 生成服务返回的错误信息
+}
+
+// Error 返回错误描述
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s 返回错误（%d）: %s", e.Provider, e.Code, e.Message)
+}
+
+// ImageGenerator 图片生成服务统一接口
+type ImageGenerator interface {
+	// Name 返回生成服务名称
+	Name() string
+	// Submit 提交生成任务，返回的任务至少包含ID
+	Submit(ctx context.Context, req Request) (*Task, error)
+	// Status 查询任务状态，任务不存在时返回 ErrTaskNotFound
+	Status(ctx context.Context, taskID string) (*Task, error)
+	// Cancel 取消任务，不支持时返回 ErrCancelNotSupported
+	Cancel(ctx context.Context, taskID string) error
+}
+
+// CallbackParser 支持推送任务结果的生成服务实现该接口，用于解析回调内容
+type CallbackParser interface {
+	// ParseCallback 解析生成服务推送的回调请求体，返回的任务至少包含ID和状态
+	ParseCallback(body []byte) (*Task, error)
+}
+
+// 各生成服务必需的环境变量
+var requiredEnv = map[string][]string{
+	ProviderKieAI: {"KIEAI_API_KEY"},
+	ProviderMock:  {},
+}
+
+// defaultGenerator 进程级共享的生成服务客户端
+var defaultGenerator lazy.Value[ImageGenerator]
+
+// ConfigReport 生成服务配置检查结果
+type ConfigReport struct {
+	Provider string   `json:"provider"`
+	Missing  []string `json:"missing,omitempty"` // 缺少的环境变量
+	Errors   []string `json:"errors,omitempty"`  // 其他配置错误
+}
+
+// OK 所选生成服务受支持且必需的环境变量齐全
+func (r ConfigReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Errors) == 0
+}
+
+// String 返回写入启动日志和健康检查的描述
+func (r ConfigReport) String() string {
+	if r.OK() {
+		return fmt.Sprintf("生成服务 %s 配置有效", r.Provider)
+	}
+	var parts []string
+	if len(r.Missing) > 0 {
+		parts = append(parts, "缺少环境变量: "+strings.Join(r.Missing, ", "))
+	}
+	parts = append(parts, r.Errors...)
+	return fmt.Sprintf("生成服务 %s 配置无效，%s", r.Provider, strings.Join(parts, "；"))
+}
+
+// Provider 返回 IMAGE_GENERATOR 配置的生成服务名称，默认使用kieai
+func Provider() string {
+	provider := strings.ToLower(strings.TrimSpace(os.Getenv("IMAGE_GENERATOR")))
+	if provider == "" {
+		return ProviderKieAI
+	}
+	return provider
+}
+
+// ValidateConfig 检查生成服务相关配置，不创建任何客户端
+func ValidateConfig() ConfigReport {
+	report := ConfigReport{Provider: Provider()}
+
+	required, ok := requiredEnv[report.Provider]
+	if !ok {
+		report.Errors = append(report.Errors, fmt.Sprintf("不支持的生成服务: %s", report.Provider))
+	}
+	for _, name := range required {
+		if os.Getenv(name) == "" {
+			report.Missing = append(report.Missing, name)
+		}
+	}
+	return report
+}
+
+// New 根据 IMAGE_GENERATOR 环境变量创建生成服务客户端
+// 处理请求时应使用 Default 获取共享客户端
+func New() (ImageGenerator, error) {
+	provider := Provider()
+	switch provider {
+	case ProviderKieAI:
+		kieai, err := NewKieAI()
+		if err != nil {
+			return nil, err
+		}
+		return kieai, nil
+	case ProviderMock:
+		return NewMock(), nil
+	default:
+		return nil, fmt.Errorf("不支持的生成服务: %s", provider)
+	}
+}
+
+// Init 记录生成服务的配置检查结果并预先创建客户端，缺少密钥时只记录警告，不影响上传等其他接口
+func Init() ConfigReport {
+	report := ValidateConfig()
+	if report.OK() {
+		log.Println(report.String())
+	} else {
+		log.Println("⚠️ " + report.String())
+	}
+
+	Default()
+	return report
+}
+
+// Default 返回 IMAGE_GENERATOR 对应的共享客户端，缺少密钥时返回配置检查结果作为错误
+func Default() (ImageGenerator, error) {
+	return defaultGenerator.Get(func() (ImageGenerator, error) {
+		generator, err := New()
+		if err != nil {
+			if report := ValidateConfig(); !report.OK() {
+				err = errors.New(report.String())
+			}
+		}
+		return generator, err
+	})
+}
+
+// SetDefault 替换共享生成服务客户端，测试时可注入 Mock
+func SetDefault(generator ImageGenerator) {
+	defaultGenerator.Set(generator)
+}
@@ -0,0 +1,232 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultKieAIBaseURL kieai接口的默认地址
+const defaultKieAIBaseURL = "https://kieai.erweima.ai"
+
+// kieai任务状态
+const (
+	kieaiGenerating       = "GENERATING"
+	kieaiSuccess          = "SUCCESS"
+	kieaiCreateTaskFailed = "CREATE_TASK_FAILED"
+	kieaiGenerateFailed   = "GENERATE_FAILED"
+)
+
+// KieAI 基于kieai GPT-4o图片接口的生成服务
+type KieAI struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewKieAI 从环境变量 KIEAI_BASE_URL、KIEAI_API_KEY 创建kieai客户端
+func NewKieAI() (*KieAI, error) {
+	apiKey := os.Getenv("KIEAI_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("未配置kieai API密钥")
+	}
+	baseURL := strings.TrimRight(os.Getenv("KIEAI_BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = defaultKieAIBaseURL
+	}
+	return &KieAI{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Name 返回生成服务名称
+func (k *KieAI) Name() string {
+	return ProviderKieAI
+}
+
+// kieaiEnvelope kieai接口的统一响应结构
+type kieaiEnvelope struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// kieaiRecord 任务详情
+type kieaiRecord struct {
+	TaskID       string          `json:"taskId"`
+	Status       string          `json:"status"`
+	SuccessFlag  int             `json:"successFlag"` // 0 生成中，1 成功，2 失败
+	Progress     json.RawMessage `json:"progress"`    // 字符串或数字，如 "0.50"
+	ErrorMessage string          `json:"errorMessage"`
+	CreateTime   int64           `json:"createTime"`   // 毫秒时间戳
+	CompleteTime int64           `json:"completeTime"` // 毫秒时间戳
+	Response     *struct {
+		ResultURLs []string `json:"resultUrls"`
+	} `json:"response"`
+}
+
+// kieaiCallback 任务完成时kieai推送的回调内容，code 为200表示生成成功
+type kieaiCallback struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		TaskID string `json:"taskId"`
+		Info   *struct {
+			ResultURLs []string `json:"result_urls"`
+		} `json:"info"`
+	} `json:"data"`
+}
+
+// Submit 提交生成任务
+func (k *KieAI) Submit(ctx context.Context, req Request) (*Task, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"filesUrl":    req.ImageURLs,
+		"prompt":      req.Prompt,
+		"size":        req.Size,
+		"callBackUrl": req.CallbackURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("构建请求体失败: %v", err)
+	}
+
+	var data struct {
+		TaskID string `json:"taskId"`
+	}
+	if err := k.do(ctx, http.MethodPost, "/api/v1/gpt4o-image/generate", bytes.NewReader(payload), &data); err != nil {
+		return nil, err
+	}
+	if data.TaskID == "" {
+		return nil, fmt.Errorf("kieai 未返回任务ID")
+	}
+	return &Task{ID: data.TaskID, Status: StatusGenerating, CreatedAt: time.Now().UTC()}, nil
+}
+
+// Status 查询任务状态
+func (k *KieAI) Status(ctx context.Context, taskID string) (*Task, error) {
+	var record *kieaiRecord
+	path := "/api/v1/gpt4o-image/record-info?taskId=" + url.QueryEscape(taskID)
+	if err := k.do(ctx, http.MethodGet, path, nil, &record); err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrTaskNotFound
+	}
+	return record.task(taskID), nil
+}
+
+// Cancel kieai不支持取消任务
+func (k *KieAI) Cancel(ctx context.Context, taskID string) error {
+	return ErrCancelNotSupported
+}
+
+// ParseCallback 解析kieai推送的任务结果
+func (k *KieAI) ParseCallback(body []byte) (*Task, error) {
+	var callback kieaiCallback
+	if err := json.Unmarshal(body, &callback); err != nil {
+		return nil, fmt.Errorf("解析 kieai 回调失败: %v", err)
+	}
+	if callback.Data.TaskID == "" {
+		return nil, fmt.Errorf("kieai 回调缺少任务ID")
+	}
+
+	task := &Task{ID: callback.Data.TaskID, CompletedAt: time.Now().UTC()}
+	if callback.Code != http.StatusOK {
+		task.Status = StatusFailed
+		task.Error = callback.Msg
+		return task, nil
+	}
+	task.Status = StatusSucceeded
+	task.Progress = 1
+	if callback.Data.Info != nil {
+		task.ResultURLs = callback.Data.Info.ResultURLs
+	}
+	return task, nil
+}
+
+// do 发送请求并解析响应中的 data，业务错误码不为200时返回 *ProviderError
+func (k *KieAI) do(ctx context.Context, method, path string, body io.Reader, data interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, method, k.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("Authorization", "Bearer "+k.apiKey)
+
+	response, err := k.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer response.Body.Close()
+
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %v", err)
+	}
+	var envelope kieaiEnvelope
+	if err := json.Unmarshal(content, &envelope); err != nil {
+		return &ProviderError{Provider: ProviderKieAI, StatusCode: response.StatusCode, Code: response.StatusCode, Message: strings.TrimSpace(string(content))}
+	}
+	if response.StatusCode != http.StatusOK || envelope.Code != http.StatusOK {
+		return &ProviderError{Provider: ProviderKieAI, StatusCode: response.StatusCode, Code: envelope.Code, Message: envelope.Msg}
+	}
+	if len(envelope.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, data); err != nil {
+		return fmt.Errorf("解析 kieai 响应失败: %v", err)
+	}
+	return nil
+}
+
+// task 转换为统一的任务状态
+func (r *kieaiRecord) task(taskID string) *Task {
+	task := &Task{ID: r.TaskID, Error: r.ErrorMessage}
+	if task.ID == "" {
+		task.ID = taskID
+	}
+	switch {
+	case r.SuccessFlag == 1 || r.Status == kieaiSuccess:
+		task.Status = StatusSucceeded
+		task.Progress = 1
+	case r.SuccessFlag == 2 || r.Status == kieaiCreateTaskFailed || r.Status == kieaiGenerateFailed:
+		task.Status = StatusFailed
+	default:
+		task.Status = StatusGenerating
+	}
+	if task.Status != StatusSucceeded {
+		task.Progress = parseProgress(r.Progress)
+	}
+	if r.Response != nil {
+		task.ResultURLs = r.Response.ResultURLs
+	}
+	if r.CreateTime > 0 {
+		task.CreatedAt = time.UnixMilli(r.CreateTime).UTC()
+	}
+	if r.CompleteTime > 0 {
+		task.CompletedAt = time.UnixMilli(r.CompleteTime).UTC()
+	}
+	return task
+}
+
+// parseProgress 解析字符串或数字形式的进度
+func parseProgress(raw json.RawMessage) float64 {
+	text := strings.Trim(string(raw), `"`)
+	progress, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0
+	}
+	return progress
+}
@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newKieAITestServer 启动模拟kieai接口的服务，返回指向该服务的客户端
+func newKieAITestServer(t *testing.T, handler http.HandlerFunc) *KieAI {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	t.Setenv("KIEAI_API_KEY", "test-key")
+	t.Setenv("KIEAI_BASE_URL", server.URL+"/")
+	kieai, err := NewKieAI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kieai
+}
+
+func TestNewKieAIRequiresKey(t *testing.T) {
+	t.Setenv("KIEAI_API_KEY", "")
+	if _, err := NewKieAI(); err == nil {
+		t.Error("NewKieAI() without key should fail")
+	}
+}
+
+func TestKieAISubmit(t *testing.T) {
+	var payload map[string]interface{}
+	kieai := newKieAITestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/gpt4o-image/generate" || r.Method != http.MethodPost {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Write([]byte(`{"code":200,"msg":"success","data":{"taskId":"task-1"}}`))
+	})
+
+	task, err := kieai.Submit(context.Background(), Request{
+		ImageURLs:   []string{"https://example.com/in.png"},
+		Prompt:      "a cat",
+		Size:        "1:1",
+		CallbackURL: "https://example.com/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.ID != "task-1" || task.Status != StatusGenerating {
+		t.Errorf("Submit() = %+v", task)
+	}
+	if payload["prompt"] != "a cat" || payload["size"] != "1:1" || payload["callBackUrl"] != "https://example.com/callback" {
+		t.Errorf("payload = %v", payload)
+	}
+}
+
+func TestKieAIProviderError(t *testing.T) {
+	kieai := newKieAITestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":402,"msg":"insufficient credits"}`))
+	})
+
+	_, err := kieai.Submit(context.Background(), Request{})
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Code != 402 || providerErr.Message != "insufficient credits" {
+		t.Fatalf("Submit() error = %v, want ProviderError 402", err)
+	}
+}
+
+func TestKieAIStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		status   string
+		progress float64
+		results  int
+	}{
+		{"generating", `{"taskId":"t","status":"GENERATING","successFlag":0,"progress":"0.40"}`, StatusGenerating, 0.4, 0},
+		{"success", `{"taskId":"t","status":"SUCCESS","successFlag":1,"response":{"resultUrls":["https://example.com/r.png"]},"completeTime":1700000000000}`, StatusSucceeded, 1, 1},
+		{"failed", `{"taskId":"t","status":"GENERATE_FAILED","successFlag":2,"errorMessage":"bad prompt","progress":0.2}`, StatusFailed, 0.2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kieai := newKieAITestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("taskId") != "t" {
+					t.Errorf("taskId = %q", r.URL.Query().Get("taskId"))
+				}
+				w.Write([]byte(`{"code":200,"msg":"success","data":` + tt.data + `}`))
+			})
+
+			task, err := kieai.Status(context.Background(), "t")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if task.Status != tt.status || task.Progress != tt.progress || len(task.ResultURLs) != tt.results {
+				t.Errorf("Status() = %+v", task)
+			}
+		})
+	}
+}
+
+func TestKieAIStatusNotFound(t *testing.T) {
+	kieai := newKieAITestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":200,"msg":"success","data":null}`))
+	})
+
+	if _, err := kieai.Status(context.Background(), "gone"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Status() error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestKieAIParseCallback(t *testing.T) {
+	kieai := &KieAI{}
+
+	task, err := kieai.ParseCallback([]byte(`{"code":200,"msg":"success","data":{"taskId":"t","info":{"result_urls":["https://example.com/r.png"]}}}`))
+	if err != nil || task.Status != StatusSucceeded || len(task.ResultURLs) != 1 {
+		t.Fatalf("success callback = %+v, %v", task, err)
+	}
+
+	task, err = kieai.ParseCallback([]byte(`{"code":400,"msg":"content rejected","data":{"taskId":"t"}}`))
+	if err != nil || task.Status != StatusFailed || task.Error != "content rejected" {
+		t.Fatalf("failure callback = %+v, %v", task, err)
+	}
+
+	if _, err := kieai.ParseCallback([]byte(`{"code":200,"data":{}}`)); err == nil {
+		t.Error("callback without task ID should fail")
+	}
+}
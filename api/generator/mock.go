@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mockFailMarker 提示词包含该标记时模拟任务失败
+const mockFailMarker = "[fail]"
+
+// Mock 进程内的模拟生成服务，不访问网络，用于测试和离线开发
+// 任务ID按提交顺序生成；每次查询推进一步，查询 Steps 次后完成，
+// 结果为 ResultURL（未设置时为第一张参考图片）；提示词包含 [fail] 时任务失败
+type Mock struct {
+	ResultURL string // 生成结果地址
+	Steps     int    // 完成前需要查询的次数
+
+	mu    sync.Mutex
+	seq   int
+	tasks map[string]*mockTask
+}
+
+// mockTask 模拟任务的内部状态
+type mockTask struct {
+	task    Task
+	request Request
+	polls   int
+}
+
+// NewMock 创建模拟生成服务，结果地址可通过 MOCK_GENERATOR_RESULT_URL 配置
+func NewMock() *Mock {
+	return &Mock{
+		ResultURL: os.Getenv("MOCK_GENERATOR_RESULT_URL"),
+		Steps:     2,
+		tasks:     map[string]*mockTask{},
+	}
+}
+
+// Name 返回生成服务名称
+func (m *Mock) Name() string {
+	return ProviderMock
+}
+
+// Submit 记录任务并返回按顺序生成的任务ID
+func (m *Mock) Submit(ctx context.Context, req Request) (*Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.seq++
+	task := Task{
+		ID:        fmt.Sprintf("mock-%06d", m.seq),
+		Status:    StatusGenerating,
+		CreatedAt: time.Now().UTC(),
+	}
+	m.tasks[task.ID] = &mockTask{task: task, request: req}
+	return copyTask(task), nil
+}
+
+// Status 推进一步并返回任务状态
+func (m *Mock) Status(ctx context.Context, taskID string) (*Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.tasks[taskID]
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	if entry.task.Done() {
+		return copyTask(entry.task), nil
+	}
+
+	entry.polls++
+	steps := max(1, m.Steps)
+	if entry.polls < steps {
+		entry.task.Progress = float64(entry.polls) / float64(steps)
+		return copyTask(entry.task), nil
+	}
+
+	entry.task.Progress = 1
+	entry.task.CompletedAt = time.Now().UTC()
+	if strings.Contains(entry.request.Prompt, mockFailMarker) {
+		entry.task.Status = StatusFailed
+		entry.task.Error = "模拟生成失败"
+		return copyTask(entry.task), nil
+	}
+	entry.task.Status = StatusSucceeded
+	if url := m.resultURL(entry.request); url != "" {
+		entry.task.ResultURLs = []string{url}
+	}
+	return copyTask(entry.task), nil
+}
+
+// Cancel 取消未完成的任务
+func (m *Mock) Cancel(ctx context.Context, taskID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.tasks[taskID]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if !entry.task.Done() {
+		entry.task.Status = StatusCanceled
+		entry.task.CompletedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// ParseCallback 解析 Task 格式的JSON回调，用于在本地模拟生成服务推送结果
+func (m *Mock) ParseCallback(body []byte) (*Task, error) {
+	var task Task
+	if err := json.Unmarshal(body, &task); err != nil {
+		return nil, fmt.Errorf("解析回调失败: %v", err)
+	}
+	if task.ID == "" || task.Status == "" {
+		return nil, fmt.Errorf("回调缺少任务ID或状态")
+	}
+	return &task, nil
+}
+
+// resultURL 返回模拟的生成结果地址
+func (m *Mock) resultURL(req Request) string {
+	if m.ResultURL != "" {
+		return m.ResultURL
+	}
+	if len(req.ImageURLs) > 0 {
+		return req.ImageURLs[0]
+	}
+	return ""
+}
+
+// copyTask 复制任务，避免调用方修改内部状态
+func copyTask(task Task) *Task {
+	task.ResultURLs = append([]string(nil), task.ResultURLs...)
+	return &task
+}
@@ -0,0 +1,131 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMockLifecycle(t *testing.T) {
+	ctx := context.Background()
+	mock := NewMock()
+	mock.ResultURL = "https://example.com/result.png"
+
+	task, err := mock.Submit(ctx, Request{Prompt: "a cat", ImageURLs: []string{"https://example.com/in.png"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.ID != "mock-000001" || task.Status != StatusGenerating {
+		t.Fatalf("Submit() = %+v", task)
+	}
+	if second, _ := mock.Submit(ctx, Request{}); second.ID != "mock-000002" {
+		t.Errorf("second task ID = %q, want mock-000002", second.ID)
+	}
+
+	status, err := mock.Status(ctx, task.ID)
+	if err != nil || status.Status != StatusGenerating || status.Progress != 0.5 {
+		t.Fatalf("first Status() = %+v, %v", status, err)
+	}
+	status, err = mock.Status(ctx, task.ID)
+	if err != nil || status.Status != StatusSucceeded || status.Progress != 1 {
+		t.Fatalf("second Status() = %+v, %v", status, err)
+	}
+	if len(status.ResultURLs) != 1 || status.ResultURLs[0] != mock.ResultURL {
+		t.Errorf("ResultURLs = %v", status.ResultURLs)
+	}
+	if status.CompletedAt.IsZero() {
+		t.Error("CompletedAt not set")
+	}
+
+	// 返回的是副本，修改不影响内部状态
+	status.ResultURLs[0] = "changed"
+	if again, _ := mock.Status(ctx, task.ID); again.ResultURLs[0] != mock.ResultURL {
+		t.Errorf("internal state modified: %v", again.ResultURLs)
+	}
+}
+
+func TestMockResultDefaultsToFirstImage(t *testing.T) {
+	ctx := context.Background()
+	mock := &Mock{Steps: 1, tasks: map[string]*mockTask{}}
+
+	task, _ := mock.Submit(ctx, Request{ImageURLs: []string{"https://example.com/in.png", "https://example.com/b.png"}})
+	status, err := mock.Status(ctx, task.ID)
+	if err != nil || status.Status != StatusSucceeded {
+		t.Fatalf("Status() = %+v, %v", status, err)
+	}
+	if len(status.ResultURLs) != 1 || status.ResultURLs[0] != "https://example.com/in.png" {
+		t.Errorf("ResultURLs = %v", status.ResultURLs)
+	}
+}
+
+func TestMockFailure(t *testing.T) {
+	ctx := context.Background()
+	mock := &Mock{Steps: 1, tasks: map[string]*mockTask{}}
+
+	task, _ := mock.Submit(ctx, Request{Prompt: "draw [fail]"})
+	status, err := mock.Status(ctx, task.ID)
+	if err != nil || status.Status != StatusFailed || status.Error == "" {
+		t.Fatalf("Status() = %+v, %v", status, err)
+	}
+	if len(status.ResultURLs) != 0 {
+		t.Errorf("failed task has results: %v", status.ResultURLs)
+	}
+}
+
+func TestMockCancel(t *testing.T) {
+	ctx := context.Background()
+	mock := NewMock()
+
+	task, _ := mock.Submit(ctx, Request{})
+	if err := mock.Cancel(ctx, task.ID); err != nil {
+		t.Fatal(err)
+	}
+	status, err := mock.Status(ctx, task.ID)
+	if err != nil || status.Status != StatusCanceled {
+		t.Fatalf("Status() after cancel = %+v, %v", status, err)
+	}
+
+	if _, err := mock.Status(ctx, "missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Status(missing) error = %v, want ErrTaskNotFound", err)
+	}
+	if err := mock.Cancel(ctx, "missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Cancel(missing) error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestMockParseCallback(t *testing.T) {
+	mock := NewMock()
+
+	task, err := mock.ParseCallback([]byte(`{"taskId":"mock-000001","status":"succeeded","resultUrls":["https://example.com/r.png"]}`))
+	if err != nil || task.ID != "mock-000001" || task.Status != StatusSucceeded || len(task.ResultURLs) != 1 {
+		t.Fatalf("ParseCallback() = %+v, %v", task, err)
+	}
+	for _, body := range []string{`not json`, `{"status":"succeeded"}`, `{"taskId":"x"}`} {
+		if _, err := mock.ParseCallback([]byte(body)); err == nil {
+			t.Errorf("ParseCallback(%s) should fail", body)
+		}
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	t.Setenv("IMAGE_GENERATOR", "")
+	t.Setenv("KIEAI_API_KEY", "")
+	if report := ValidateConfig(); report.Provider != ProviderKieAI || report.OK() || len(report.Missing) != 1 {
+		t.Errorf("kieai without key: %+v", report)
+	}
+
+	t.Setenv("KIEAI_API_KEY", "key")
+	if report := ValidateConfig(); !report.OK() {
+		t.Errorf("kieai with key: %+v", report)
+	}
+
+	t.Setenv("IMAGE_GENERATOR", "Mock")
+	if report := ValidateConfig(); report.Provider != ProviderMock || !report.OK() {
+		t.Errorf("mock: %+v", report)
+	}
+
+	t.Setenv("IMAGE_GENERATOR", "other")
+	if report := ValidateConfig(); report.OK() || len(report.Errors) != 1 {
+		t.Errorf("unsupported provider: %+v", report)
+	}
+}
@@ -0,0 +1,116 @@
+package imaging
+
+import (
+	"image"
+	"math"
+	"strings"
+)
+
+// base83Chars BlurHash使用的83进制字符表
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// BlurHash 按 https://blurha.sh 的算法编码图片，componentsX、componentsY 为横纵方向的分量数（1～9）
+// 透明区域按白色背景处理；为减少计算量，调用方应先将图片缩小
+func BlurHash(img *image.NRGBA, componentsX, componentsY int) string {
+	componentsX = max(1, min(componentsX, 9))
+	componentsY = max(1, min(componentsY, 9))
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+
+	// 预先转换为线性RGB
+	linear := make([][3]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			offset := img.PixOffset(img.Bounds().Min.X+x, img.Bounds().Min.Y+y)
+			p := img.Pix[offset : offset+4]
+			alpha := float64(p[3]) / 255
+			for c := 0; c < 3; c++ {
+				value := float64(p[c])*alpha + 255*(1-alpha)
+				linear[y*width+x][c] = sRGBToLinear(value)
+			}
+		}
+	}
+
+	factors := make([][3]float64, 0, componentsX*componentsY)
+	for j := 0; j < componentsY; j++ {
+		for i := 0; i < componentsX; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				basisY := math.Cos(math.Pi * float64(j) * float64(y) / float64(height))
+				for x := 0; x < width; x++ {
+					basis := normalisation * math.Cos(math.Pi*float64(i)*float64(x)/float64(width)) * basisY
+					pixel := linear[y*width+x]
+					factor[0] += basis * pixel[0]
+					factor[1] += basis * pixel[1]
+					factor[2] += basis * pixel[2]
+				}
+			}
+			scale := 1 / float64(width*height)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	var hash strings.Builder
+	encodeBase83(&hash, (componentsX-1)+(componentsY-1)*9, 1)
+
+	// 交流分量按最大绝对值量化
+	maximumValue := 1.0
+	if len(factors) > 1 {
+		actualMaximum := 0.0
+		for _, factor := range factors[1:] {
+			for _, value := range factor {
+				actualMaximum = math.Max(actualMaximum, math.Abs(value))
+			}
+		}
+		quantisedMaximum := int(math.Max(0, math.Min(82, math.Floor(actualMaximum*166-0.5))))
+		maximumValue = float64(quantisedMaximum+1) / 166
+		encodeBase83(&hash, quantisedMaximum, 1)
+	} else {
+		encodeBase83(&hash, 0, 1)
+	}
+
+	dc := factors[0]
+	encodeBase83(&hash, linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4)
+	for _, factor := range factors[1:] {
+		var quantised [3]int
+		for c, value := range factor {
+			quantised[c] = int(math.Max(0, math.Min(18, math.Floor(signPow(value/maximumValue, 0.5)*9+9.5))))
+		}
+		encodeBase83(&hash, quantised[0]*19*19+quantised[1]*19+quantised[2], 2)
+	}
+	return hash.String()
+}
+
+// encodeBase83 将 value 编码为 length 位83进制字符
+func encodeBase83(b *strings.Builder, value, length int) {
+	for i := 1; i <= length; i++ {
+		digit := (value / int(math.Pow(83, float64(length-i)))) % 83
+		b.WriteByte(base83Chars[digit])
+	}
+}
+
+// sRGBToLinear 将0～255的sRGB值转换为0～1的线性值
+func sRGBToLinear(value float64) float64 {
+	v := value / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB 将线性值转换为0～255的sRGB值
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+// signPow 保留符号的幂运算
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
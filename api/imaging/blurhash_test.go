@@ -0,0 +1,74 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// solidImage 返回单色图片
+func solidImage(width, height int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// gradientImage 返回16x9的渐变图片，红色沿横向、绿色沿纵向变化
+func gradientImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 9))
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 16; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x * 16), uint8(y * 28), 128, 255})
+		}
+	}
+	return img
+}
+
+// 期望值按 https://github.com/woltapp/blurhash 参考实现的算法计算
+func TestBlurHash(t *testing.T) {
+	white := color.NRGBA{255, 255, 255, 255}
+	tests := []struct {
+		name                     string
+		img                      *image.NRGBA
+		componentsX, componentsY int
+		want                     string
+	}{
+		{"white 1x1", solidImage(4, 4, white), 1, 1, "00TSUA"},
+		{"black 1x1", solidImage(4, 4, color.NRGBA{0, 0, 0, 255}), 1, 1, "000000"},
+		{"transparent as white", solidImage(4, 4, color.NRGBA{0, 0, 0, 0}), 1, 1, "00TSUA"},
+		{"white 4x3", solidImage(8, 6, white), 4, 3, "LsTSUA_3fQ_3~qt7fQt7fQfQfQfQ"},
+		{"components clamped", solidImage(4, 4, white), 0, 12, "=~TSUA~qfQ~qfQ~qfQ~q~q"},
+		{"gradient 4x3", gradientImage(), 4, 3, "LsGugw2@wxozu]R-jtjIf%fQfQfQ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BlurHash(tt.img, tt.componentsX, tt.componentsY); got != tt.want {
+				t.Errorf("BlurHash() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlurHashLength(t *testing.T) {
+	img := gradientImage()
+	tests := []struct {
+		componentsX, componentsY int
+	}{
+		{1, 1}, {4, 3}, {9, 9}, {3, 7},
+	}
+	for _, tt := range tests {
+		got := BlurHash(img, tt.componentsX, tt.componentsY)
+		// 1位分量数 + 1位最大值 + 4位直流分量 + 每个交流分量2位
+		if want := 4 + 2*tt.componentsX*tt.componentsY; len(got) != want {
+			t.Errorf("BlurHash(%dx%d) = %s, length %d, want %d", tt.componentsX, tt.componentsY, got, len(got), want)
+		}
+		if strings.Trim(got, base83Chars) != "" {
+			t.Errorf("BlurHash(%dx%d) = %s contains characters outside base83", tt.componentsX, tt.componentsY, got)
+		}
+	}
+}
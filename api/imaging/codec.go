@@ -0,0 +1,76 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/gen2brain/heic"
+	_ "golang.org/x/image/webp"
+)
+
+// ErrCannotDecode 格式可以识别但无法在服务端解码
+var ErrCannotDecode = errors.New("暂不支持解码该格式")
+
+// Decode 按已识别的格式解码图片，AVIF等无法解码的格式返回 ErrCannotDecode
+func Decode(r io.Reader, format Format) (image.Image, error) {
+	switch format.Name {
+	case JPEG.Name, PNG.Name, GIF.Name, WebP.Name:
+		img, _, err := image.Decode(r)
+		if err != nil {
+			return nil, fmt.Errorf("解码图片失败: %v", err)
+		}
+		return img, nil
+	case HEIC.Name:
+		// heic 包只按 heic 品牌注册，直接调用以支持 mif1 等品牌
+		img, err := heic.Decode(r)
+		if err != nil {
+			return nil, fmt.Errorf("解码图片失败: %v", err)
+		}
+		return img, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrCannotDecode, format.Name)
+}
+
+// CanEncode 是否支持在服务端编码为该格式
+func CanEncode(format Format) bool {
+	switch format.Name {
+	case JPEG.Name, PNG.Name, GIF.Name, WebP.Name:
+		return true
+	}
+	return false
+}
+
+// Encode 将图片编码为指定格式，quality 只对JPEG生效，WebP使用无损编码
+func Encode(w io.Writer, img image.Image, format Format, quality int) error {
+	var err error
+	switch format.Name {
+	case JPEG.Name:
+		err = jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case PNG.Name:
+		err = pngEncoder.Encode(w, img)
+	case GIF.Name:
+		err = encodeGIF(w, img)
+	case WebP.Name:
+		err = nativewebp.Encode(w, img, nil)
+	default:
+		return fmt.Errorf("暂不支持编码为 %s 格式", format.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("编码 %s 图片失败: %v", format.Name, err)
+	}
+	return nil
+}
+
+// pngEncoder 变体和转换结果使用较快的压缩级别
+var pngEncoder = png.Encoder{CompressionLevel: png.BestSpeed}
+
+// encodeGIF 编码静态GIF，动图只保留第一帧
+func encodeGIF(w io.Writer, img image.Image) error {
+	return gif.Encode(w, img, &gif.Options{NumColors: 256})
+}
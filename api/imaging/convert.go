@@ -0,0 +1,25 @@
+package imaging
+
+import (
+	"fmt"
+	"image/jpeg"
+	"io"
+
+	"github.com/gen2brain/heic"
+)
+
+// DefaultJPEGQuality 转换为JPEG时的默认质量
+const DefaultJPEGQuality = 90
+
+// HEICToJPEG 将HEIC图片解码后重新编码为JPEG
+// 解码需要将完整图片读入内存
+func HEICToJPEG(w io.Writer, r io.Reader, quality int) error {
+	img, err := heic.Decode(r)
+	if err != nil {
+		return fmt.Errorf("解码HEIC图片失败: %v", err)
+	}
+	if err := jpeg.Encode(w, img, &jpeg.Options{Quality: quality}); err != nil {
+		return fmt.Errorf("编码JPEG图片失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// EXIF标签
+const (
+	tagOrientation = 0x0112
+	tagCopyright   = 0x8298
+	tagGPSInfo     = 0x8825
+)
+
+// exifHeader JPEG APP1 和部分 WebP EXIF 块中TIFF数据前的标识
+var exifHeader = []byte("Exif\x00\x00")
+
+// exifInfo 从EXIF中读取的信息
+type exifInfo struct {
+	Orientation int    // 方向，1-8，未设置时为0
+	Copyright   string // 版权信息
+	HasGPS      bool   // 是否包含GPS信息
+}
+
+// parseEXIF 解析TIFF格式的EXIF数据中 IFD0 的方向、版权和GPS信息，数据可带 Exif\0\0 前缀
+func parseEXIF(data []byte) exifInfo {
+	var info exifInfo
+	data = bytes.TrimPrefix(data, exifHeader)
+	if len(data) < 8 {
+		return info
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return info
+	}
+
+	offset := int(order.Uint32(data[4:8]))
+	if offset < 8 || offset+2 > len(data) {
+		return info
+	}
+	count := int(order.Uint16(data[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(data) {
+			break
+		}
+		tag := order.Uint16(data[entry:])
+		typ := order.Uint16(data[entry+2:])
+		n := int(order.Uint32(data[entry+4:]))
+		switch tag {
+		case tagOrientation:
+			if typ == 3 {
+				info.Orientation = int(order.Uint16(data[entry+8:]))
+			}
+		case tagGPSInfo:
+			info.HasGPS = true
+		case tagCopyright:
+			if typ != 2 || n == 0 {
+				continue
+			}
+			value := data[entry+8 : entry+12]
+			if n > 4 {
+				start := int(order.Uint32(data[entry+8:]))
+				if start < 0 || start+n > len(data) {
+					continue
+				}
+				value = data[start : start+n]
+			}
+			info.Copyright = string(bytes.TrimRight(value[:min(n, len(value))], "\x00"))
+		}
+	}
+	if info.Orientation < 1 || info.Orientation > 8 {
+		info.Orientation = 0
+	}
+	return info
+}
+
+// buildEXIF 生成只包含方向和版权信息的TIFF格式EXIF数据（大端序，不含 Exif\0\0 前缀）
+// orientation 为 0 或 copyright 为空时不写入对应条目
+func buildEXIF(orientation int, copyright string) []byte {
+	type entry struct {
+		tag, kind uint16
+		value     []byte
+	}
+	// IFD条目按标签升序排列
+	var entries []entry
+	if orientation > 0 {
+		value := make([]byte, 2)
+		binary.BigEndian.PutUint16(value, uint16(orientation))
+		entries = append(entries, entry{tagOrientation, 3, value}) // SHORT
+	}
+	if copyright != "" {
+		entries = append(entries, entry{tagCopyright, 2, append([]byte(copyright), 0)}) // ASCII
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("MM\x00*")
+	binary.Write(&buf, binary.BigEndian, uint32(8)) // IFD0 偏移
+	binary.Write(&buf, binary.BigEndian, uint16(len(entries)))
+	// 超过4字节的值紧跟在IFD之后
+	offset := uint32(8 + 2 + 12*len(entries) + 4)
+	var values []byte
+	for _, e := range entries {
+		binary.Write(&buf, binary.BigEndian, e.tag)
+		binary.Write(&buf, binary.BigEndian, e.kind)
+		count := uint32(len(e.value))
+		if e.kind == 3 {
+			count = 1
+		}
+		binary.Write(&buf, binary.BigEndian, count)
+		if len(e.value) <= 4 {
+			padded := make([]byte, 4)
+			copy(padded, e.value)
+			buf.Write(padded)
+			continue
+		}
+		binary.Write(&buf, binary.BigEndian, offset+uint32(len(values)))
+		values = append(values, e.value...)
+	}
+	binary.Write(&buf, binary.BigEndian, uint32(0)) // 无下一个IFD
+	buf.Write(values)
+	return buf.Bytes()
+}
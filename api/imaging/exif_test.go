@@ -0,0 +1,56 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// littleEndianEXIF 构建小端序的IFD0，条目的值均内联
+func littleEndianEXIF(entries ...[3]uint32) []byte {
+	data := []byte("II*\x00")
+	data = binary.LittleEndian.AppendUint32(data, 8)
+	data = binary.LittleEndian.AppendUint16(data, uint16(len(entries)))
+	for _, e := range entries {
+		data = binary.LittleEndian.AppendUint16(data, uint16(e[0]))
+		data = binary.LittleEndian.AppendUint16(data, uint16(e[1]))
+		data = binary.LittleEndian.AppendUint32(data, 1)
+		data = binary.LittleEndian.AppendUint32(data, e[2])
+	}
+	return binary.LittleEndian.AppendUint32(data, 0)
+}
+
+func TestParseEXIF(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want exifInfo
+	}{
+		{"empty", nil, exifInfo{}},
+		{"invalid header", []byte("XX*\x00\x08\x00\x00\x00\x00\x00"), exifInfo{}},
+		{"truncated", []byte("MM\x00*\x00\x00"), exifInfo{}},
+		{"ifd offset out of range", []byte("MM\x00*\x00\x00\x01\x00"), exifInfo{}},
+		{"orientation", buildEXIF(6, ""), exifInfo{Orientation: 6}},
+		{"exif prefix", append([]byte("Exif\x00\x00"), buildEXIF(3, "")...), exifInfo{Orientation: 3}},
+		{"short copyright", buildEXIF(0, "AB"), exifInfo{Copyright: "AB"}},
+		{"long copyright", buildEXIF(8, "(c) Example Inc."), exifInfo{Orientation: 8, Copyright: "(c) Example Inc."}},
+		{"orientation out of range", buildEXIF(9, ""), exifInfo{}},
+		{"little endian with gps", littleEndianEXIF([3]uint32{tagOrientation, 3, 5}, [3]uint32{tagGPSInfo, 4, 100}), exifInfo{Orientation: 5, HasGPS: true}},
+		{"orientation with wrong type", littleEndianEXIF([3]uint32{tagOrientation, 4, 6}), exifInfo{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseEXIF(tt.data); got != tt.want {
+				t.Errorf("parseEXIF() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEXIFCopyrightOutOfRange(t *testing.T) {
+	data := buildEXIF(0, "a long copyright notice")
+	// 截断超过4字节的值，偏移指向数据之外
+	data = data[:len(data)-10]
+	if got := parseEXIF(data); got.Copyright != "" {
+		t.Errorf("Copyright = %q, want empty", got.Copyright)
+	}
+}
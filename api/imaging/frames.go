@@ -0,0 +1,179 @@
+package imaging
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// frameCounter 在后台顺序解析图片结构统计帧数，只跳过数据块，不解码像素
+// 读取方通过管道写入已读取的数据
+type frameCounter struct {
+	writer *io.PipeWriter
+	done   chan struct{}
+	count  int
+	err    error
+}
+
+// countsFrames 检查格式是否可能包含多帧
+func countsFrames(format Format) bool {
+	switch format.Name {
+	case PNG.Name, GIF.Name, WebP.Name:
+		return true
+	}
+	return false
+}
+
+// newFrameCounter 启动帧数统计，超过 maxFrames 时立即结束并返回 *LimitError
+func newFrameCounter(format Format, maxFrames int) *frameCounter {
+	reader, writer := io.Pipe()
+	counter := &frameCounter{writer: writer, done: make(chan struct{})}
+	go func() {
+		defer close(counter.done)
+		counter.count, counter.err = countFrames(bufio.NewReader(reader), format, maxFrames)
+		if counter.err != nil {
+			reader.CloseWithError(counter.err)
+			return
+		}
+		// 结构解析结束后继续读取剩余数据，避免写入方阻塞
+		io.Copy(io.Discard, reader)
+	}()
+	return counter
+}
+
+// wait 等待统计结束并返回错误
+func (c *frameCounter) wait() error {
+	<-c.done
+	return c.err
+}
+
+// countFrames 按格式统计帧数，结构无法识别时按单帧处理
+func countFrames(reader *bufio.Reader, format Format, maxFrames int) (int, error) {
+	var count int
+	var err error
+	check := func(frames int) error {
+		if maxFrames > 0 && frames > maxFrames {
+			return &LimitError{Reason: ReasonTooManyFrames, Limit: int64(maxFrames), Actual: int64(frames)}
+		}
+		return nil
+	}
+
+	switch format.Name {
+	case PNG.Name:
+		count, err = countPNGFrames(reader, check)
+	case GIF.Name:
+		count, err = countGIFFrames(reader, check)
+	case WebP.Name:
+		count, err = countWebPFrames(reader, check)
+	}
+	if _, ok := err.(*LimitError); ok {
+		return count, err
+	}
+	return max(count, 1), nil
+}
+
+// countPNGFrames 读取APNG的acTL块获取帧数，acTL位于图像数据之前
+func countPNGFrames(reader *bufio.Reader, check func(int) error) (int, error) {
+	if _, err := reader.Discard(len(pngSignature)); err != nil {
+		return 0, err
+	}
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return 0, err
+		}
+		length := int64(binary.BigEndian.Uint32(header))
+		switch string(header[4:]) {
+		case "acTL":
+			data := make([]byte, 4)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return 0, err
+			}
+			frames := int(binary.BigEndian.Uint32(data))
+			return frames, check(frames)
+		case "IDAT", "IEND":
+			return 1, nil
+		}
+		// 数据和CRC
+		if _, err := io.CopyN(io.Discard, reader, length+4); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// countGIFFrames 统计GIF中的图像描述符
+func countGIFFrames(reader *bufio.Reader, check func(int) error) (int, error) {
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, err
+	}
+	if header[10]&0x80 != 0 {
+		if _, err := io.CopyN(io.Discard, reader, colorTableSize(header[10])); err != nil {
+			return 0, err
+		}
+	}
+
+	frames := 0
+	for {
+		block, err := reader.ReadByte()
+		if err != nil {
+			return frames, err
+		}
+		switch block {
+		case gifExtension:
+			if _, err := reader.ReadByte(); err != nil {
+				return frames, err
+			}
+			if err := copyGIFSubBlocks(io.Discard, reader); err != nil {
+				return frames, err
+			}
+		case gifImageDescriptor:
+			frames++
+			if err := check(frames); err != nil {
+				return frames, err
+			}
+			descriptor := make([]byte, 9)
+			if _, err := io.ReadFull(reader, descriptor); err != nil {
+				return frames, err
+			}
+			skip := int64(1) // LZW最小码长
+			if descriptor[8]&0x80 != 0 {
+				skip += colorTableSize(descriptor[8])
+			}
+			if _, err := io.CopyN(io.Discard, reader, skip); err != nil {
+				return frames, err
+			}
+			if err := copyGIFSubBlocks(io.Discard, reader); err != nil {
+				return frames, err
+			}
+		default:
+			return frames, nil
+		}
+	}
+}
+
+// countWebPFrames 统计动画WebP中的ANMF块
+func countWebPFrames(reader *bufio.Reader, check func(int) error) (int, error) {
+	if _, err := reader.Discard(12); err != nil {
+		return 0, err
+	}
+
+	frames := 0
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return frames, err
+		}
+		if string(header[:4]) == "ANMF" {
+			frames++
+			if err := check(frames); err != nil {
+				return frames, err
+			}
+		}
+		// 块数据按偶数字节对齐
+		size := int64(binary.LittleEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(io.Discard, reader, size+size&1); err != nil {
+			return frames, err
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+)
+
+// 超出限制的原因，用于响应中的 reason 字段
+const (
+	ReasonFileTooLarge  = "file_too_large"  // 文件字节数超出限制
+	ReasonTooManyPixels = "too_many_pixels" // 宽×高超出限制
+	ReasonTooManyFrames = "too_many_frames" // 动图帧数超出限制
+	ReasonInvalidHeader = "invalid_header"  // 无法从文件头读取尺寸
+)
+
+// maxHeaderLen 读取尺寸时最多读取的文件头长度，JPEG的EXIF等数据位于尺寸之前
+const maxHeaderLen = 1 << 20
+
+// Limits 图片处理限制，为 0 的项不限制
+type Limits struct {
+	MaxBytes  int64 // 文件最大字节数
+	MaxPixels int64 // 最大像素数（宽×高）
+	MaxFrames int   // 动图最大帧数
+}
+
+// Info 从文件头读取的图片信息
+type Info struct {
+	Width  int
+	Height int
+	Frames int // 帧数，读取完整内容后才准确
+}
+
+// LimitError 图片超出处理限制
+type LimitError struct {
+	Reason string // 超出限制的原因
+	Limit  int64  // 限制值
+	Actual int64  // 实际值，读取完整内容前可能只是已读取的部分
+}
+
+// Error 返回错误描述
+func (e *LimitError) Error() string {
+	switch e.Reason {
+	case ReasonFileTooLarge:
+		return fmt.Sprintf("文件大小超出限制（最大 %d 字节）", e.Limit)
+	case ReasonTooManyPixels:
+		return fmt.Sprintf("图片像素数 %d 超出限制（最大 %d）", e.Actual, e.Limit)
+	case ReasonTooManyFrames:
+		return fmt.Sprintf("动图帧数超出限制（最多 %d 帧）", e.Limit)
+	default:
+		return "无法读取图片尺寸"
+	}
+}
+
+// Guard 在处理前检查图片尺寸，并在读取过程中检查文件大小和动图帧数
+// 超出限制时 Read 返回 *LimitError，不会解码图片像素
+type Guard struct {
+	Format Format // 图片格式
+	Info   Info   // 图片信息
+
+	reader io.Reader
+	limits Limits
+	read   int64
+	frames *frameCounter // 统计帧数，不需要统计时为 nil
+	err    error
+}
+
+// NewGuard 只读取文件头获取图片尺寸，超出像素限制时返回 *LimitError
+// 返回的 Guard 仍可读取完整内容，使用完毕后应调用 Close
+func NewGuard(r io.Reader, format Format, limits Limits) (*Guard, error) {
+	width, height, header, err := readDimensions(r, format)
+	if err != nil {
+		return nil, &LimitError{Reason: ReasonInvalidHeader}
+	}
+	pixels := int64(width) * int64(height)
+	if limits.MaxPixels > 0 && pixels > limits.MaxPixels {
+		return nil, &LimitError{Reason: ReasonTooManyPixels, Limit: limits.MaxPixels, Actual: pixels}
+	}
+
+	guard := &Guard{
+		Format: format,
+		Info:   Info{Width: width, Height: height, Frames: 1},
+		reader: io.MultiReader(bytes.NewReader(header), r),
+		limits: limits,
+	}
+	if countsFrames(format) {
+		guard.frames = newFrameCounter(format, limits.MaxFrames)
+	}
+	return guard, nil
+}
+
+// Read 读取内容，超出文件大小或帧数限制时返回 *LimitError
+func (g *Guard) Read(p []byte) (int, error) {
+	if g.err != nil {
+		return 0, g.err
+	}
+
+	n, err := g.reader.Read(p)
+	g.read += int64(n)
+	if g.limits.MaxBytes > 0 && g.read > g.limits.MaxBytes {
+		g.fail(&LimitError{Reason: ReasonFileTooLarge, Limit: g.limits.MaxBytes, Actual: g.read})
+		return 0, g.err
+	}
+	if g.frames == nil {
+		return n, err
+	}
+
+	if n > 0 {
+		if _, writeErr := g.frames.writer.Write(p[:n]); writeErr != nil {
+			g.fail(g.frames.wait())
+			return 0, g.err
+		}
+	}
+	if err == io.EOF {
+		g.frames.writer.Close()
+		if countErr := g.frames.wait(); countErr != nil {
+			g.fail(countErr)
+			return 0, g.err
+		}
+		g.Info.Frames = g.frames.count
+	}
+	return n, err
+}
+
+// Err 返回读取过程中发现的超限错误，用于在写入失败后区分原因
+func (g *Guard) Err() error {
+	return g.err
+}
+
+// Close 结束后台的帧数统计，不关闭底层 Reader
+func (g *Guard) Close() error {
+	if g.frames != nil {
+		g.frames.writer.Close()
+		g.frames.wait()
+	}
+	return nil
+}
+
+// fail 记录错误并结束帧数统计
+func (g *Guard) fail(err error) {
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	g.err = err
+	if g.frames != nil {
+		g.frames.writer.CloseWithError(err)
+	}
+}
+
+// readDimensions 只读取文件头获取宽高，返回已读取的数据以便重放
+func readDimensions(r io.Reader, format Format) (int, int, []byte, error) {
+	var header bytes.Buffer
+	limited := io.TeeReader(io.LimitReader(r, maxHeaderLen), &header)
+
+	switch format.Name {
+	case HEIC.Name, AVIF.Name:
+		// HEIF的尺寸记录在meta盒的ispe属性中，meta盒通常位于文件开头
+		if _, err := io.Copy(io.Discard, limited); err != nil {
+			return 0, 0, nil, err
+		}
+		width, height, err := heifDimensions(bytes.NewReader(header.Bytes()))
+		return width, height, header.Bytes(), err
+	default:
+		config, _, err := image.DecodeConfig(limited)
+		return config.Width, config.Height, header.Bytes(), err
+	}
+}
+
+// heifDimensions 读取meta/iprp/ipco中最大的ispe属性，网格图片的整体尺寸大于各个分块
+func heifDimensions(src io.ReadSeeker) (int, int, error) {
+	metaOffset, metaSize, err := findBox(src, 0, -1, "meta")
+	if err != nil {
+		return 0, 0, err
+	}
+	// meta为FullBox，跳过版本和标志
+	iprpOffset, iprpSize, err := findBox(src, metaOffset+4, metaOffset+metaSize, "iprp")
+	if err != nil {
+		return 0, 0, err
+	}
+	ipcoOffset, ipcoSize, err := findBox(src, iprpOffset, iprpOffset+iprpSize, "ipco")
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := src.Seek(ipcoOffset, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+	ipco := make([]byte, ipcoSize)
+	if _, err := io.ReadFull(src, ipco); err != nil {
+		return 0, 0, errInvalidHEIF
+	}
+
+	var width, height int
+	for pos := 0; pos+8 <= len(ipco); {
+		size, typ, header := parseBoxHeader(ipco[pos:])
+		if size < header || pos+size > len(ipco) {
+			break
+		}
+		// ispe: 版本和标志4字节，宽高各4字节
+		if typ == "ispe" && size-header >= 12 {
+			data := ipco[pos+header+4 : pos+size]
+			w := int(binary.BigEndian.Uint32(data))
+			h := int(binary.BigEndian.Uint32(data[4:]))
+			if int64(w)*int64(h) > int64(width)*int64(height) {
+				width, height = w, h
+			}
+		}
+		pos += size
+	}
+	if width == 0 || height == 0 {
+		return 0, 0, errInvalidHEIF
+	}
+	return width, height, nil
+}
@@ -0,0 +1,118 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"testing"
+)
+
+// encodePNG 返回指定尺寸的PNG图片
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// animatedGIF 返回指定帧数的动图
+func animatedGIF(t *testing.T, frames int) []byte {
+	t.Helper()
+	anim := &gif.GIF{}
+	palette := color.Palette{color.Black, color.White}
+	for i := 0; i < frames; i++ {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, 4, 4), palette))
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// limitReason 返回错误中的超限原因，不是 *LimitError 时返回空字符串
+func limitReason(err error) string {
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		return limitErr.Reason
+	}
+	return ""
+}
+
+func TestNewGuard(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		format     Format
+		limits     Limits
+		wantReason string
+	}{
+		{"within limits", encodePNG(t, 20, 10), PNG, Limits{MaxPixels: 200}, ""},
+		{"unlimited", encodePNG(t, 20, 10), PNG, Limits{}, ""},
+		{"too many pixels", encodePNG(t, 20, 10), PNG, Limits{MaxPixels: 199}, ReasonTooManyPixels},
+		{"invalid header", []byte("\x89PNG\r\n\x1a\nbroken"), PNG, Limits{}, ReasonInvalidHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard, err := NewGuard(bytes.NewReader(tt.data), tt.format, tt.limits)
+			if got := limitReason(err); got != tt.wantReason {
+				t.Fatalf("NewGuard() error = %v, want reason %q", err, tt.wantReason)
+			}
+			if err != nil {
+				return
+			}
+			defer guard.Close()
+			if guard.Info.Width != 20 || guard.Info.Height != 10 {
+				t.Errorf("Info = %+v", guard.Info)
+			}
+			// 读取的内容包含已读取的文件头
+			data, err := io.ReadAll(guard)
+			if err != nil || !bytes.Equal(data, tt.data) {
+				t.Errorf("ReadAll() = %d bytes, err = %v", len(data), err)
+			}
+		})
+	}
+}
+
+func TestGuardRead(t *testing.T) {
+	animated := animatedGIF(t, 3)
+	tests := []struct {
+		name       string
+		data       []byte
+		format     Format
+		limits     Limits
+		wantReason string
+		wantFrames int
+	}{
+		{"frames within limit", animated, GIF, Limits{MaxFrames: 3}, "", 3},
+		{"too many frames", animated, GIF, Limits{MaxFrames: 2}, ReasonTooManyFrames, 0},
+		{"too many bytes", animated, GIF, Limits{MaxBytes: int64(len(animated)) - 1}, ReasonFileTooLarge, 0},
+		{"exact size", animated, GIF, Limits{MaxBytes: int64(len(animated))}, "", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard, err := NewGuard(bytes.NewReader(tt.data), tt.format, tt.limits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer guard.Close()
+			_, err = io.Copy(io.Discard, guard)
+			if got := limitReason(err); got != tt.wantReason {
+				t.Fatalf("read error = %v, want reason %q", err, tt.wantReason)
+			}
+			if got := limitReason(guard.Err()); got != tt.wantReason {
+				t.Errorf("Err() = %v, want reason %q", guard.Err(), tt.wantReason)
+			}
+			if err == nil && guard.Info.Frames != tt.wantFrames {
+				t.Errorf("Frames = %d, want %d", guard.Info.Frames, tt.wantFrames)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"sort"
+	"strings"
+)
+
+// 可保留的元数据
+const (
+	KeepICC       = "icc"       // ICC色彩配置文件
+	KeepCopyright = "copyright" // EXIF/PNG文本中的版权信息
+)
+
+// 报告中的元数据类型
+const (
+	MetadataEXIF        = "exif"
+	MetadataGPS         = "gps"
+	MetadataXMP         = "xmp"
+	MetadataIPTC        = "iptc"
+	MetadataICC         = "icc"
+	MetadataComment     = "comment"
+	MetadataText        = "text"
+	MetadataTime        = "time"
+	MetadataApplication = "application"
+	MetadataCopyright   = "copyright"
+	MetadataOrientation = "orientation"
+)
+
+// orientedJPEGQuality 按方向旋转后重新编码JPEG的质量
+const orientedJPEGQuality = 92
+
+// MetadataPolicy 元数据保留策略，默认删除所有元数据
+type MetadataPolicy struct {
+	KeepICC       bool  // 保留ICC色彩配置文件
+	KeepCopyright bool  // 保留版权信息
+	MaxPixels     int64 // 按方向旋转时允许解码的最大像素数，0 表示不限制
+}
+
+// MetadataReport 元数据处理结果
+type MetadataReport struct {
+	Removed     []string `json:"removed"`               // 删除的元数据类型
+	Kept        []string `json:"kept,omitempty"`        // 按策略保留的元数据类型
+	Orientation int      `json:"orientation,omitempty"` // 原图的EXIF方向
+	Rotated     bool     `json:"rotated"`               // 是否已按方向旋转像素
+}
+
+// ParseMetadataPolicy 解析以逗号分隔的保留列表，支持 icc、copyright，none 或空表示全部删除
+func ParseMetadataPolicy(spec string) (MetadataPolicy, error) {
+	var policy MetadataPolicy
+	for _, item := range strings.Split(spec, ",") {
+		switch strings.ToLower(strings.TrimSpace(item)) {
+		case "", "none":
+		case KeepICC:
+			policy.KeepICC = true
+		case KeepCopyright:
+			policy.KeepCopyright = true
+		default:
+			return policy, fmt.Errorf("无效的元数据保留项: %s", item)
+		}
+	}
+	return policy, nil
+}
+
+// Sanitize 删除图片中的隐私元数据（EXIF、GPS、XMP、IPTC、注释等），并按EXIF方向旋转像素后写入 dst
+// 只在需要旋转时解码并重新编码图片，否则直接复制图像数据
+func Sanitize(dst io.Writer, src io.ReadSeeker, format Format, policy MetadataPolicy) (*MetadataReport, error) {
+	report := &MetadataReport{Removed: []string{}}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("读取图片失败: %v", err)
+	}
+
+	var err error
+	switch format.Name {
+	case JPEG.Name:
+		err = sanitizeJPEG(dst, src, policy, report)
+	case PNG.Name:
+		err = sanitizePNG(dst, src, policy, report)
+	case WebP.Name:
+		err = sanitizeWebP(dst, src, policy, report)
+	case GIF.Name:
+		err = sanitizeGIF(dst, src, report)
+	case HEIC.Name, AVIF.Name:
+		err = sanitizeHEIF(dst, src, report)
+	default:
+		_, err = io.Copy(dst, src)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(report.Removed)
+	sort.Strings(report.Kept)
+	return report, nil
+}
+
+// remove 记录删除的元数据类型
+func (r *MetadataReport) remove(kind string) {
+	for _, existing := range r.Removed {
+		if existing == kind {
+			return
+		}
+	}
+	r.Removed = append(r.Removed, kind)
+}
+
+// keep 记录保留的元数据类型
+func (r *MetadataReport) keep(kind string) {
+	for _, existing := range r.Kept {
+		if existing == kind {
+			return
+		}
+	}
+	r.Kept = append(r.Kept, kind)
+}
+
+// removeEXIF 记录删除的EXIF，包含GPS时同时记录
+func (r *MetadataReport) removeEXIF(info exifInfo) {
+	r.remove(MetadataEXIF)
+	if info.HasGPS {
+		r.remove(MetadataGPS)
+	}
+}
+
+// reorient 解码图片并按方向旋转后重新编码，失败或像素数超出 policy.MaxPixels 时返回 nil 并保持原图
+// 未旋转时调用方应通过 keptOrientation 保留方向标签，避免图片显示方向错误
+func reorient(src io.ReadSeeker, format Format, orientation int, policy MetadataPolicy, report *MetadataReport) []byte {
+	report.Orientation = orientation
+	if orientation <= 1 {
+		return nil
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil
+	}
+	// 只读取文件头检查尺寸，超出限制时不解码像素
+	width, height, _, err := readDimensions(src, format)
+	if err != nil || policy.MaxPixels > 0 && int64(width)*int64(height) > policy.MaxPixels {
+		return nil
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil
+	}
+	img, _, err := image.Decode(src)
+	if err != nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, Orient(img, orientation), format, orientedJPEGQuality); err != nil {
+		return nil
+	}
+	report.Rotated = true
+	return buf.Bytes()
+}
+
+// keptOrientation 返回需要保留在EXIF中的方向，已旋转像素或无需旋转时为 0
+func (r *MetadataReport) keptOrientation() int {
+	if r.Rotated || r.Orientation <= 1 {
+		return 0
+	}
+	return r.Orientation
+}
+
+// keptEXIF 生成需要保留的EXIF数据，包含策略保留的版权和未能旋转时的方向，都不需要时返回 nil
+func keptEXIF(exif exifInfo, policy MetadataPolicy, report *MetadataReport) []byte {
+	copyright := ""
+	if policy.KeepCopyright && exif.Copyright != "" {
+		copyright = exif.Copyright
+		report.keep(MetadataCopyright)
+	}
+	orientation := report.keptOrientation()
+	if orientation > 0 {
+		report.keep(MetadataOrientation)
+	}
+	if copyright == "" && orientation == 0 {
+		return nil
+	}
+	return buildEXIF(orientation, copyright)
+}
+
+// Merge 合并另一次处理的结果，用于输入和输出分别清理的场景
+func (r *MetadataReport) Merge(other *MetadataReport) {
+	if other == nil {
+		return
+	}
+	for _, kind := range other.Removed {
+		r.remove(kind)
+	}
+	for _, kind := range other.Kept {
+		r.keep(kind)
+	}
+	if r.Orientation == 0 {
+		r.Orientation = other.Orientation
+	}
+	r.Rotated = r.Rotated || other.Rotated
+	sort.Strings(r.Removed)
+	sort.Strings(r.Kept)
+}
@@ -0,0 +1,149 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// GIF块标识
+const (
+	gifExtension       = 0x21
+	gifImageDescriptor = 0x2C
+	gifTrailer         = 0x3B
+	gifCommentLabel    = 0xFE
+	gifAppLabel        = 0xFF
+)
+
+// errInvalidGIF GIF结构无效
+var errInvalidGIF = fmt.Errorf("无效的GIF文件")
+
+// gifAnimationApps 控制动画循环的应用扩展，需要保留
+var gifAnimationApps = []string{"NETSCAPE2.0", "ANIMEXTS1.0"}
+
+// sanitizeGIF 删除注释扩展和XMP等应用扩展，保留动画循环扩展
+func sanitizeGIF(dst io.Writer, src io.Reader, report *MetadataReport) error {
+	reader := bufio.NewReader(src)
+	output := bufio.NewWriter(dst)
+
+	// 文件头、逻辑屏幕描述符和全局颜色表
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return errInvalidGIF
+	}
+	output.Write(header)
+	if header[10]&0x80 != 0 {
+		if _, err := io.CopyN(output, reader, colorTableSize(header[10])); err != nil {
+			return errInvalidGIF
+		}
+	}
+
+	for {
+		block, err := reader.ReadByte()
+		if err != nil {
+			return errInvalidGIF
+		}
+		switch block {
+		case gifExtension:
+			label, err := reader.ReadByte()
+			if err != nil {
+				return errInvalidGIF
+			}
+			data, err := readGIFSubBlocks(reader)
+			if err != nil {
+				return err
+			}
+			if kind := gifExtensionKind(label, data); kind != "" {
+				report.remove(kind)
+				continue
+			}
+			output.Write([]byte{gifExtension, label})
+			output.Write(data)
+		case gifImageDescriptor:
+			descriptor := make([]byte, 9)
+			if _, err := io.ReadFull(reader, descriptor); err != nil {
+				return errInvalidGIF
+			}
+			output.WriteByte(gifImageDescriptor)
+			output.Write(descriptor)
+			if descriptor[8]&0x80 != 0 {
+				if _, err := io.CopyN(output, reader, colorTableSize(descriptor[8])); err != nil {
+					return errInvalidGIF
+				}
+			}
+			// LZW最小码长，之后是图像数据子块
+			if _, err := io.CopyN(output, reader, 1); err != nil {
+				return errInvalidGIF
+			}
+			if err := copyGIFSubBlocks(output, reader); err != nil {
+				return err
+			}
+		case gifTrailer:
+			output.WriteByte(gifTrailer)
+			if _, err := reader.Peek(1); err == nil {
+				report.remove(MetadataApplication)
+			}
+			return output.Flush()
+		default:
+			return errInvalidGIF
+		}
+	}
+}
+
+// gifExtensionKind 返回扩展中元数据的类型，需要保留的扩展返回空字符串
+func gifExtensionKind(label byte, data []byte) string {
+	switch label {
+	case gifCommentLabel:
+		return MetadataComment
+	case gifAppLabel:
+		// 第一个子块为11字节的应用标识
+		if len(data) < 12 {
+			return MetadataApplication
+		}
+		identifier := string(data[1:12])
+		for _, app := range gifAnimationApps {
+			if identifier == app {
+				return ""
+			}
+		}
+		if identifier == "XMP DataXMP" {
+			return MetadataXMP
+		}
+		return MetadataApplication
+	}
+	return ""
+}
+
+// colorTableSize 根据标志字节计算颜色表字节数
+func colorTableSize(flags byte) int64 {
+	return 3 << (int64(flags&0x07) + 1)
+}
+
+// readGIFSubBlocks 读取子块序列（含长度字节和结束符）
+func readGIFSubBlocks(reader *bufio.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := copyGIFSubBlocks(&buf, reader); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// copyGIFSubBlocks 复制子块序列直到长度为0的结束符
+func copyGIFSubBlocks(dst io.Writer, reader *bufio.Reader) error {
+	for {
+		size, err := reader.ReadByte()
+		if err != nil {
+			return errInvalidGIF
+		}
+		if _, err := dst.Write([]byte{size}); err != nil {
+			return err
+		}
+		if size == 0 {
+			return nil
+		}
+		if _, err := io.CopyN(dst, reader, int64(size)); err != nil {
+			return errInvalidGIF
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// maxHEIFMetaSize meta盒大小上限，正常文件只有几十KB
+const maxHEIFMetaSize = 16 << 20
+
+// errInvalidHEIF HEIF结构无效
+var errInvalidHEIF = fmt.Errorf("无效的HEIF文件")
+
+// heifRange 需要清零的字节范围
+type heifRange struct {
+	Offset int64
+	Length int64
+	Kind   string // 元数据类型
+}
+
+// sanitizeHEIF 将HEIC/AVIF中Exif项和XMP项的数据清零
+// 不改变文件结构和偏移，无需重新编码；HEIF的方向由irot/imir属性描述，不依赖EXIF
+func sanitizeHEIF(dst io.Writer, src io.ReadSeeker, report *MetadataReport) error {
+	ranges, err := heifMetadataRanges(src)
+	if err != nil {
+		return err
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Offset < ranges[j].Offset })
+
+	for _, r := range ranges {
+		if r.Kind == MetadataEXIF {
+			report.removeEXIF(readHEIFExif(src, r))
+		} else {
+			report.remove(r.Kind)
+		}
+	}
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	position := int64(0)
+	for _, r := range ranges {
+		if r.Offset < position {
+			continue
+		}
+		if _, err := io.CopyN(dst, src, r.Offset-position); err != nil {
+			return errInvalidHEIF
+		}
+		if _, err := io.CopyN(dst, zeroReader{}, r.Length); err != nil {
+			return err
+		}
+		if _, err := src.Seek(r.Length, io.SeekCurrent); err != nil {
+			return err
+		}
+		position = r.Offset + r.Length
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// heifMetadataRanges 解析meta盒，返回Exif项和XMP项数据所在的文件范围
+func heifMetadataRanges(src io.ReadSeeker) ([]heifRange, error) {
+	metaOffset, metaSize, err := findBox(src, 0, -1, "meta")
+	if err != nil {
+		return nil, err
+	}
+	if metaSize > maxHEIFMetaSize || metaSize < 4 {
+		return nil, errInvalidHEIF
+	}
+	meta := make([]byte, metaSize)
+	if _, err := src.Seek(metaOffset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(src, meta); err != nil {
+		return nil, errInvalidHEIF
+	}
+
+	// meta为FullBox，跳过版本和标志
+	var iinf, iloc []byte
+	idatOffset := int64(-1)
+	for pos := 4; pos+8 <= len(meta); {
+		size, typ, header := parseBoxHeader(meta[pos:])
+		if size < header || pos+size > len(meta) {
+			break
+		}
+		switch typ {
+		case "iinf":
+			iinf = meta[pos+header : pos+size]
+		case "iloc":
+			iloc = meta[pos+header : pos+size]
+		case "idat":
+			idatOffset = metaOffset + int64(pos+header)
+		}
+		pos += size
+	}
+
+	items := heifMetadataItems(iinf)
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return heifItemRanges(iloc, items, idatOffset), nil
+}
+
+// heifMetadataItems 从iinf中找出Exif项和XMP项，返回项ID到类型的映射
+func heifMetadataItems(iinf []byte) map[uint32]string {
+	items := map[uint32]string{}
+	if len(iinf) < 6 {
+		return items
+	}
+	pos := 6
+	if iinf[0] != 0 {
+		pos = 8
+	}
+	for pos+8 <= len(iinf) {
+		size, typ, header := parseBoxHeader(iinf[pos:])
+		if size < header || pos+size > len(iinf) {
+			break
+		}
+		box := iinf[pos+header : pos+size]
+		pos += size
+		if typ != "infe" || len(box) < 4 || box[0] < 2 {
+			continue
+		}
+
+		// infe版本2使用16位项ID，版本3使用32位
+		var id uint32
+		rest := box[4:]
+		if box[0] == 2 && len(rest) >= 8 {
+			id, rest = uint32(binary.BigEndian.Uint16(rest)), rest[2:]
+		} else if box[0] == 3 && len(rest) >= 10 {
+			id, rest = binary.BigEndian.Uint32(rest), rest[4:]
+		} else {
+			continue
+		}
+		itemType := string(rest[2:6])
+		switch itemType {
+		case "Exif":
+			items[id] = MetadataEXIF
+		case "mime":
+			// item_name 和 content_type 均以 \0 结尾
+			fields := bytes.SplitN(rest[6:], []byte{0}, 3)
+			if len(fields) >= 2 && bytes.Contains(bytes.ToLower(fields[1]), []byte("rdf+xml")) {
+				items[id] = MetadataXMP
+			}
+		}
+	}
+	return items
+}
+
+// heifItemRanges 根据iloc计算指定项的数据范围，只处理文件偏移和idat两种构造方式
+func heifItemRanges(iloc []byte, items map[uint32]string, idatOffset int64) []heifRange {
+	if len(iloc) < 8 {
+		return nil
+	}
+	version := iloc[0]
+	offsetSize := int(iloc[4] >> 4)
+	lengthSize := int(iloc[4] & 0x0F)
+	baseOffsetSize := int(iloc[5] >> 4)
+	indexSize := 0
+	if version == 1 || version == 2 {
+		indexSize = int(iloc[5] & 0x0F)
+	}
+
+	reader := &boxReader{data: iloc[6:]}
+	var itemCount uint64
+	if version == 2 {
+		itemCount = reader.uint(4)
+	} else {
+		itemCount = reader.uint(2)
+	}
+
+	var ranges []heifRange
+	for i := uint64(0); i < itemCount && reader.err == nil; i++ {
+		var id uint64
+		if version == 2 {
+			id = reader.uint(4)
+		} else {
+			id = reader.uint(2)
+		}
+		method := uint64(0)
+		if version == 1 || version == 2 {
+			method = reader.uint(2) & 0x0F
+		}
+		reader.uint(2) // data_reference_index
+		base := reader.uint(baseOffsetSize)
+		extentCount := reader.uint(2)
+		for j := uint64(0); j < extentCount && reader.err == nil; j++ {
+			reader.uint(indexSize)
+			offset := reader.uint(offsetSize)
+			length := reader.uint(lengthSize)
+
+			kind, ok := items[uint32(id)]
+			if !ok || length == 0 {
+				continue
+			}
+			switch method {
+			case 0:
+				ranges = append(ranges, heifRange{Offset: int64(base + offset), Length: int64(length), Kind: kind})
+			case 1:
+				if idatOffset >= 0 {
+					ranges = append(ranges, heifRange{Offset: idatOffset + int64(base+offset), Length: int64(length), Kind: kind})
+				}
+			}
+		}
+	}
+	return ranges
+}
+
+// readHEIFExif 读取Exif项内容，前4字节为TIFF头的偏移
+func readHEIFExif(src io.ReadSeeker, r heifRange) exifInfo {
+	if r.Length > maxHEIFMetaSize {
+		return exifInfo{}
+	}
+	data := make([]byte, r.Length)
+	if _, err := src.Seek(r.Offset, io.SeekStart); err != nil {
+		return exifInfo{}
+	}
+	if _, err := io.ReadFull(src, data); err != nil || len(data) < 4 {
+		return exifInfo{}
+	}
+	offset := int(binary.BigEndian.Uint32(data)) + 4
+	if offset > len(data) {
+		return exifInfo{}
+	}
+	return parseEXIF(data[offset:])
+}
+
+// findBox 在 [start, end) 范围内查找指定类型的顶层盒，返回内容的偏移和长度，end 为 -1 表示到文件末尾
+func findBox(src io.ReadSeeker, start, end int64, boxType string) (int64, int64, error) {
+	header := make([]byte, 16)
+	for position := start; end < 0 || position+8 <= end; {
+		if _, err := src.Seek(position, io.SeekStart); err != nil {
+			return 0, 0, err
+		}
+		n, _ := io.ReadFull(src, header)
+		if n < 8 {
+			break
+		}
+		size, typ, headerSize := parseBoxHeader(header[:n])
+		if size == 0 {
+			// 盒延伸到文件末尾
+			fileEnd, err := src.Seek(0, io.SeekEnd)
+			if err != nil {
+				return 0, 0, err
+			}
+			size = int(fileEnd - position)
+		}
+		if size < headerSize {
+			break
+		}
+		if typ == boxType {
+			return position + int64(headerSize), int64(size - headerSize), nil
+		}
+		position += int64(size)
+	}
+	return 0, 0, errInvalidHEIF
+}
+
+// parseBoxHeader 解析盒头，返回盒大小、类型和头长度；大小为0表示延伸到末尾
+func parseBoxHeader(data []byte) (int, string, int) {
+	if len(data) < 8 {
+		return 0, "", 8
+	}
+	size := int(binary.BigEndian.Uint32(data))
+	typ := string(data[4:8])
+	if size == 1 && len(data) >= 16 {
+		return int(binary.BigEndian.Uint64(data[8:])), typ, 16
+	}
+	return size, typ, 8
+}
+
+// boxReader 按字节数读取大端整数
+type boxReader struct {
+	data []byte
+	err  error
+}
+
+// uint 读取 n 字节的大端整数，n 为 0 时返回 0
+func (r *boxReader) uint(n int) uint64 {
+	if r.err != nil || n == 0 {
+		return 0
+	}
+	if len(r.data) < n {
+		r.err = errInvalidHEIF
+		return 0
+	}
+	var v uint64
+	for _, b := range r.data[:n] {
+		v = v<<8 | uint64(b)
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+// zeroReader 无限输出 0 的 Reader
+type zeroReader struct{}
+
+// Read 填充 0
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,246 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// JPEG标记
+const (
+	jpegSOI  = 0xD8
+	jpegEOI  = 0xD9
+	jpegSOS  = 0xDA
+	jpegAPP0 = 0xE0
+	jpegAPP1 = 0xE1
+	jpegAPP2 = 0xE2
+	jpegAPPD = 0xED
+	jpegAPPE = 0xEE
+	jpegAPPF = 0xEF
+	jpegCOM  = 0xFE
+)
+
+// JPEG段的标识
+var (
+	jfifHeader     = []byte("JFIF\x00")
+	jfxxHeader     = []byte("JFXX\x00")
+	xmpHeader      = []byte("http://ns.adobe.com/xap/1.0/")
+	xmpExtHeader   = []byte("http://ns.adobe.com/xmp/extension/")
+	iccHeader      = []byte("ICC_PROFILE\x00")
+	adobeHeader    = []byte("Adobe")
+	jpegSOIMarker  = []byte{0xFF, jpegSOI}
+	jpegEOIMarker  = []byte{0xFF, jpegEOI}
+	errInvalidJPEG = fmt.Errorf("无效的JPEG文件")
+)
+
+// jpegSegment JPEG文件头中的段
+type jpegSegment struct {
+	Marker byte
+	Data   []byte // 段内容，不含标记和长度
+}
+
+// sanitizeJPEG 删除APP段和注释中的元数据，只保留JFIF、Adobe以及策略允许的ICC
+// 图像数据直接复制，EOI之后附加的数据（如多图格式的附图）一并删除
+func sanitizeJPEG(dst io.Writer, src io.ReadSeeker, policy MetadataPolicy, report *MetadataReport) error {
+	reader := bufio.NewReader(src)
+	segments, err := readJPEGHeader(reader)
+	if err != nil {
+		return err
+	}
+
+	var exif exifInfo
+	var jfif *jpegSegment
+	var icc, kept []jpegSegment
+	for i, segment := range segments {
+		switch kind := jpegSegmentKind(segment); kind {
+		case "":
+			kept = append(kept, segment)
+		case "jfif":
+			if jfif == nil {
+				jfif = &segments[i]
+			}
+		case MetadataICC:
+			if policy.KeepICC {
+				icc = append(icc, segment)
+				report.keep(MetadataICC)
+			} else {
+				report.remove(MetadataICC)
+			}
+		case MetadataEXIF:
+			info := parseEXIF(segment.Data)
+			if exif.Orientation == 0 {
+				exif.Orientation = info.Orientation
+			}
+			if exif.Copyright == "" {
+				exif.Copyright = info.Copyright
+			}
+			report.removeEXIF(info)
+		default:
+			report.remove(kind)
+		}
+	}
+
+	// 需要旋转时使用重新编码的图片，其中不包含任何元数据
+	if data := reorient(src, JPEG, exif.Orientation, policy, report); data != nil {
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		// 检查原图EOI之后是否有附加数据，用于报告
+		original := bufio.NewReader(src)
+		if _, err := readJPEGHeader(original); err != nil {
+			return err
+		}
+		if trailing, _ := copyJPEGScan(io.Discard, original); trailing {
+			report.remove(MetadataApplication)
+		}
+
+		reader = bufio.NewReader(bytes.NewReader(data))
+		if kept, err = readJPEGHeader(reader); err != nil {
+			return err
+		}
+	}
+
+	// 依次写入 SOI、JFIF、保留的元数据和其余段
+	output := bufio.NewWriter(dst)
+	output.Write(jpegSOIMarker)
+	if jfif != nil {
+		writeJPEGSegment(output, *jfif)
+	}
+	if data := keptEXIF(exif, policy, report); data != nil {
+		writeJPEGSegment(output, jpegSegment{Marker: jpegAPP1, Data: append(append([]byte{}, exifHeader...), data...)})
+	}
+	for _, segment := range icc {
+		writeJPEGSegment(output, segment)
+	}
+	for _, segment := range kept {
+		writeJPEGSegment(output, segment)
+	}
+
+	trailing, err := copyJPEGScan(output, reader)
+	if err != nil {
+		return err
+	}
+	if trailing {
+		report.remove(MetadataApplication)
+	}
+	return output.Flush()
+}
+
+// jpegSegmentKind 返回段中元数据的类型，需要原样保留的段返回空字符串
+func jpegSegmentKind(segment jpegSegment) string {
+	switch {
+	case segment.Marker == jpegAPP0:
+		if bytes.HasPrefix(segment.Data, jfifHeader) || bytes.HasPrefix(segment.Data, jfxxHeader) {
+			return "jfif"
+		}
+		return MetadataApplication
+	case segment.Marker == jpegAPP1:
+		if bytes.HasPrefix(segment.Data, exifHeader) {
+			return MetadataEXIF
+		}
+		if bytes.HasPrefix(segment.Data, xmpHeader) || bytes.HasPrefix(segment.Data, xmpExtHeader) {
+			return MetadataXMP
+		}
+		return MetadataApplication
+	case segment.Marker == jpegAPP2 && bytes.HasPrefix(segment.Data, iccHeader):
+		return MetadataICC
+	case segment.Marker == jpegAPPD:
+		return MetadataIPTC
+	case segment.Marker == jpegAPPE && bytes.HasPrefix(segment.Data, adobeHeader):
+		// Adobe段记录颜色转换方式，删除后CMYK等图片颜色会出错
+		return ""
+	case segment.Marker >= jpegAPP0 && segment.Marker <= jpegAPPF:
+		return MetadataApplication
+	case segment.Marker == jpegCOM:
+		return MetadataComment
+	}
+	return ""
+}
+
+// readJPEGHeader 读取SOI之后、SOS之前的所有段，返回后 reader 位于SOS标记处
+func readJPEGHeader(reader *bufio.Reader) ([]jpegSegment, error) {
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(reader, soi); err != nil || !bytes.Equal(soi, jpegSOIMarker) {
+		return nil, errInvalidJPEG
+	}
+
+	var segments []jpegSegment
+	for {
+		// 标记前可能有多个填充的 0xFF
+		marker, err := reader.Peek(2)
+		if err != nil || marker[0] != 0xFF {
+			return nil, errInvalidJPEG
+		}
+		if marker[1] == 0xFF {
+			reader.Discard(1)
+			continue
+		}
+		if marker[1] == jpegSOS || marker[1] == jpegEOI {
+			return segments, nil
+		}
+		reader.Discard(2)
+
+		// 独立标记没有长度字段
+		if marker[1] == 0x01 || (marker[1] >= 0xD0 && marker[1] <= 0xD7) {
+			continue
+		}
+		code := marker[1]
+		var length uint16
+		if err := binary.Read(reader, binary.BigEndian, &length); err != nil || length < 2 {
+			return nil, errInvalidJPEG
+		}
+		data := make([]byte, length-2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, errInvalidJPEG
+		}
+		segments = append(segments, jpegSegment{Marker: code, Data: data})
+	}
+}
+
+// writeJPEGSegment 写入一个段
+func writeJPEGSegment(w io.Writer, segment jpegSegment) {
+	header := []byte{0xFF, segment.Marker, 0, 0}
+	binary.BigEndian.PutUint16(header[2:], uint16(len(segment.Data)+2))
+	w.Write(header)
+	w.Write(segment.Data)
+}
+
+// copyJPEGScan 从SOS开始复制到EOI为止，返回EOI之后是否还有数据
+// 压缩数据中的 0xFF 会被填充为 0xFF00，因此 0xFFD9 只会出现在文件结尾
+func copyJPEGScan(dst io.Writer, reader *bufio.Reader) (bool, error) {
+	for {
+		chunk, err := reader.ReadSlice(0xFF)
+		if err == bufio.ErrBufferFull {
+			if _, err := dst.Write(chunk); err != nil {
+				return false, err
+			}
+			continue
+		}
+		if err == io.EOF {
+			// 缺少EOI的文件原样保留已有数据
+			_, err = dst.Write(chunk)
+			return false, err
+		}
+		if err != nil {
+			return false, fmt.Errorf("读取图片失败: %v", err)
+		}
+		if _, err := dst.Write(chunk); err != nil {
+			return false, err
+		}
+
+		next, err := reader.Peek(1)
+		if err != nil {
+			return false, nil
+		}
+		if next[0] == jpegEOI {
+			reader.Discard(1)
+			if _, err := dst.Write(jpegEOIMarker[1:]); err != nil {
+				return false, err
+			}
+			_, err := reader.Peek(1)
+			return err == nil, nil
+		}
+	}
+}
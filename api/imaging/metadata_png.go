@@ -0,0 +1,181 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// pngSignature PNG文件签名
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// errInvalidPNG PNG结构无效
+var errInvalidPNG = fmt.Errorf("无效的PNG文件")
+
+// pngCopyrightKeyword PNG文本块中版权信息的关键字
+const pngCopyrightKeyword = "Copyright"
+
+// pngXMPKeyword 保存XMP的iTXt关键字
+const pngXMPKeyword = "XML:com.adobe.xmp"
+
+// pngChunk PNG数据块，图像数据块不读入内存
+type pngChunk struct {
+	Type   string
+	Data   []byte // 元数据块内容，IDAT等块为空
+	Offset int64  // 块在文件中的起始位置（长度字段处）
+	Length uint32 // 数据长度
+}
+
+// sanitizePNG 删除eXIf、文本块和时间块，按策略保留iCCP和版权文本
+func sanitizePNG(dst io.Writer, src io.ReadSeeker, policy MetadataPolicy, report *MetadataReport) error {
+	chunks, err := readPNGChunks(src)
+	if err != nil {
+		return err
+	}
+
+	var exif exifInfo
+	var copyright string
+	var iccp *pngChunk
+	var kept []pngChunk
+	for i, chunk := range chunks {
+		switch chunk.Type {
+		case "eXIf":
+			exif = parseEXIF(chunk.Data)
+			if copyright == "" {
+				copyright = exif.Copyright
+			}
+			report.removeEXIF(exif)
+		case "tEXt", "zTXt", "iTXt":
+			keyword, _, _ := bytes.Cut(chunk.Data, []byte{0})
+			switch string(keyword) {
+			case pngXMPKeyword:
+				report.remove(MetadataXMP)
+			case pngCopyrightKeyword:
+				if chunk.Type == "tEXt" {
+					copyright = string(chunk.Data[len(keyword)+1:])
+				}
+				report.remove(MetadataText)
+			default:
+				report.remove(MetadataText)
+			}
+		case "tIME":
+			report.remove(MetadataTime)
+		case "iCCP":
+			if policy.KeepICC {
+				iccp = &chunks[i]
+				report.keep(MetadataICC)
+			} else {
+				report.remove(MetadataICC)
+			}
+		default:
+			kept = append(kept, chunk)
+		}
+	}
+
+	// 需要旋转时使用重新编码的图片
+	if data := reorient(src, PNG, exif.Orientation, policy, report); data != nil {
+		src = bytes.NewReader(data)
+		if kept, err = readPNGChunks(src); err != nil {
+			return err
+		}
+	}
+
+	output := bufio.NewWriter(dst)
+	output.Write(pngSignature)
+	for i, chunk := range kept {
+		if err := copyPNGChunk(output, src, chunk); err != nil {
+			return err
+		}
+		// iCCP和文本块放在IHDR之后、图像数据之前
+		if i == 0 {
+			if iccp != nil {
+				writePNGChunk(output, iccp.Type, iccp.Data)
+			}
+			if policy.KeepCopyright && copyright != "" {
+				writePNGChunk(output, "tEXt", append([]byte(pngCopyrightKeyword+"\x00"), copyright...))
+				report.keep(MetadataCopyright)
+			}
+			// 未能旋转时保留方向，版权已写入文本块
+			if data := keptEXIF(exifInfo{}, policy, report); data != nil {
+				writePNGChunk(output, "eXIf", data)
+			}
+		}
+	}
+	return output.Flush()
+}
+
+// readPNGChunks 读取所有数据块的位置，元数据块同时读取内容
+func readPNGChunks(src io.ReadSeeker) ([]pngChunk, error) {
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(src, signature); err != nil || !bytes.Equal(signature, pngSignature) {
+		return nil, errInvalidPNG
+	}
+
+	var chunks []pngChunk
+	offset := int64(len(pngSignature))
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(src, header); err != nil {
+			if err == io.EOF && len(chunks) > 0 {
+				return chunks, nil
+			}
+			return nil, errInvalidPNG
+		}
+		chunk := pngChunk{
+			Type:   string(header[4:8]),
+			Offset: offset,
+			Length: binary.BigEndian.Uint32(header[0:4]),
+		}
+		switch chunk.Type {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "iCCP":
+			chunk.Data = make([]byte, chunk.Length)
+			if _, err := io.ReadFull(src, chunk.Data); err != nil {
+				return nil, errInvalidPNG
+			}
+			if _, err := src.Seek(4, io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		default:
+			if _, err := src.Seek(int64(chunk.Length)+4, io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		}
+		chunks = append(chunks, chunk)
+		offset += 12 + int64(chunk.Length)
+		if chunk.Type == "IEND" {
+			// IEND之后的数据不再保留
+			return chunks, nil
+		}
+	}
+}
+
+// copyPNGChunk 从原文件复制整个数据块（含长度、类型和CRC）
+func copyPNGChunk(dst io.Writer, src io.ReadSeeker, chunk pngChunk) error {
+	if _, err := src.Seek(chunk.Offset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(dst, src, 12+int64(chunk.Length)); err != nil {
+		return errInvalidPNG
+	}
+	return nil
+}
+
+// writePNGChunk 写入一个数据块并计算CRC
+func writePNGChunk(w io.Writer, typ string, data []byte) {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(data)))
+	copy(header[4:], typ)
+	w.Write(header)
+	w.Write(data)
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	binary.Write(w, binary.BigEndian, crc.Sum32())
+}
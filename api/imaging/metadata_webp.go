@@ -0,0 +1,200 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// VP8X 标志位
+const (
+	vp8xFlagICC   = 0x20
+	vp8xFlagAlpha = 0x10
+	vp8xFlagEXIF  = 0x08
+	vp8xFlagXMP   = 0x04
+)
+
+// errInvalidWebP WebP结构无效
+var errInvalidWebP = fmt.Errorf("无效的WebP文件")
+
+// webpChunk WebP数据块，图像数据块不读入内存
+type webpChunk struct {
+	FourCC string
+	Data   []byte // 元数据块和VP8X的内容
+	Offset int64  // 数据在文件中的起始位置（不含块头）
+	Size   uint32 // 数据长度（不含填充）
+}
+
+// sanitizeWebP 删除EXIF和XMP块，按策略保留ICCP和版权信息，并更新VP8X标志和RIFF大小
+func sanitizeWebP(dst io.Writer, src io.ReadSeeker, policy MetadataPolicy, report *MetadataReport) error {
+	chunks, err := readWebPChunks(src)
+	if err != nil {
+		return err
+	}
+
+	var exif exifInfo
+	var vp8x *webpChunk
+	var icc *webpChunk
+	var kept []webpChunk
+	for i, chunk := range chunks {
+		switch chunk.FourCC {
+		case "VP8X":
+			vp8x = &chunks[i]
+		case "EXIF":
+			exif = parseEXIF(chunk.Data)
+			report.removeEXIF(exif)
+		case "XMP ":
+			report.remove(MetadataXMP)
+		case "ICCP":
+			if policy.KeepICC {
+				icc = &chunks[i]
+				report.keep(MetadataICC)
+			} else {
+				report.remove(MetadataICC)
+			}
+		default:
+			kept = append(kept, chunk)
+		}
+	}
+
+	// 需要旋转时使用重新编码的无损WebP，其中不包含VP8X
+	if data := reorient(src, WebP, exif.Orientation, policy, report); data != nil {
+		src = bytes.NewReader(data)
+		if kept, err = readWebPChunks(src); err != nil {
+			return err
+		}
+		if vp8x, err = newVP8X(src, kept); err != nil {
+			return err
+		}
+	}
+
+	var extra []webpChunk
+	if vp8x != nil {
+		flags := vp8x.Data[0] &^ (vp8xFlagICC | vp8xFlagEXIF | vp8xFlagXMP)
+		if icc != nil {
+			flags |= vp8xFlagICC
+		}
+		if data := keptEXIF(exif, policy, report); data != nil {
+			extra = append(extra, webpChunk{FourCC: "EXIF", Data: data, Size: uint32(len(data))})
+			flags |= vp8xFlagEXIF
+		}
+		header := append([]byte{flags}, vp8x.Data[1:]...)
+		chunks = []webpChunk{{FourCC: "VP8X", Data: header, Size: uint32(len(header))}}
+		if icc != nil {
+			chunks = append(chunks, *icc)
+		}
+	} else {
+		// 简单格式不包含元数据
+		chunks = nil
+	}
+	chunks = append(append(chunks, kept...), extra...)
+
+	size := uint32(4)
+	for _, chunk := range chunks {
+		size += 8 + chunk.Size + chunk.Size%2
+	}
+	output := bufio.NewWriter(dst)
+	output.WriteString("RIFF")
+	binary.Write(output, binary.LittleEndian, size)
+	output.WriteString("WEBP")
+	for _, chunk := range chunks {
+		output.WriteString(chunk.FourCC)
+		binary.Write(output, binary.LittleEndian, chunk.Size)
+		if chunk.Data != nil {
+			output.Write(chunk.Data)
+		} else {
+			if _, err := src.Seek(chunk.Offset, io.SeekStart); err != nil {
+				return err
+			}
+			if _, err := io.CopyN(output, src, int64(chunk.Size)); err != nil {
+				return errInvalidWebP
+			}
+		}
+		if chunk.Size%2 == 1 {
+			output.WriteByte(0)
+		}
+	}
+	return output.Flush()
+}
+
+// readWebPChunks 读取所有数据块的位置，元数据块和VP8X同时读取内容
+func readWebPChunks(src io.ReadSeeker) ([]webpChunk, error) {
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(src, header); err != nil || string(header[0:4]) != "RIFF" || string(header[8:12]) != "WEBP" {
+		return nil, errInvalidWebP
+	}
+	end := int64(8) + int64(binary.LittleEndian.Uint32(header[4:8]))
+
+	var chunks []webpChunk
+	offset := int64(12)
+	for offset+8 <= end {
+		if _, err := io.ReadFull(src, header[:8]); err != nil {
+			break
+		}
+		chunk := webpChunk{
+			FourCC: string(header[0:4]),
+			Size:   binary.LittleEndian.Uint32(header[4:8]),
+			Offset: offset + 8,
+		}
+		padded := int64(chunk.Size) + int64(chunk.Size%2)
+		switch chunk.FourCC {
+		case "VP8X", "EXIF", "XMP ", "ICCP":
+			chunk.Data = make([]byte, chunk.Size)
+			if _, err := io.ReadFull(src, chunk.Data); err != nil {
+				return nil, errInvalidWebP
+			}
+			if _, err := src.Seek(padded-int64(chunk.Size), io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		default:
+			if _, err := src.Seek(padded, io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		}
+		if chunk.FourCC == "VP8X" && len(chunk.Data) < 10 {
+			return nil, errInvalidWebP
+		}
+		chunks = append(chunks, chunk)
+		offset += 8 + padded
+	}
+	if len(chunks) == 0 {
+		return nil, errInvalidWebP
+	}
+	return chunks, nil
+}
+
+// newVP8X 为简单格式（单个VP8L块）生成VP8X块，用于添加ICC或EXIF
+func newVP8X(src io.ReadSeeker, chunks []webpChunk) (*webpChunk, error) {
+	if len(chunks) != 1 || chunks[0].FourCC != "VP8L" || chunks[0].Size < 5 {
+		return nil, errInvalidWebP
+	}
+	header := make([]byte, 5)
+	if _, err := src.Seek(chunks[0].Offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(src, header); err != nil || header[0] != 0x2F {
+		return nil, errInvalidWebP
+	}
+
+	// VP8L头：14位宽度-1、14位高度-1、1位透明通道
+	bits := binary.LittleEndian.Uint32(header[1:5])
+	width := bits&0x3FFF + 1
+	height := (bits>>14)&0x3FFF + 1
+	data := make([]byte, 10)
+	if bits>>28&1 == 1 {
+		data[0] = vp8xFlagAlpha
+	}
+	putUint24(data[4:7], width-1)
+	putUint24(data[7:10], height-1)
+	return &webpChunk{FourCC: "VP8X", Data: data, Size: 10}, nil
+}
+
+// putUint24 以小端序写入24位整数
+func putUint24(b []byte, v uint32) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
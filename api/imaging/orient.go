@@ -0,0 +1,47 @@
+package imaging
+
+import (
+	"image"
+)
+
+// Orient 按EXIF方向（1-8）调整像素，使图片以正确方向显示
+func Orient(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2, 3, 4, 5, 6, 7, 8:
+	default:
+		return img
+	}
+
+	src := toNRGBA(img)
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = width-1-x, y
+			case 3: // 旋转180度
+				dx, dy = width-1-x, height-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, height-1-y
+			case 5: // 沿主对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转90度
+				dx, dy = height-1-y, x
+			case 7: // 沿副对角线翻转
+				dx, dy = height-1-y, width-1-x
+			case 8: // 顺时针旋转270度
+				dx, dy = y, width-1-x
+			}
+			d, s := dst.PixOffset(dx, dy), src.PixOffset(x, y)
+			copy(dst.Pix[d:d+4], src.Pix[s:s+4])
+		}
+	}
+	return dst
+}
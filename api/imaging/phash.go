@@ -0,0 +1,98 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+// 感知哈希参数：缩小到32×32灰度图做DCT，取左上角8×8低频系数
+const (
+	phashSize    = 32
+	phashLowFreq = 8
+)
+
+// phashCosines DCT使用的余弦表，phashCosines[u][x] = cos((2x+1)uπ/2N)
+var phashCosines = func() [phashSize][phashSize]float64 {
+	var table [phashSize][phashSize]float64
+	for u := 0; u < phashSize; u++ {
+		for x := 0; x < phashSize; x++ {
+			table[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * phashSize))
+		}
+	}
+	return table
+}()
+
+// PHash 计算图片的64位感知哈希
+// 缩放、重新压缩后的同一图片哈希相近，可用汉明距离比较；透明区域按白色背景处理
+func PHash(img image.Image) uint64 {
+	small := Resize(img, phashSize, phashSize)
+
+	var pixels [phashSize][phashSize]float64
+	for y := 0; y < phashSize; y++ {
+		for x := 0; x < phashSize; x++ {
+			offset := small.PixOffset(x, y)
+			p := small.Pix[offset : offset+4]
+			alpha := float64(p[3]) / 255
+			r := float64(p[0])*alpha + 255*(1-alpha)
+			g := float64(p[1])*alpha + 255*(1-alpha)
+			b := float64(p[2])*alpha + 255*(1-alpha)
+			pixels[y][x] = 0.299*r + 0.587*g + 0.114*b
+		}
+	}
+
+	// 只计算需要的低频系数
+	var coefficients [phashLowFreq * phashLowFreq]float64
+	for v := 0; v < phashLowFreq; v++ {
+		for u := 0; u < phashLowFreq; u++ {
+			var sum float64
+			for y := 0; y < phashSize; y++ {
+				var row float64
+				for x := 0; x < phashSize; x++ {
+					row += pixels[y][x] * phashCosines[u][x]
+				}
+				sum += row * phashCosines[v][y]
+			}
+			coefficients[v*phashLowFreq+u] = sum
+		}
+	}
+
+	// 与中位数比较，直流分量不参与中位数计算
+	sorted := make([]float64, len(coefficients)-1)
+	copy(sorted, coefficients[1:])
+	sort.Float64s(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	var hash uint64
+	for i, coefficient := range coefficients {
+		if coefficient > median {
+			hash |= 1 << uint(len(coefficients)-1-i)
+		}
+	}
+	return hash
+}
+
+// HashDistance 返回两个感知哈希的汉明距离，0表示几乎相同
+func HashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// FormatHash 将哈希格式化为16位十六进制字符串
+func FormatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// ParseHash 解析16位十六进制哈希
+func ParseHash(s string) (uint64, error) {
+	if len(s) != 16 {
+		return 0, fmt.Errorf("无效的感知哈希: %s", s)
+	}
+	hash, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的感知哈希: %s", s)
+	}
+	return hash, nil
+}
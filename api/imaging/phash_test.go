@@ -0,0 +1,86 @@
+package imaging
+
+import "testing"
+
+func TestHashDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b uint64
+		want int
+	}{
+		{"equal", 0x0123456789abcdef, 0x0123456789abcdef, 0},
+		{"one bit", 0, 1, 1},
+		{"high bit", 0, 1 << 63, 1},
+		{"byte", 0xff00, 0x0000, 8},
+		{"all bits", 0, ^uint64(0), 64},
+		{"symmetric", 0xf0f0, 0x0ff0, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HashDistance(tt.a, tt.b); got != tt.want {
+				t.Errorf("HashDistance(%x, %x) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := HashDistance(tt.b, tt.a); got != tt.want {
+				t.Errorf("HashDistance(%x, %x) = %d, want %d", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatHash(t *testing.T) {
+	tests := []struct {
+		hash uint64
+		want string
+	}{
+		{0, "0000000000000000"},
+		{1, "0000000000000001"},
+		{0x0123456789abcdef, "0123456789abcdef"},
+		{^uint64(0), "ffffffffffffffff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := FormatHash(tt.hash); got != tt.want {
+				t.Errorf("FormatHash(%x) = %s, want %s", tt.hash, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    uint64
+		wantErr bool
+	}{
+		{"zero", "0000000000000000", 0, false},
+		{"lower", "0123456789abcdef", 0x0123456789abcdef, false},
+		{"upper", "0123456789ABCDEF", 0x0123456789abcdef, false},
+		{"max", "ffffffffffffffff", ^uint64(0), false},
+		{"empty", "", 0, true},
+		{"short", "abc", 0, true},
+		{"long", "0123456789abcdef0", 0, true},
+		{"not hex", "0123456789abcdeg", 0, true},
+		{"sign", "+123456789abcdef", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHash(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHash(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseHash(%q) = %x, want %x", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHashRoundTrip(t *testing.T) {
+	for _, hash := range []uint64{0, 42, 0x8000000000000000, 0xdeadbeefcafebabe} {
+		got, err := ParseHash(FormatHash(hash))
+		if err != nil || got != hash {
+			t.Errorf("ParseHash(FormatHash(%x)) = %x, %v", hash, got, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+)
+
+// 占位图参数
+const (
+	placeholderSampleSize = 64 // 计算BlurHash和颜色前缩小到的最长边
+	lqipSize              = 16 // LQIP的最长边
+)
+
+// Placeholder 图片加载完成前用于显示占位的信息
+type Placeholder struct {
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	BlurHash      string `json:"blurhash"`
+	LQIP          string `json:"lqip"`          // 极小的缩略图，data URI
+	DominantColor string `json:"dominantColor"` // 主色调，如 #3a6ea5
+	AverageColor  string `json:"averageColor"`  // 平均颜色
+}
+
+// NewPlaceholder 计算图片的尺寸、BlurHash、LQIP和主色调
+func NewPlaceholder(img image.Image) (*Placeholder, error) {
+	bounds := img.Bounds()
+	width, height := FitWithin(bounds.Dx(), bounds.Dy(), placeholderSampleSize)
+	sample := Resize(img, width, height)
+
+	// 横向图片横向分量多，纵向图片反之
+	componentsX, componentsY := 4, 3
+	if height > width {
+		componentsX, componentsY = 3, 4
+	}
+
+	lqip, err := encodeLQIP(img)
+	if err != nil {
+		return nil, err
+	}
+	return &Placeholder{
+		Width:         bounds.Dx(),
+		Height:        bounds.Dy(),
+		BlurHash:      BlurHash(sample, componentsX, componentsY),
+		LQIP:          lqip,
+		DominantColor: dominantColor(sample),
+		AverageColor:  averageColor(sample),
+	}, nil
+}
+
+// encodeLQIP 将图片缩小到 lqipSize 并编码为PNG data URI
+// 这个尺寸下PNG比JPEG小（JPEG的量化表和霍夫曼表就有约600字节），并且保留透明区域
+func encodeLQIP(img image.Image) (string, error) {
+	bounds := img.Bounds()
+	width, height := FitWithin(bounds.Dx(), bounds.Dy(), lqipSize)
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, Resize(img, width, height), PNG, 0); err != nil {
+		return "", err
+	}
+	return "data:" + PNG.ContentType + ";base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// dominantColor 将颜色按每通道4位分组，返回像素最多的一组的平均颜色，忽略接近透明的像素
+func dominantColor(img *image.NRGBA) string {
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	var buckets [4096]bucket
+	best := -1
+	for i := 0; i+4 <= len(img.Pix); i += 4 {
+		p := img.Pix[i : i+4]
+		if p[3] < 128 {
+			continue
+		}
+		index := int(p[0]>>4)<<8 | int(p[1]>>4)<<4 | int(p[2]>>4)
+		buckets[index].count++
+		buckets[index].r += int(p[0])
+		buckets[index].g += int(p[1])
+		buckets[index].b += int(p[2])
+		if best < 0 || buckets[index].count > buckets[best].count {
+			best = index
+		}
+	}
+	if best < 0 {
+		return averageColor(img)
+	}
+	b := buckets[best]
+	return hexColor(b.r/b.count, b.g/b.count, b.b/b.count)
+}
+
+// averageColor 按透明度加权的平均颜色，完全透明时返回白色
+func averageColor(img *image.NRGBA) string {
+	var r, g, b, weight int
+	for i := 0; i+4 <= len(img.Pix); i += 4 {
+		p := img.Pix[i : i+4]
+		alpha := int(p[3])
+		r += int(p[0]) * alpha
+		g += int(p[1]) * alpha
+		b += int(p[2]) * alpha
+		weight += alpha
+	}
+	if weight == 0 {
+		return hexColor(255, 255, 255)
+	}
+	return hexColor(r/weight, g/weight, b/weight)
+}
+
+// hexColor 格式化为 #rrggbb
+func hexColor(r, g, b int) string {
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
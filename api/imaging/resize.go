@@ -0,0 +1,25 @@
+package imaging
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// FitWithin 计算等比缩放到最长边不超过 size 后的尺寸，不放大
+func FitWithin(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
+
+// Resize 将图片缩放到指定尺寸
+func Resize(img image.Image, width, height int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
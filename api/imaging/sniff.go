@@ -0,0 +1,260 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Format 图片格式
+type Format struct {
+	Name        string   // 格式名称
+	ContentType string   // MIME类型
+	Extensions  []string // 常用扩展名（小写，含点），第一个为默认扩展名
+}
+
+// 可识别的图片格式
+var (
+	JPEG = Format{Name: "jpeg", ContentType: "image/jpeg", Extensions: []string{".jpg", ".jpeg", ".jpe", ".jfif"}}
+	PNG  = Format{Name: "png", ContentType: "image/png", Extensions: []string{".png"}}
+	GIF  = Format{Name: "gif", ContentType: "image/gif", Extensions: []string{".gif"}}
+	WebP = Format{Name: "webp", ContentType: "image/webp", Extensions: []string{".webp"}}
+	AVIF = Format{Name: "avif", ContentType: "image/avif", Extensions: []string{".avif"}}
+	HEIC = Format{Name: "heic", ContentType: "image/heic", Extensions: []string{".heic", ".heif"}}
+	BMP  = Format{Name: "bmp", ContentType: "image/bmp", Extensions: []string{".bmp", ".dib"}}
+	TIFF = Format{Name: "tiff", ContentType: "image/tiff", Extensions: []string{".tif", ".tiff"}}
+)
+
+// Formats 所有可识别的图片格式
+var Formats = []Format{JPEG, PNG, GIF, WebP, AVIF, HEIC, BMP, TIFF}
+
+// 识别失败时返回的错误
+var (
+	ErrUnknownFormat     = errors.New("无法识别的图片格式")
+	ErrExtensionMismatch = errors.New("文件扩展名与实际格式不符")
+	ErrPolyglot          = errors.New("文件包含非图片内容")
+)
+
+// headerLen 识别格式和检查嵌入内容时读取的文件头长度
+const headerLen = 4096
+
+// trailerLen 读取完毕时检查的文件尾长度
+const trailerLen = 4096
+
+// polyglotMarkers 出现在文件头或文件尾时视为嵌入了其他内容，统一使用小写比较
+// 浏览器会嗅探文件开头的HTML，压缩包（如GIFAR）的目录位于文件末尾
+var polyglotMarkers = [][]byte{
+	[]byte("<html"),
+	[]byte("<!doctype"),
+	[]byte("<script"),
+	[]byte("<iframe"),
+	[]byte("<?php"),
+	[]byte("%pdf-"),
+}
+
+// zipEndOfDirectory ZIP中央目录结束标记
+var zipEndOfDirectory = []byte("PK\x05\x06")
+
+// isoBrands ISO BMFF（AVIF/HEIC）的品牌
+var (
+	avifBrands = []string{"avif", "avis"}
+	heicBrands = []string{"heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1"}
+)
+
+// Detect 根据文件头的签名识别图片格式
+func Detect(header []byte) (Format, bool) {
+	switch {
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8, 0xFF}):
+		return JPEG, true
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return PNG, true
+	case bytes.HasPrefix(header, []byte("GIF87a")), bytes.HasPrefix(header, []byte("GIF89a")):
+		return GIF, true
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WEBP")):
+		return WebP, true
+	case bytes.HasPrefix(header, []byte("II*\x00")), bytes.HasPrefix(header, []byte("MM\x00*")):
+		return TIFF, true
+	case isBMP(header):
+		return BMP, true
+	}
+	return detectISOBMFF(header)
+}
+
+// isBMP 检查BMP文件头：保留字段为0且信息头长度为已知版本
+func isBMP(header []byte) bool {
+	if len(header) < 18 || !bytes.HasPrefix(header, []byte("BM")) {
+		return false
+	}
+	if binary.LittleEndian.Uint32(header[6:10]) != 0 {
+		return false
+	}
+	switch binary.LittleEndian.Uint32(header[14:18]) {
+	case 12, 40, 52, 56, 64, 108, 124:
+		return true
+	}
+	return false
+}
+
+// detectISOBMFF 根据 ftyp 盒中的主品牌和兼容品牌识别AVIF和HEIC
+func detectISOBMFF(header []byte) (Format, bool) {
+	if len(header) < 16 || !bytes.Equal(header[4:8], []byte("ftyp")) {
+		return Format{}, false
+	}
+	size := int(binary.BigEndian.Uint32(header[0:4]))
+	if size < 16 || size > len(header) {
+		size = len(header)
+	}
+
+	// 主品牌位于 8-12，兼容品牌从 16 开始
+	brands := []string{string(header[8:12])}
+	for i := 16; i+4 <= size; i += 4 {
+		brands = append(brands, string(header[i:i+4]))
+	}
+	// AVIF 同样声明 mif1 兼容品牌，需要优先判断
+	if containsAny(brands, avifBrands) {
+		return AVIF, true
+	}
+	if containsAny(brands, heicBrands) {
+		return HEIC, true
+	}
+	return Format{}, false
+}
+
+// containsAny 检查两个列表是否有相同元素
+func containsAny(values, candidates []string) bool {
+	for _, value := range values {
+		for _, candidate := range candidates {
+			if value == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ByContentType 根据MIME类型查找格式
+func ByContentType(contentType string) (Format, bool) {
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if contentType == "image/jpg" {
+		contentType = JPEG.ContentType
+	}
+	if contentType == "image/heif" {
+		contentType = HEIC.ContentType
+	}
+	for _, format := range Formats {
+		if format.ContentType == contentType {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// ByExtension 根据文件扩展名查找格式
+func ByExtension(filename string) (Format, bool) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, format := range Formats {
+		for _, candidate := range format.Extensions {
+			if candidate == ext {
+				return format, true
+			}
+		}
+	}
+	return Format{}, false
+}
+
+// Extension 返回格式的默认扩展名
+func (f Format) Extension() string {
+	if len(f.Extensions) == 0 {
+		return ""
+	}
+	return f.Extensions[0]
+}
+
+// CheckExtension 检查文件扩展名与格式是否一致，没有扩展名时视为一致
+func (f Format) CheckExtension(filename string) error {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == "" {
+		return nil
+	}
+	for _, candidate := range f.Extensions {
+		if candidate == ext {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s 不是 %s 图片", ErrExtensionMismatch, ext, strings.ToUpper(f.Name))
+}
+
+// Reader 识别图片格式的 Reader，读取到末尾时检查文件尾，发现嵌入内容时返回 ErrPolyglot
+type Reader struct {
+	Format Format // 识别出的格式
+
+	reader  *bufio.Reader
+	trailer []byte // 最近读取的数据，用于检查文件尾
+	err     error  // 文件尾检查结果
+}
+
+// NewReader 读取文件头识别图片格式并检查嵌入内容
+// 返回的 Reader 仍可读取完整内容
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := bufio.NewReaderSize(r, headerLen)
+	header, err := reader.Peek(headerLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("读取文件头失败: %v", err)
+	}
+
+	format, ok := Detect(header)
+	if !ok {
+		return nil, ErrUnknownFormat
+	}
+	if containsPolyglotMarker(header) {
+		return nil, ErrPolyglot
+	}
+
+	return &Reader{Format: format, reader: reader}, nil
+}
+
+// Read 读取内容，读取到末尾时检查文件尾
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	n, err := r.reader.Read(p)
+	r.trailer = append(r.trailer, p[:n]...)
+	if len(r.trailer) > 2*trailerLen {
+		r.trailer = append(r.trailer[:0], r.trailer[len(r.trailer)-trailerLen:]...)
+	}
+
+	if err == io.EOF {
+		trailer := r.trailer
+		if len(trailer) > trailerLen {
+			trailer = trailer[len(trailer)-trailerLen:]
+		}
+		if containsPolyglotMarker(trailer) || bytes.Contains(trailer, zipEndOfDirectory) {
+			r.err = ErrPolyglot
+			return n, r.err
+		}
+	}
+	return n, err
+}
+
+// Err 返回文件尾检查发现的问题，用于在写入失败后区分原因
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// containsPolyglotMarker 检查数据中是否包含可被浏览器或其他程序解析的内容
+func containsPolyglotMarker(data []byte) bool {
+	lower := bytes.ToLower(data)
+	for _, marker := range polyglotMarkers {
+		if bytes.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
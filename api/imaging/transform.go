@@ -0,0 +1,310 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// 缩放模式
+const (
+	FitCover   = "cover"   // 等比缩放覆盖目标尺寸，超出部分按重心裁剪
+	FitContain = "contain" // 等比缩放到目标尺寸以内
+	FitFill    = "fill"    // 拉伸到目标尺寸
+)
+
+// Gravity 裁剪重心
+type Gravity string
+
+// 支持的裁剪重心
+const (
+	GravityCenter    Gravity = "center"
+	GravityNorth     Gravity = "north"
+	GravitySouth     Gravity = "south"
+	GravityEast      Gravity = "east"
+	GravityWest      Gravity = "west"
+	GravityNorthEast Gravity = "northeast"
+	GravityNorthWest Gravity = "northwest"
+	GravitySouthEast Gravity = "southeast"
+	GravitySouthWest Gravity = "southwest"
+)
+
+// gravityAliases 裁剪重心的简写
+var gravityAliases = map[string]Gravity{
+	"c": GravityCenter, "n": GravityNorth, "s": GravitySouth, "e": GravityEast, "w": GravityWest,
+	"ne": GravityNorthEast, "nw": GravityNorthWest, "se": GravitySouthEast, "sw": GravitySouthWest,
+}
+
+// MaxBlur 模糊半径上限
+const MaxBlur = 50
+
+// TransformOptions 图片变换参数
+type TransformOptions struct {
+	Width   int     // 目标宽度，0 表示按比例计算
+	Height  int     // 目标高度，0 表示按比例计算
+	Fit     string  // 缩放模式，默认 cover
+	Gravity Gravity // 裁剪重心，默认 center
+	Rotate  int     // 顺时针旋转角度，只支持 0、90、180、270
+	Blur    float64 // 高斯模糊半径，0 表示不模糊
+	Enlarge bool    // 只指定一边时是否允许放大，默认不超过原图尺寸
+}
+
+// ParseGravity 解析裁剪重心，支持全称和简写，空值为 center
+func ParseGravity(value string) (Gravity, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return GravityCenter, nil
+	}
+	if gravity, ok := gravityAliases[value]; ok {
+		return gravity, nil
+	}
+	for _, gravity := range gravityAliases {
+		if string(gravity) == value {
+			return gravity, nil
+		}
+	}
+	return "", fmt.Errorf("无效的裁剪重心: %s", value)
+}
+
+// Validate 检查并补全默认值
+func (o *TransformOptions) Validate() error {
+	if o.Width < 0 || o.Height < 0 {
+		return fmt.Errorf("宽高不能为负数")
+	}
+	switch o.Fit {
+	case "":
+		o.Fit = FitCover
+	case FitCover, FitContain, FitFill:
+	default:
+		return fmt.Errorf("无效的缩放模式: %s", o.Fit)
+	}
+	if o.Gravity == "" {
+		o.Gravity = GravityCenter
+	}
+	o.Rotate = ((o.Rotate % 360) + 360) % 360
+	if o.Rotate%90 != 0 {
+		return fmt.Errorf("旋转角度只支持 90 的倍数")
+	}
+	if o.Blur < 0 || o.Blur > MaxBlur {
+		return fmt.Errorf("模糊半径必须在 0-%d 之间", MaxBlur)
+	}
+	return nil
+}
+
+// CacheKey 返回规范化的参数串，相同效果的参数得到相同结果，用于派生对象键
+func (o TransformOptions) CacheKey() string {
+	parts := []string{}
+	if o.Width > 0 {
+		parts = append(parts, "w"+strconv.Itoa(o.Width))
+	}
+	if o.Height > 0 {
+		parts = append(parts, "h"+strconv.Itoa(o.Height))
+	}
+	// 只指定一边时缩放模式和重心不影响结果
+	if o.Width > 0 && o.Height > 0 {
+		parts = append(parts, "fit-"+o.Fit)
+		if o.Fit == FitCover {
+			parts = append(parts, "g-"+string(o.Gravity))
+		}
+	}
+	if o.Rotate != 0 {
+		parts = append(parts, "r"+strconv.Itoa(o.Rotate))
+	}
+	if o.Blur > 0 {
+		parts = append(parts, "blur"+strconv.FormatFloat(o.Blur, 'f', -1, 64))
+	}
+	if o.Enlarge && (o.Width == 0) != (o.Height == 0) {
+		parts = append(parts, "enlarge")
+	}
+	if len(parts) == 0 {
+		return "original"
+	}
+	return strings.Join(parts, "_")
+}
+
+// OutputSize 返回 width×height 的图片按参数变换后的尺寸，用于在变换前检查输出大小
+func (o TransformOptions) OutputSize(width, height int) (int, int) {
+	if o.Rotate == 90 || o.Rotate == 270 {
+		width, height = height, width
+	}
+	if width <= 0 || height <= 0 {
+		return width, height
+	}
+	switch {
+	case o.Width == 0 && o.Height == 0:
+		return width, height
+	case o.Width == 0:
+		if !o.Enlarge && o.Height >= height {
+			return width, height
+		}
+		return max(1, width*o.Height/height), o.Height
+	case o.Height == 0:
+		if !o.Enlarge && o.Width >= width {
+			return width, height
+		}
+		return o.Width, max(1, height*o.Width/width)
+	case o.Fit == FitContain:
+		scale := math.Min(float64(o.Width)/float64(width), float64(o.Height)/float64(height))
+		return max(1, int(math.Round(float64(width)*scale))), max(1, int(math.Round(float64(height)*scale)))
+	}
+	return o.Width, o.Height
+}
+
+// Transform 按参数依次旋转、缩放裁剪和模糊图片
+func Transform(img image.Image, opts TransformOptions) image.Image {
+	if opts.Rotate != 0 {
+		img = Rotate(img, opts.Rotate)
+	}
+	if opts.Width > 0 || opts.Height > 0 {
+		img = resizeTo(img, opts)
+	}
+	if opts.Blur > 0 {
+		img = Blur(img, opts.Blur)
+	}
+	return img
+}
+
+// resizeTo 按缩放模式调整尺寸
+func resizeTo(img image.Image, opts TransformOptions) image.Image {
+	bounds := img.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	width, height := opts.Width, opts.Height
+
+	// 只指定一边时按比例计算另一边，未要求放大时不超过原图尺寸
+	if width == 0 {
+		if !opts.Enlarge && height >= srcHeight {
+			return img
+		}
+		width = max(1, srcWidth*height/srcHeight)
+		return Resize(img, width, height)
+	}
+	if height == 0 {
+		if !opts.Enlarge && width >= srcWidth {
+			return img
+		}
+		height = max(1, srcHeight*width/srcWidth)
+		return Resize(img, width, height)
+	}
+
+	switch opts.Fit {
+	case FitFill:
+		return Resize(img, width, height)
+	case FitContain:
+		scale := math.Min(float64(width)/float64(srcWidth), float64(height)/float64(srcHeight))
+		return Resize(img, max(1, int(math.Round(float64(srcWidth)*scale))), max(1, int(math.Round(float64(srcHeight)*scale))))
+	}
+
+	// cover：先按目标宽高比裁剪原图，再缩放，避免缩放整张大图
+	scale := math.Max(float64(width)/float64(srcWidth), float64(height)/float64(srcHeight))
+	cropWidth := min(srcWidth, max(1, int(math.Round(float64(width)/scale))))
+	cropHeight := min(srcHeight, max(1, int(math.Round(float64(height)/scale))))
+	x, y := gravityOffset(opts.Gravity, srcWidth-cropWidth, srcHeight-cropHeight)
+	crop := image.Rect(x, y, x+cropWidth, y+cropHeight).Add(bounds.Min)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+	return dst
+}
+
+// gravityOffset 根据重心计算裁剪起点
+func gravityOffset(gravity Gravity, extraX, extraY int) (int, int) {
+	x, y := extraX/2, extraY/2
+	switch gravity {
+	case GravityNorth, GravityNorthEast, GravityNorthWest:
+		y = 0
+	case GravitySouth, GravitySouthEast, GravitySouthWest:
+		y = extraY
+	}
+	switch gravity {
+	case GravityWest, GravityNorthWest, GravitySouthWest:
+		x = 0
+	case GravityEast, GravityNorthEast, GravitySouthEast:
+		x = extraX
+	}
+	return x, y
+}
+
+// Rotate 顺时针旋转 90、180 或 270 度
+func Rotate(img image.Image, degrees int) image.Image {
+	switch degrees {
+	case 90:
+		return Orient(img, 6)
+	case 180:
+		return Orient(img, 3)
+	case 270:
+		return Orient(img, 8)
+	}
+	return img
+}
+
+// Blur 近似高斯模糊，使用三次盒式模糊
+func Blur(img image.Image, sigma float64) *image.NRGBA {
+	src := toNRGBA(img)
+	// 三次盒式模糊近似标准差为 sigma 的高斯模糊
+	radius := int(math.Round(math.Sqrt(12*sigma*sigma/3+1)-1) / 2)
+	if radius < 1 {
+		radius = 1
+	}
+
+	tmp := image.NewNRGBA(src.Rect)
+	for i := 0; i < 3; i++ {
+		boxBlur(src, tmp, radius, true)
+		boxBlur(tmp, src, radius, false)
+	}
+	return src
+}
+
+// boxBlur 单方向盒式模糊，horizontal 为 false 时按列处理
+func boxBlur(src, dst *image.NRGBA, radius int, horizontal bool) {
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	lines, length := height, width
+	if !horizontal {
+		lines, length = width, height
+	}
+	offset := func(line, i int) int {
+		if horizontal {
+			return line*src.Stride + i*4
+		}
+		return i*src.Stride + line*4
+	}
+
+	window := 2*radius + 1
+	for line := 0; line < lines; line++ {
+		var sum [4]int
+		// 边缘像素向外延伸
+		for i := -radius; i <= radius; i++ {
+			p := offset(line, min(max(i, 0), length-1))
+			for c := 0; c < 4; c++ {
+				sum[c] += int(src.Pix[p+c])
+			}
+		}
+		for i := 0; i < length; i++ {
+			p := offset(line, i)
+			for c := 0; c < 4; c++ {
+				dst.Pix[p+c] = uint8(sum[c] / window)
+			}
+			add := offset(line, min(i+radius+1, length-1))
+			remove := offset(line, max(i-radius, 0))
+			for c := 0; c < 4; c++ {
+				sum[c] += int(src.Pix[add+c]) - int(src.Pix[remove+c])
+			}
+		}
+	}
+}
+
+// toNRGBA 转换为以 (0,0) 为原点的 NRGBA 图片
+func toNRGBA(img image.Image) *image.NRGBA {
+	bounds := img.Bounds()
+	if nrgba, ok := img.(*image.NRGBA); ok && bounds.Min == (image.Point{}) {
+		dst := image.NewNRGBA(nrgba.Rect)
+		copy(dst.Pix, nrgba.Pix)
+		return dst
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	return dst
+}
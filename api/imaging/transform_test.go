@@ -0,0 +1,83 @@
+package imaging
+
+import "testing"
+
+func TestTransformOptionsCacheKey(t *testing.T) {
+	tests := []struct {
+		name string
+		opts TransformOptions
+		want string
+	}{
+		{"original", TransformOptions{}, "original"},
+		{"width only", TransformOptions{Width: 400, Fit: FitFill, Gravity: GravityNorth}, "w400"},
+		{"cover", TransformOptions{Width: 400, Height: 300, Fit: FitCover, Gravity: GravityNorth}, "w400_h300_fit-cover_g-north"},
+		{"contain ignores gravity", TransformOptions{Width: 400, Height: 300, Fit: FitContain, Gravity: GravityNorth}, "w400_h300_fit-contain"},
+		{"rotate and blur", TransformOptions{Rotate: 90, Blur: 5}, "r90_blur5"},
+		{"enlarge one side", TransformOptions{Height: 800, Enlarge: true}, "h800_enlarge"},
+		{"enlarge ignored with both sides", TransformOptions{Width: 400, Height: 300, Fit: FitFill, Enlarge: true}, "w400_h300_fit-fill"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.CacheKey(); got != tt.want {
+				t.Errorf("CacheKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       TransformOptions
+		wantErr    bool
+		wantRotate int
+	}{
+		{"defaults", TransformOptions{}, false, 0},
+		{"negative rotate", TransformOptions{Rotate: -90}, false, 270},
+		{"invalid rotate", TransformOptions{Rotate: 45}, true, 0},
+		{"invalid fit", TransformOptions{Fit: "stretch"}, true, 0},
+		{"max blur", TransformOptions{Blur: MaxBlur}, false, 0},
+		{"blur too large", TransformOptions{Blur: MaxBlur + 1}, true, 0},
+		{"negative width", TransformOptions{Width: -1}, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			err := opts.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if opts.Fit != FitCover || opts.Gravity != GravityCenter || opts.Rotate != tt.wantRotate {
+				t.Errorf("Validate() = %+v", opts)
+			}
+		})
+	}
+}
+
+func TestTransformOptionsOutputSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          TransformOptions
+		width, height int
+		wantW, wantH  int
+	}{
+		{"unchanged", TransformOptions{}, 800, 600, 800, 600},
+		{"rotated", TransformOptions{Rotate: 90}, 800, 600, 600, 800},
+		{"width only", TransformOptions{Width: 400}, 800, 600, 400, 300},
+		{"no enlarge", TransformOptions{Width: 1600}, 800, 600, 800, 600},
+		{"enlarge narrow image", TransformOptions{Height: 4000, Enlarge: true}, 10000, 10, 4000000, 4000},
+		{"cover", TransformOptions{Width: 400, Height: 400, Fit: FitCover}, 800, 600, 400, 400},
+		{"contain", TransformOptions{Width: 400, Height: 400, Fit: FitContain}, 800, 600, 400, 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height := tt.opts.OutputSize(tt.width, tt.height)
+			if width != tt.wantW || height != tt.wantH {
+				t.Errorf("OutputSize() = %dx%d, want %dx%d", width, height, tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultPresets 默认的变体预设
+const DefaultPresets = "thumb:150,medium:800,large:1600"
+
+// DefaultVariantQuality 生成JPEG变体时的质量
+const DefaultVariantQuality = 85
+
+// presetNamePattern 预设名称只能包含小写字母、数字和 -，用于生成对象键
+var presetNamePattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// Preset 变体预设
+type Preset struct {
+	Name string // 名称，如 thumb
+	Size int    // 最长边像素
+}
+
+// Variant 生成的图片变体
+type Variant struct {
+	Preset string // 预设名称
+	Format Format // 输出格式
+	Width  int
+	Height int
+	Data   []byte // 编码后的内容
+}
+
+// ParsePresets 解析预设配置，格式为 名称:最长边像素，多个预设以逗号分隔
+// 例如 thumb:150,medium:800,large:1600
+func ParsePresets(spec string) ([]Preset, error) {
+	var presets []Preset
+	seen := map[string]bool{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, size, ok := strings.Cut(item, ":")
+		if !ok || !presetNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("无效的变体预设: %s", item)
+		}
+		pixels, err := strconv.Atoi(size)
+		if err != nil || pixels <= 0 {
+			return nil, fmt.Errorf("无效的变体尺寸: %s", item)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("重复的变体预设: %s", name)
+		}
+		seen[name] = true
+		presets = append(presets, Preset{Name: name, Size: pixels})
+	}
+	return presets, nil
+}
+
+// GenerateVariants 按预设缩放图片，每个预设输出原格式和WebP（withWebP 为 true 时）
+// 原格式无法编码时（如HEIC）改用JPEG；原图不大于预设尺寸时跳过该预设，不放大
+func GenerateVariants(img image.Image, format Format, presets []Preset, withWebP bool) ([]Variant, error) {
+	formats := []Format{format}
+	if !CanEncode(format) {
+		formats = []Format{JPEG}
+	}
+	if withWebP && formats[0].Name != WebP.Name {
+		formats = append(formats, WebP)
+	}
+
+	bounds := img.Bounds()
+	var variants []Variant
+	for _, preset := range presets {
+		if bounds.Dx() <= preset.Size && bounds.Dy() <= preset.Size {
+			continue
+		}
+		width, height := FitWithin(bounds.Dx(), bounds.Dy(), preset.Size)
+		resized := Resize(img, width, height)
+
+		for _, output := range formats {
+			var buf bytes.Buffer
+			if err := Encode(&buf, resized, output, DefaultVariantQuality); err != nil {
+				return nil, err
+			}
+			variants = append(variants, Variant{
+				Preset: preset.Name,
+				Format: output,
+				Width:  width,
+				Height: height,
+				Data:   buf.Bytes(),
+			})
+		}
+	}
+	return variants, nil
+}
@@ -1,19 +1,35 @@
 package handler
 
 import (
+	"go-api/api/generator"
 	"go-api/api/routes"
+	"go-api/api/storage"
+	"go-api/api/tasks"
 	"net/http"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 )
 
+var (
+	routerOnce sync.Once
+	router     *gin.Engine
+)
+
 func Handler(w http.ResponseWriter, r *http.Request) {
-	// 创建 Gin 引擎
-	gin.SetMode(gin.ReleaseMode)
-	router := gin.New()
+	// 同一实例只创建一次 Gin 引擎和存储客户端，后续请求复用
+	routerOnce.Do(func() {
+		gin.SetMode(gin.ReleaseMode)
+		router = gin.New()
+
+		// 检查存储和生成服务配置并记录缺少的环境变量
+		storage.Init()
+		generator.Init()
+		tasks.Init()
 
-	// 注册路由
-	routes.SetupRoutes(router)
+		// 注册路由
+		routes.SetupRoutes(router)
+	})
 
 	// 处理请求
 	router.ServeHTTP(w, r)
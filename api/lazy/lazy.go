@@ -0,0 +1,36 @@
+// Package lazy 提供进程级共享、首次使用时创建的值，用于各包的默认客户端和存储
+package lazy
+
+import "sync"
+
+// Value 延迟创建的共享值，零值可直接使用
+// 创建失败时不缓存结果，下次获取时重新创建，以便修正配置后无需重启
+type Value[T any] struct {
+	mu    sync.Mutex
+	value T
+	ok    bool
+}
+
+// Get 返回共享值，尚未创建或上次创建失败时调用 create
+func (v *Value[T]) Get(create func() (T, error)) (T, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.ok {
+		return v.value, nil
+	}
+	value, err := create()
+	if err != nil {
+		return value, err
+	}
+	v.value, v.ok = value, true
+	return v.value, nil
+}
+
+// Set 替换共享值，用于测试或自定义初始化
+func (v *Value[T]) Set(value T) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.value, v.ok = value, true
+}
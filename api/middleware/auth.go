@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth 校验管理令牌，用于保护对象浏览、删除等运维接口
+// 令牌通过 Authorization: Bearer <ADMIN_TOKEN> 或 X-Admin-Token 请求头传递
+func AdminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		expected := os.Getenv("ADMIN_TOKEN")
+		if expected == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "未配置管理令牌"})
+			return
+		}
+
+		token := c.GetHeader("X-Admin-Token")
+		if token == "" {
+			token = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "管理令牌无效"})
+			return
+		}
+
+		c.Next()
+	}
+}
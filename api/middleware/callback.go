@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCallbackBody 回调请求体的最大字节数
+const maxCallbackBody = 1 << 20
+
+// CallbackAuth 校验生成服务回调，密钥为 GENERATION_CALLBACK_SECRET
+// 支持请求体签名 X-Signature: sha256=<hex>（HMAC-SHA256），
+// 或通过 token 查询参数、X-Callback-Token 请求头传递共享密钥（用于不支持签名的生成服务）
+func CallbackAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := os.Getenv("GENERATION_CALLBACK_SECRET")
+		if secret == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "未配置回调密钥"})
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCallbackBody+1))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "读取回调内容失败"})
+			return
+		}
+		if len(body) > maxCallbackBody {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "回调内容过大"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if signature := c.GetHeader("X-Signature"); signature != "" {
+			if !validSignature(secret, body, signature) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "回调签名无效"})
+				return
+			}
+			c.Next()
+			return
+		}
+
+		token := c.GetHeader("X-Callback-Token")
+		if token == "" {
+			token = c.Query("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "回调密钥无效"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// validSignature 校验请求体的 HMAC-SHA256 签名
+func validSignature(secret string, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// callbackRouter 返回经过 CallbackAuth 的路由，处理函数回显请求体
+func callbackRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/callback", CallbackAuth(), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+	return router
+}
+
+// sign 计算请求体的 X-Signature 请求头
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestCallbackAuth(t *testing.T) {
+	const body = `{"taskId":"t1"}`
+	tests := []struct {
+		name    string
+		secret  string
+		path    string
+		headers map[string]string
+		body    string
+		want    int
+	}{
+		{"no secret configured", "", "/callback?token=", nil, body, http.StatusForbidden},
+		{"token query", "s3cret", "/callback?token=s3cret", nil, body, http.StatusOK},
+		{"token header", "s3cret", "/callback", map[string]string{"X-Callback-Token": "s3cret"}, body, http.StatusOK},
+		{"wrong token", "s3cret", "/callback?token=other", nil, body, http.StatusUnauthorized},
+		{"missing token", "s3cret", "/callback", nil, body, http.StatusUnauthorized},
+		{"valid signature", "s3cret", "/callback", map[string]string{"X-Signature": sign("s3cret", body)}, body, http.StatusOK},
+		{"signature over other body", "s3cret", "/callback", map[string]string{"X-Signature": sign("s3cret", "{}")}, body, http.StatusUnauthorized},
+		{"malformed signature", "s3cret", "/callback", map[string]string{"X-Signature": "sha256=zz"}, body, http.StatusUnauthorized},
+		// 签名无效时不回退到令牌校验
+		{"bad signature with token", "s3cret", "/callback?token=s3cret", map[string]string{"X-Signature": sign("other", body)}, body, http.StatusUnauthorized},
+		{"body too large", "s3cret", "/callback?token=s3cret", nil, strings.Repeat("a", maxCallbackBody+1), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GENERATION_CALLBACK_SECRET", tt.secret)
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			callbackRouter().ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("status = %d, want %d, body %s", recorder.Code, tt.want, recorder.Body)
+			}
+			// 校验后请求体仍可被处理函数读取
+			if tt.want == http.StatusOK && recorder.Body.String() != tt.body {
+				t.Errorf("handler body = %q, want %q", recorder.Body, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TusVersion 支持的tus协议版本
+const TusVersion = "1.0.0"
+
+// TusResumable 为tus协议的响应添加 Tus-Resumable 头，并校验请求的协议版本
+// OPTIONS 请求用于探测服务端能力，不要求携带版本
+func TusResumable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Tus-Resumable", TusVersion)
+		if c.Request.Method != http.MethodOptions && c.GetHeader("Tus-Resumable") != TusVersion {
+			c.Header("Tus-Version", TusVersion)
+			c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": "不支持的tus协议版本"})
+			return
+		}
+		c.Next()
+	}
+}
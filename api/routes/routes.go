@@ -2,6 +2,8 @@ package routes
 
 import (
 	"go-api/api/controllers"
+	"go-api/api/middleware"
+	"go-api/api/storage"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,8 +13,8 @@ func SetupRoutes(router *gin.Engine) {
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
-	// 静态文件服务 - 为images目录提供静态文件访问
-	router.Static("/images", "./images")
+	// 静态文件服务 - 为本地存储目录提供静态文件访问
+	router.StaticFS(storage.LocalRoutePrefix, storage.LocalFileSystem())
 
 	// 定义路由组
 	api := router.Group("/api")
@@ -20,17 +22,63 @@ func SetupRoutes(router *gin.Engine) {
 		// 示例路由
 		api.GET("/hello", controllers.Hello)
 		api.GET("/ping", controllers.Ping)
+		api.GET("/health", controllers.Health)
 
 		// 图片生成路由
 		api.POST("/generate-image", controllers.GenerateImage)
 		api.GET("/getTaskInfo", controllers.GetTaskInfo)
+		api.POST("/cancelTask", controllers.CancelTask)
+		api.GET("/tasks/:id", controllers.GetTask)
+		api.POST("/callbacks/generation", middleware.CallbackAuth(), controllers.GenerationCallback)
 
 		// 图片上传路由
 		api.POST("/uploadImg", controllers.UploadImage)
+		api.POST("/uploads/batch", controllers.BatchUpload)
+
+		// 浏览器直传路由
+		api.POST("/uploads/presign", controllers.PresignUpload)
+		api.POST("/uploads/complete", controllers.CompleteUpload)
+
+		// 可续传上传路由（tus 1.0）
+		tus := api.Group("/tus", middleware.TusResumable())
+		{
+			tus.OPTIONS("/", controllers.TusOptions)
+			tus.POST("/", controllers.TusCreate)
+			tus.OPTIONS("/:id", controllers.TusOptions)
+			tus.HEAD("/:id", controllers.TusHead)
+			tus.PATCH("/:id", controllers.TusPatch)
+			tus.DELETE("/:id", controllers.TusDelete)
+			tus.GET("/:id", controllers.TusStatus)
+		}
+
+		// 按需变换路由
+		api.GET("/img/*key", controllers.TransformImage)
+
+		// 相似图片查询路由，需要管理令牌
+		api.GET("/images/similar", middleware.AdminAuth(), controllers.SimilarImages)
 
 		// 背景移除路由
 		api.POST("/remove-background", controllers.RemoveBackground)
 
+		// 对象管理路由，需要管理令牌
+		objects := api.Group("/objects", middleware.AdminAuth())
+		{
+			objects.GET("", controllers.ListObjects)
+			objects.GET("/*key", controllers.GetObject)
+			objects.DELETE("/*key", controllers.DeleteObject)
+			objects.POST("/batch-delete", controllers.BatchDeleteObjects)
+			objects.POST("/sweep", controllers.SweepExpiredObjects)
+		}
+
+		// webhook投递记录路由，需要管理令牌
+		webhookRoutes := api.Group("/webhooks", middleware.AdminAuth())
+		{
+			webhookRoutes.GET("/deliveries", controllers.ListWebhookDeliveries)
+			webhookRoutes.GET("/deliveries/:id", controllers.GetWebhookDelivery)
+			webhookRoutes.POST("/deliveries/:id/replay", controllers.ReplayWebhookDelivery)
+			webhookRoutes.POST("/sweep", controllers.SweepWebhookDeliveries)
+		}
+
 		// 可以在这里添加更多路由组
 		// v1 := api.Group("/v1")
 		// {
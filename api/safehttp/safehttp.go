@@ -0,0 +1,99 @@
+// Package safehttp 访问第三方或用户提供的地址时使用的HTTP客户端
+// 连接前检查解析出的IP，拒绝回环、内网、链路本地等地址，避免通过服务端访问内部服务
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress 目标地址为内网或保留地址
+var ErrBlockedAddress = errors.New("不允许访问内网或保留地址")
+
+// maxRedirects 最多跟随的重定向次数
+const maxRedirects = 5
+
+// sharedAddressSpace 运营商级NAT地址段（100.64.0.0/10），net.IP 没有对应的判断方法
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// NewClient 创建只连接公网地址的HTTP客户端，allowedHosts 非空时只允许访问其中的域名（含子域名），重定向同样检查
+// 不使用环境变量中的代理，否则检查的是代理地址而不是目标地址
+func NewClient(timeout time.Duration, allowedHosts []string) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: checkDial}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("重定向次数过多")
+			}
+			return CheckURL(req.URL.String(), allowedHosts)
+		},
+	}
+}
+
+// CheckURL 检查地址是否为 http/https 且域名在 allowedHosts 中，allowedHosts 为空时不限制域名
+// IP是否为内网地址在连接时检查，域名解析结果可能变化
+func CheckURL(rawURL string, allowedHosts []string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("无效的地址: %s", rawURL)
+	}
+	if len(allowedHosts) > 0 && !hostAllowed(parsed.Hostname(), allowedHosts) {
+		return fmt.Errorf("不允许访问的域名: %s", parsed.Hostname())
+	}
+	return nil
+}
+
+// ParseHosts 解析逗号分隔的域名列表
+func ParseHosts(value string) []string {
+	var hosts []string
+	for _, host := range strings.Split(value, ",") {
+		if host = strings.ToLower(strings.Trim(strings.TrimSpace(host), ".")); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// hostAllowed 域名等于列表中的某一项或为其子域名
+func hostAllowed(host string, allowedHosts []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range allowedHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDial 在建立连接前检查实际连接的IP
+func checkDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || blocked(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	return nil
+}
+
+// blocked 是否为回环、内网、链路本地、组播、未指定或运营商级NAT地址
+func blocked(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
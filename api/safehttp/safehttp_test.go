@@ -0,0 +1,106 @@
+package safehttp
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckURL(t *testing.T) {
+	hosts := []string{"example.com"}
+	tests := []struct {
+		name    string
+		url     string
+		hosts   []string
+		wantErr bool
+	}{
+		{"https", "https://cdn.other.com/a.png", nil, false},
+		{"http", "http://cdn.other.com/a.png", nil, false},
+		{"ftp", "ftp://example.com/a.png", nil, true},
+		{"no host", "https:///a.png", nil, true},
+		{"invalid", "://", nil, true},
+		{"allowed host", "https://example.com/a.png", hosts, false},
+		{"allowed subdomain", "https://cdn.example.com/a.png", hosts, false},
+		{"allowed with trailing dot", "https://Example.COM./a.png", hosts, false},
+		{"suffix is not subdomain", "https://badexample.com/a.png", hosts, true},
+		{"other host", "https://other.com/a.png", hosts, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckURL(tt.url, tt.hosts); (err != nil) != tt.wantErr {
+				t.Errorf("CheckURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseHosts(t *testing.T) {
+	got := ParseHosts(" Example.com, .cdn.example.net. ,,")
+	want := []string{"example.com", "cdn.example.net"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseHosts() = %v, want %v", got, want)
+	}
+	if hosts := ParseHosts(""); len(hosts) != 0 {
+		t.Errorf("ParseHosts(\"\") = %v", hosts)
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"8.8.8.8", false},
+		{"100.128.0.1", false},
+		{"2606:4700:4700::1111", false},
+	}
+	for _, tt := range tests {
+		if got := blocked(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("blocked(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestNewClientRejectsLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached loopback server")
+	}))
+	defer server.Close()
+
+	client := NewClient(5*time.Second, nil)
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Get(loopback) error = %v, want ErrBlockedAddress", err)
+	}
+}
+
+func TestNewClientChecksRedirects(t *testing.T) {
+	client := NewClient(5*time.Second, []string{"example.com"})
+
+	req, _ := http.NewRequest(http.MethodGet, "https://other.com/a.png", nil)
+	if err := client.CheckRedirect(req, nil); err == nil {
+		t.Error("redirect to host outside allow list should fail")
+	}
+	req, _ = http.NewRequest(http.MethodGet, "https://cdn.example.com/a.png", nil)
+	if err := client.CheckRedirect(req, nil); err != nil {
+		t.Errorf("redirect to allowed host error = %v", err)
+	}
+	if err := client.CheckRedirect(req, make([]*http.Request, maxRedirects)); err == nil {
+		t.Error("too many redirects should fail")
+	}
+}
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// blobAPIURL Vercel Blob API 地址
+const blobAPIURL = "https://blob.vercel-storage.com"
+
+// blobAPIVersion Vercel Blob API 版本
+const blobAPIVersion = "7"
+
+// BlobStore Vercel Blob 存储
+// Vercel Blob 不支持自定义元数据，PutOptions.Metadata 会被忽略；过期清理以过期索引中的时间为准
+type BlobStore struct {
+	token   string
+	baseURL string // 存储的公开访问地址，如 https://xxx.public.blob.vercel-storage.com
+	client  *http.Client
+}
+
+// blobObject Vercel Blob API 返回的对象结构
+type blobObject struct {
+	URL         string    `json:"url"`
+	Pathname    string    `json:"pathname"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	UploadedAt  time.Time `json:"uploadedAt"`
+}
+
+// NewBlobStore 创建 Vercel Blob 存储
+func NewBlobStore() (*BlobStore, error) {
+	token := os.Getenv("BLOB_READ_WRITE_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("未配置Vercel Blob令牌")
+	}
+
+	return &BlobStore{
+		token:   token,
+		baseURL: strings.TrimRight(os.Getenv("BLOB_BASE_URL"), "/"),
+		client:  &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// Put 上传对象到 Vercel Blob
+func (b *BlobStore) Put(ctx context.Context, key string, body io.Reader, opts PutOptions) (*ObjectInfo, error) {
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = getContentType(key)
+	}
+
+	counter := &countingReader{reader: body}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, blobAPIURL+"/"+key, counter)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	b.setHeaders(req)
+	req.Header.Set("x-content-type", contentType)
+	req.Header.Set("x-add-random-suffix", "0")
+
+	var result blobObject
+	if err := b.do(req, &result); err != nil {
+		return nil, fmt.Errorf("上传到Blob Storage失败: %v", err)
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         counter.n,
+		ContentType:  contentType,
+		LastModified: time.Now(),
+		Metadata:     opts.Metadata,
+		URL:          result.URL,
+	}, nil
+}
+
+// Get 从 Vercel Blob 读取对象
+func (b *BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	info, err := b.Head(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, info.URL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取Blob对象失败: %v", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("读取Blob对象失败，状态码: %d", resp.StatusCode)
+	}
+
+	return resp.Body, info, nil
+}
+
+// Head 获取 Vercel Blob 对象信息
+func (b *BlobStore) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	// 未配置公开地址时通过前缀列举定位对象，前缀下对象较多时逐页查找直到完全匹配
+	if b.baseURL == "" {
+		token := ""
+		for {
+			result, err := b.List(ctx, ListOptions{Prefix: key, ContinuationToken: token, MaxKeys: 1000})
+			if err != nil {
+				return nil, err
+			}
+			for _, object := range result.Objects {
+				if object.Key == key {
+					return &object, nil
+				}
+			}
+			if !result.IsTruncated || result.NextContinuationToken == "" {
+				return nil, ErrNotFound
+			}
+			token = result.NextContinuationToken
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, blobAPIURL+"?url="+url.QueryEscape(b.PublicURL(key)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	b.setHeaders(req)
+
+	var result blobObject
+	if err := b.do(req, &result); err != nil {
+		return nil, err
+	}
+
+	return result.toObjectInfo(), nil
+}
+
+// Delete 删除 Vercel Blob 对象
+func (b *BlobStore) Delete(ctx context.Context, key string) error {
+	info, err := b.Head(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string][]string{"urls": {info.URL}})
+	if err != nil {
+		return fmt.Errorf("构建请求体失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, blobAPIURL+"/delete", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	b.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	if err := b.do(req, nil); err != nil {
+		return fmt.Errorf("删除Blob对象失败: %v", err)
+	}
+	return nil
+}
+
+// List 列举 Vercel Blob 对象
+func (b *BlobStore) List(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	query := url.Values{}
+	if opts.Prefix != "" {
+		query.Set("prefix", opts.Prefix)
+	}
+	if opts.ContinuationToken != "" {
+		query.Set("cursor", opts.ContinuationToken)
+	}
+	if opts.MaxKeys > 0 {
+		query.Set("limit", strconv.Itoa(opts.MaxKeys))
+	}
+	// Vercel Blob 只支持以 / 为分隔符的目录模式
+	if opts.Delimiter != "" {
+		query.Set("mode", "folded")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, blobAPIURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	b.setHeaders(req)
+
+	var response struct {
+		Blobs   []blobObject `json:"blobs"`
+		Folders []string     `json:"folders"`
+		Cursor  string       `json:"cursor"`
+		HasMore bool         `json:"hasMore"`
+	}
+	if err := b.do(req, &response); err != nil {
+		return nil, fmt.Errorf("列举Blob对象失败: %v", err)
+	}
+
+	result := &ListResult{
+		Objects:               make([]ObjectInfo, 0, len(response.Blobs)),
+		CommonPrefixes:        response.Folders,
+		NextContinuationToken: response.Cursor,
+		IsTruncated:           response.HasMore,
+	}
+	for _, blob := range response.Blobs {
+		result.Objects = append(result.Objects, *blob.toObjectInfo())
+	}
+
+	return result, nil
+}
+
+// KeepsMetadata Vercel Blob 不保存自定义元数据
+func (b *BlobStore) KeepsMetadata() bool {
+	return false
+}
+
+// PublicURL 返回对象的公开访问URL，未配置 BLOB_BASE_URL 时返回空字符串
+func (b *BlobStore) PublicURL(key string) string {
+	if b.baseURL == "" {
+		return ""
+	}
+	return b.baseURL + "/" + key
+}
+
+// setHeaders 设置 Vercel Blob API 公共请求头
+func (b *BlobStore) setHeaders(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	req.Header.Set("x-api-version", blobAPIVersion)
+}
+
+// do 发送请求并解析JSON响应
+func (b *BlobStore) do(req *http.Request, out interface{}) error {
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("状态码: %d, 响应: %s", resp.StatusCode, string(body))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+// toObjectInfo 转换为通用对象信息
+func (o blobObject) toObjectInfo() *ObjectInfo {
+	return &ObjectInfo{
+		Key:          o.Pathname,
+		Size:         o.Size,
+		ContentType:  o.ContentType,
+		LastModified: o.UploadedAt,
+		URL:          o.URL,
+	}
+}
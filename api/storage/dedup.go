@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 内容寻址写入时记录的元数据键
+const (
+	MetaOriginalFilename = "original-filename"
+	MetaSHA256           = "sha256"
+)
+
+// stagingPrefix 流式内容寻址上传时的暂存目录
+const stagingPrefix = "_staging/"
+
+// ContentAddressingEnabled 是否启用内容寻址键（STORAGE_CONTENT_ADDRESSED）
+func ContentAddressingEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("STORAGE_CONTENT_ADDRESSED"))
+	return enabled
+}
+
+// ContentKey 根据内容的SHA-256生成对象键，保留原文件扩展名
+func ContentKey(data []byte, filename string) string {
+	sum := sha256.Sum256(data)
+	return contentKeyFromSum(hex.EncodeToString(sum[:]), filename)
+}
+
+// PutDeduplicated 以内容哈希为键写入对象
+// 对象已存在时跳过上传并返回已有对象信息，第二个返回值表示是否命中已有对象
+func PutDeduplicated(ctx context.Context, store ObjectStore, data []byte, filename string, opts PutOptions) (*ObjectInfo, bool, error) {
+	key := ContentKey(data, filename)
+	opts = contentAddressedOptions(key, filename, opts)
+
+	info, err := reuseExisting(ctx, store, key, opts)
+	if err != nil || info != nil {
+		return info, info != nil, err
+	}
+
+	info, err = store.Put(ctx, key, bytes.NewReader(data), opts)
+	if err != nil {
+		return nil, false, err
+	}
+	return info, false, nil
+}
+
+// PutDeduplicatedStream 以流的方式按内容哈希写入对象
+// 支持服务端复制的后端先写入暂存键并同时计算哈希，再复制到最终键；
+// 其他后端需要先缓存到临时文件计算哈希
+func PutDeduplicatedStream(ctx context.Context, store ObjectStore, body io.Reader, filename string, opts PutOptions) (*ObjectInfo, bool, error) {
+	copier, ok := store.(Copier)
+	if !ok {
+		return putDeduplicatedSpooled(ctx, store, body, filename, opts)
+	}
+
+	hasher := sha256.New()
+	stagingKey := stagingPrefix + newID() + strings.ToLower(filepath.Ext(filename))
+	if _, err := store.Put(ctx, stagingKey, io.TeeReader(body, hasher), PutOptions{ContentType: opts.ContentType}); err != nil {
+		return nil, false, err
+	}
+	defer store.Delete(context.Background(), stagingKey)
+
+	key := contentKeyFromSum(hex.EncodeToString(hasher.Sum(nil)), filename)
+	opts = contentAddressedOptions(key, filename, opts)
+	info, err := reuseExisting(ctx, store, key, opts)
+	if err != nil || info != nil {
+		return info, info != nil, err
+	}
+
+	if err := copier.Copy(ctx, stagingKey, key, opts); err != nil {
+		return nil, false, err
+	}
+	info, err = store.Head(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	return withURL(store, info), false, nil
+}
+
+// putDeduplicatedSpooled 缓存到临时文件后按内容哈希写入
+func putDeduplicatedSpooled(ctx context.Context, store ObjectStore, body io.Reader, filename string, opts PutOptions) (*ObjectInfo, bool, error) {
+	tempFile, err := os.CreateTemp("", "dedup-*.tmp")
+	if err != nil {
+		return nil, false, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tempFile, hasher), body); err != nil {
+		return nil, false, fmt.Errorf("缓存上传内容失败: %v", err)
+	}
+
+	key := contentKeyFromSum(hex.EncodeToString(hasher.Sum(nil)), filename)
+	opts = contentAddressedOptions(key, filename, opts)
+	info, err := reuseExisting(ctx, store, key, opts)
+	if err != nil || info != nil {
+		return info, info != nil, err
+	}
+
+	if _, err := tempFile.Seek(0, io.SeekStart); err != nil {
+		return nil, false, fmt.Errorf("读取临时文件失败: %v", err)
+	}
+	info, err = store.Put(ctx, key, tempFile, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	return info, false, nil
+}
+
+// reuseExisting 查询对象是否已存在并可直接复用，不可复用时返回 nil, nil
+// 已有对象会更早过期时，支持服务端复制的后端原地更新元数据，其他后端需重新写入
+func reuseExisting(ctx context.Context, store ObjectStore, key string, opts PutOptions) (*ObjectInfo, error) {
+	info, err := store.Head(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if needsExpiryRefresh(info.Metadata, opts.Metadata) {
+		copier, ok := store.(Copier)
+		if !ok {
+			return nil, nil
+		}
+		metadata := mergeMetadata(info.Metadata, opts.Metadata)
+		if _, ok := opts.Metadata[MetaExpiresAt]; !ok {
+			delete(metadata, MetaExpiresAt)
+		}
+		opts.Metadata = metadata
+		opts.ContentType = info.ContentType
+		if err := copier.Copy(ctx, key, key, opts); err != nil {
+			return nil, err
+		}
+		info.Metadata = metadata
+	}
+
+	return withURL(store, info), nil
+}
+
+// contentAddressedOptions 补充原文件名和哈希元数据
+func contentAddressedOptions(key, filename string, opts PutOptions) PutOptions {
+	// 原文件名保存在元数据中，元数据需为ASCII因此进行转义
+	opts.Metadata = mergeMetadata(opts.Metadata, map[string]string{
+		MetaOriginalFilename: EscapeMetadata(filename),
+		MetaSHA256:           strings.TrimSuffix(key, filepath.Ext(key)),
+	})
+	if opts.ContentType == "" {
+		opts.ContentType = getContentType(filename)
+	}
+	return opts
+}
+
+// contentKeyFromSum 根据十六进制哈希和文件名生成对象键
+func contentKeyFromSum(sum, filename string) string {
+	return sum + strings.ToLower(filepath.Ext(filename))
+}
+
+// withURL 确保对象信息包含访问URL
+func withURL(store ObjectStore, info *ObjectInfo) *ObjectInfo {
+	if info.URL == "" {
+		info.URL = store.PublicURL(info.Key)
+	}
+	return info
+}
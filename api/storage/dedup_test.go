@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// newTestStore 返回使用临时目录的本地存储
+func newTestStore(t *testing.T) *LocalStore {
+	t.Helper()
+	t.Setenv("LOCAL_STORAGE_ROOT", t.TempDir())
+	store, err := NewLocalStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// plainStore 隐藏本地存储的服务端复制能力，用于测试不支持复制的后端
+type plainStore struct {
+	ObjectStore
+}
+
+func TestContentKey(t *testing.T) {
+	// "hello" 的SHA-256
+	const sum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	tests := []struct {
+		name     string
+		filename string
+		want     string
+	}{
+		{"extension kept", "a.png", sum + ".png"},
+		{"extension lowercased", "A.JPG", sum + ".jpg"},
+		{"no extension", "README", sum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContentKey([]byte("hello"), tt.filename); got != tt.want {
+				t.Errorf("ContentKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPutDeduplicated(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	data := []byte("same content")
+
+	first, existed, err := PutDeduplicated(ctx, store, data, "first.png", PutOptions{})
+	if err != nil || existed {
+		t.Fatalf("first put: existed = %v, err = %v", existed, err)
+	}
+	second, existed, err := PutDeduplicated(ctx, store, data, "second.png", PutOptions{})
+	if err != nil || !existed {
+		t.Fatalf("second put: existed = %v, err = %v", existed, err)
+	}
+	if first.Key != second.Key || first.Key != ContentKey(data, "x.png") {
+		t.Errorf("keys = %q, %q, want %q", first.Key, second.Key, ContentKey(data, "x.png"))
+	}
+	// 命中已有对象时保留首次写入的原文件名
+	if got := UnescapeMetadata(second.Metadata[MetaOriginalFilename]); got != "first.png" {
+		t.Errorf("original filename = %q, want first.png", got)
+	}
+}
+
+func TestPutDeduplicatedStream(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(*LocalStore) ObjectStore
+	}{
+		{"copier", func(s *LocalStore) ObjectStore { return s }},
+		{"spooled", func(s *LocalStore) ObjectStore { return plainStore{s} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			local := newTestStore(t)
+			store := tt.store(local)
+			data := []byte("streamed content")
+
+			info, existed, err := PutDeduplicatedStream(ctx, store, bytes.NewReader(data), "photo.WEBP", PutOptions{})
+			if err != nil || existed {
+				t.Fatalf("first put: existed = %v, err = %v", existed, err)
+			}
+			if want := ContentKey(data, "photo.webp"); info.Key != want {
+				t.Errorf("key = %q, want %q", info.Key, want)
+			}
+			if info.Metadata[MetaSHA256] != strings.TrimSuffix(info.Key, ".webp") {
+				t.Errorf("sha256 metadata = %q", info.Metadata[MetaSHA256])
+			}
+
+			_, existed, err = PutDeduplicatedStream(ctx, store, bytes.NewReader(data), "again.webp", PutOptions{})
+			if err != nil || !existed {
+				t.Fatalf("second put: existed = %v, err = %v", existed, err)
+			}
+
+			// 暂存对象写入后删除
+			staged, err := local.List(ctx, ListOptions{Prefix: stagingPrefix})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(staged.Objects) != 0 {
+				t.Errorf("staging objects left: %v", staged.Objects)
+			}
+		})
+	}
+}
+
+func TestSave(t *testing.T) {
+	tests := []struct {
+		name           string
+		contentAddress string
+		fixedKey       bool
+		wantContentKey bool
+	}{
+		{"fixed key", "false", false, false},
+		{"content addressed", "true", false, true},
+		{"derived object", "true", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STORAGE_CONTENT_ADDRESSED", tt.contentAddress)
+			ctx := context.Background()
+			store := newTestStore(t)
+			data := []byte("saved content")
+
+			info, err := Save(ctx, store, bytes.NewReader(data), SaveOptions{Key: "uploads/a.png", Filename: "a.png", FixedKey: tt.fixedKey})
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantKey := "uploads/a.png"
+			if tt.wantContentKey {
+				wantKey = ContentKey(data, "a.png")
+			}
+			if info.Key != wantKey {
+				t.Errorf("key = %q, want %q", info.Key, wantKey)
+			}
+			if info.URL == "" {
+				t.Error("URL is empty")
+			}
+
+			head, err := store.Head(ctx, info.Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := ContentKey(data, ""); head.Metadata[MetaSHA256] != want {
+				t.Errorf("sha256 metadata = %q, want %q", head.Metadata[MetaSHA256], want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// MaxBatchDelete 单次批量删除的最大对象数
+const MaxBatchDelete = 1000
+
+// batchDeleteConcurrency 不支持批量删除的后端逐个删除时的并发数
+const batchDeleteConcurrency = 8
+
+// BatchDeleter 支持批量删除的存储后端
+type BatchDeleter interface {
+	// DeleteObjects 批量删除对象，返回删除失败的键及原因
+	DeleteObjects(ctx context.Context, keys []string) (map[string]string, error)
+}
+
+// DeleteMany 批量删除对象，返回删除失败的键及原因
+// 支持批量删除的后端一次请求完成，其他后端并发逐个删除
+func DeleteMany(ctx context.Context, store ObjectStore, keys []string) (map[string]string, error) {
+	if deleter, ok := store.(BatchDeleter); ok {
+		return deleter.DeleteObjects(ctx, keys)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failures := map[string]string{}
+	semaphore := make(chan struct{}, batchDeleteConcurrency)
+
+	for _, key := range keys {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if err := store.Delete(ctx, key); err != nil {
+				mu.Lock()
+				failures[key] = err.Error()
+				mu.Unlock()
+			}
+		}(key)
+	}
+
+	wg.Wait()
+	return failures, nil
+}
+
+// DeleteWithDerived 批量删除对象及其派生内容，返回删除失败的键及原因
+// 派生内容包括上传时生成的变体 _variants/<对象键>/、原图和变体的按需变换缓存 _derived/<对象键>/
+// 以及感知哈希索引；派生内容删除失败只记录日志
+func DeleteWithDerived(ctx context.Context, store ObjectStore, keys []string) (map[string]string, error) {
+	var derived []string
+	for _, key := range keys {
+		variants, err := listKeys(ctx, store, variantPrefix(key))
+		if err != nil {
+			return nil, err
+		}
+		derived = append(derived, variants...)
+		for _, object := range append([]string{key}, variants...) {
+			cached, err := listKeys(ctx, store, DerivedPrefix+object+"/")
+			if err != nil {
+				return nil, err
+			}
+			derived = append(derived, cached...)
+		}
+	}
+
+	failures, err := DeleteMany(ctx, store, keys)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []string
+	for _, key := range keys {
+		if _, failed := failures[key]; !failed {
+			deleted = append(deleted, key)
+		}
+	}
+	removePHashes(ctx, store, deleted)
+	if len(derived) > 0 {
+		derivedFailures, err := DeleteMany(ctx, store, derived)
+		if err != nil {
+			log.Printf("删除派生对象失败: %v", err)
+		}
+		for key, reason := range derivedFailures {
+			log.Printf("删除派生对象失败: %s: %s", key, reason)
+		}
+	}
+	return failures, nil
+}
+
+// listKeys 列举前缀下的全部对象键
+func listKeys(ctx context.Context, store ObjectStore, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		page, err := store.List(ctx, ListOptions{Prefix: prefix, ContinuationToken: token, MaxKeys: 1000})
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Objects {
+			keys = append(keys, object.Key)
+		}
+		if !page.IsTruncated {
+			return keys, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
@@ -0,0 +1,52 @@
+package storage
+
+import "strings"
+
+// DerivedPrefix 按需变换结果的缓存目录，键为 _derived/<原对象键>/<变换参数><扩展名>
+const DerivedPrefix = "_derived/"
+
+// VariantPrefix 上传时生成的变体目录，键为 _variants/<原对象键>/<预设><扩展名>
+// 变体可以公开访问，不属于内部对象，但用户写入的对象不能使用该前缀
+const VariantPrefix = "_variants/"
+
+// internalPrefixes 内部使用的对象键前缀，不对外提供变换等处理
+var internalPrefixes = []string{stagingPrefix, expiryPrefix, DerivedPrefix, TusPrefix, PHashIndexPrefix}
+
+// reservedPrefixes 由服务写入的对象键前缀，用户写入的对象不能使用
+var reservedPrefixes = append([]string{VariantPrefix}, internalPrefixes...)
+
+// IsInternalKey 是否为内部使用的对象键
+func IsInternalKey(key string) bool {
+	for _, prefix := range internalPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReservedKey 是否为保留的对象键，包括内部对象和变体
+func IsReservedKey(key string) bool {
+	for _, prefix := range reservedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DerivedKey 返回派生对象的缓存键
+func DerivedKey(key, variant, ext string) string {
+	return DerivedPrefix + key + "/" + variant + ext
+}
+
+// VariantKey 返回上传时生成的变体的对象键，如 photo.jpg → _variants/photo.jpg/thumb.webp
+// 变体位于保留目录中，不会与用户上传的对象重名
+func VariantKey(originalKey, preset, ext string) string {
+	return variantPrefix(originalKey) + preset + ext
+}
+
+// variantPrefix 返回原图所有变体所在的目录
+func variantPrefix(originalKey string) string {
+	return VariantPrefix + originalKey + "/"
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVariantKey(t *testing.T) {
+	tests := []struct {
+		key, preset, ext string
+		want             string
+	}{
+		{"photo.jpg", "thumb", ".webp", "_variants/photo.jpg/thumb.webp"},
+		{"a/b/photo.png", "medium", ".png", "_variants/a/b/photo.png/medium.png"},
+	}
+	for _, tt := range tests {
+		if got := VariantKey(tt.key, tt.preset, tt.ext); got != tt.want {
+			t.Errorf("VariantKey(%q, %q, %q) = %q, want %q", tt.key, tt.preset, tt.ext, got, tt.want)
+		}
+	}
+}
+
+func TestReservedKeys(t *testing.T) {
+	tests := []struct {
+		key      string
+		internal bool
+		reserved bool
+	}{
+		{"photo.jpg", false, false},
+		{"photo_thumb.webp", false, false},
+		{"tenant/_variants/photo.jpg", false, false},
+		{"_variants/photo.jpg/thumb.webp", false, true},
+		{"_derived/photo.jpg/w100.webp", true, true},
+		{"_expiry/00000000001700000000/photo.jpg", true, true},
+	}
+	for _, tt := range tests {
+		if got := IsInternalKey(tt.key); got != tt.internal {
+			t.Errorf("IsInternalKey(%q) = %v, want %v", tt.key, got, tt.internal)
+		}
+		if got := IsReservedKey(tt.key); got != tt.reserved {
+			t.Errorf("IsReservedKey(%q) = %v, want %v", tt.key, got, tt.reserved)
+		}
+	}
+}
+
+func TestDeleteWithDerivedVariants(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	keys := []string{
+		"photo.jpg",
+		VariantKey("photo.jpg", "thumb", ".webp"),
+		DerivedKey("photo.jpg", "w100", ".webp"),
+		DerivedKey(VariantKey("photo.jpg", "thumb", ".webp"), "w50", ".webp"),
+		// 与旧的变体命名方式相同的用户对象不受影响
+		"photo_thumb.webp",
+		"photo.jpg.bak",
+	}
+	for _, key := range keys {
+		if _, err := store.Put(ctx, key, strings.NewReader(key), PutOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	failures, err := DeleteWithDerived(ctx, store, []string{"photo.jpg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) > 0 {
+		t.Fatalf("failures = %v", failures)
+	}
+	for i, key := range keys {
+		_, err := store.Head(ctx, key)
+		kept := i >= 4
+		if kept && err != nil {
+			t.Errorf("%s 被删除: %v", key, err)
+		}
+		if !kept && !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s 未删除: %v", key, err)
+		}
+	}
+}
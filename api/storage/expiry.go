@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetaExpiresAt 记录对象过期时间的元数据键（RFC3339格式）
+const MetaExpiresAt = "expires-at"
+
+// expiryPrefix 过期索引目录，索引键为 _expiry/<20位Unix秒>/<对象键>，按字典序即按过期时间排序
+const expiryPrefix = "_expiry/"
+
+// SweepResult 过期清理结果
+type SweepResult struct {
+	Deleted []string          `json:"deleted"`
+	Skipped int               `json:"skipped"` // 已续期或不再过期的对象数量
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// ExpiresAt 读取对象元数据中的过期时间
+func ExpiresAt(metadata map[string]string) (time.Time, bool) {
+	value, ok := metadata[MetaExpiresAt]
+	if !ok {
+		return time.Time{}, false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expiresAt, true
+}
+
+// RecordExpiry 写入过期索引，内容为过期时间，不依赖对象元数据
+// 通过 Save 写入的对象已自动记录，由客户端直接写入存储的对象需在确认上传后调用
+func RecordExpiry(ctx context.Context, store ObjectStore, key string, expiresAt time.Time) error {
+	marker := fmt.Sprintf("%s%020d/%s", expiryPrefix, expiresAt.Unix(), key)
+	if _, err := store.Put(ctx, marker, strings.NewReader(expiresAt.Format(time.RFC3339)), PutOptions{ContentType: "text/plain"}); err != nil {
+		return fmt.Errorf("写入过期索引失败: %v", err)
+	}
+	return nil
+}
+
+// parseExpiryMarker 解析过期索引键
+func parseExpiryMarker(marker string) (time.Time, string, bool) {
+	rest := strings.TrimPrefix(marker, expiryPrefix)
+	timestamp, key, ok := strings.Cut(rest, "/")
+	if !ok || key == "" {
+		return time.Time{}, "", false
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return time.Unix(seconds, 0), key, true
+}
+
+// SweepExpired 删除所有在 now 之前过期的对象
+// 删除前会再次读取对象元数据，已续期或取消过期的对象会被跳过
+// 过期对象通过 DeleteWithDerived 删除，变体、变换缓存和感知哈希索引随之一并删除
+func SweepExpired(ctx context.Context, store ObjectStore, now time.Time) (*SweepResult, error) {
+	result := &SweepResult{Deleted: []string{}, Errors: map[string]string{}}
+
+	token := ""
+	for {
+		page, err := store.List(ctx, ListOptions{Prefix: expiryPrefix, ContinuationToken: token, MaxKeys: 1000})
+		if err != nil {
+			return result, err
+		}
+
+		// 每页的过期对象批量删除，对象删除成功后再删除其过期索引
+		markers := map[string][]string{}
+		var expired []string
+		done := false
+		for _, marker := range page.Objects {
+			expiresAt, key, ok := parseExpiryMarker(marker.Key)
+			if ok && expiresAt.After(now) {
+				// 索引按过期时间排序，后面的都未过期
+				done = true
+				break
+			}
+			if ok {
+				if _, pending := markers[key]; pending {
+					markers[key] = append(markers[key], marker.Key)
+					continue
+				}
+				isExpired, err := isObjectExpired(ctx, store, key, expiresAt, now)
+				switch {
+				case errors.Is(err, ErrNotFound):
+					// 对象已删除，只清理索引
+				case err != nil:
+					result.Errors[key] = err.Error()
+					continue
+				case isExpired:
+					markers[key] = []string{marker.Key}
+					expired = append(expired, key)
+					continue
+				default:
+					result.Skipped++
+				}
+			}
+			if err := store.Delete(ctx, marker.Key); err != nil {
+				result.Errors[marker.Key] = err.Error()
+			}
+		}
+
+		if err := sweepObjects(ctx, store, expired, markers, result); err != nil {
+			return result, err
+		}
+		if done || !page.IsTruncated {
+			return result, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// isObjectExpired 判断对象是否已过期，marked 为过期索引中的时间，对象已不存在时返回 ErrNotFound
+// 不保存元数据的后端无法续期或取消过期，以索引中的时间为准
+func isObjectExpired(ctx context.Context, store ObjectStore, key string, marked, now time.Time) (bool, error) {
+	info, err := store.Head(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	expiresAt, ok := ExpiresAt(info.Metadata)
+	if !ok && !KeepsMetadata(store) {
+		expiresAt, ok = marked, true
+	}
+	return ok && !expiresAt.After(now), nil
+}
+
+// sweepObjects 删除过期对象及其派生内容，删除成功的对象随后删除其过期索引
+func sweepObjects(ctx context.Context, store ObjectStore, keys []string, markers map[string][]string, result *SweepResult) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	failures, err := DeleteWithDerived(ctx, store, keys)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if reason, failed := failures[key]; failed {
+			result.Errors[key] = reason
+			continue
+		}
+		result.Deleted = append(result.Deleted, key)
+		for _, marker := range markers[key] {
+			if err := store.Delete(ctx, marker); err != nil {
+				result.Errors[marker] = err.Error()
+			}
+		}
+	}
+	return nil
+}
+
+// needsExpiryRefresh 判断复用已有对象时是否需要更新其过期时间
+// 新写入不过期或过期时间更晚时，需要延长已有对象的有效期
+func needsExpiryRefresh(existing, requested map[string]string) bool {
+	current, ok := ExpiresAt(existing)
+	if !ok {
+		return false
+	}
+	wanted, ok := ExpiresAt(requested)
+	return !ok || wanted.After(current)
+}
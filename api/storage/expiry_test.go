@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseExpiryMarker(t *testing.T) {
+	tests := []struct {
+		marker  string
+		wantKey string
+		wantOK  bool
+	}{
+		{"_expiry/00000000001700000000/a/b.png", "a/b.png", true},
+		{"_expiry/00000000001700000000/", "", false},
+		{"_expiry/not-a-time/a.png", "", false},
+		{"_expiry/00000000001700000000", "", false},
+	}
+	for _, tt := range tests {
+		expiresAt, key, ok := parseExpiryMarker(tt.marker)
+		if ok != tt.wantOK || key != tt.wantKey {
+			t.Errorf("parseExpiryMarker(%q) = %q, %v, want %q, %v", tt.marker, key, ok, tt.wantKey, tt.wantOK)
+		}
+		if ok && expiresAt.Unix() != 1700000000 {
+			t.Errorf("parseExpiryMarker(%q) time = %v", tt.marker, expiresAt)
+		}
+	}
+}
+
+func TestSweepExpired(t *testing.T) {
+	t.Setenv("STORAGE_CONTENT_ADDRESSED", "false")
+	ctx := context.Background()
+	store := newTestStore(t)
+	save := func(key string, ttl time.Duration) {
+		t.Helper()
+		if _, err := Save(ctx, store, strings.NewReader(key), SaveOptions{Key: key, TTL: ttl}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put := func(key string, metadata map[string]string) {
+		t.Helper()
+		if _, err := store.Put(ctx, key, strings.NewReader(key), PutOptions{Metadata: metadata}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	save("expired.png", time.Hour)
+	put(VariantKey("expired.png", "thumb", ".webp"), nil)
+	put(DerivedKey("expired.png", "w100", ".webp"), nil)
+	save("renewed.png", time.Hour)
+	// 续期后元数据中的过期时间晚于清理时间
+	put("renewed.png", map[string]string{MetaExpiresAt: time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)})
+	save("future.png", 24*time.Hour)
+	if err := RecordExpiry(ctx, store, "gone.png", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := SweepExpired(ctx, store, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("errors = %v", result.Errors)
+	}
+	if len(result.Deleted) != 1 || result.Deleted[0] != "expired.png" {
+		t.Errorf("deleted = %v, want [expired.png]", result.Deleted)
+	}
+	if result.Skipped != 1 {
+		t.Errorf("skipped = %d, want 1", result.Skipped)
+	}
+
+	for _, key := range []string{"expired.png", VariantKey("expired.png", "thumb", ".webp"), DerivedKey("expired.png", "w100", ".webp")} {
+		if _, err := store.Head(ctx, key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s 未删除: %v", key, err)
+		}
+	}
+	for _, key := range []string{"renewed.png", "future.png"} {
+		if _, err := store.Head(ctx, key); err != nil {
+			t.Errorf("%s 被删除: %v", key, err)
+		}
+	}
+
+	// 只保留未到期对象的索引
+	markers, err := listKeys(ctx, store, expiryPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(markers) != 1 || !strings.HasSuffix(markers[0], "/future.png") {
+		t.Errorf("markers = %v", markers)
+	}
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 各接口的默认对象键模板，与历史键格式保持一致
+const (
+	DefaultUploadKeyTemplate           = "{timestamp}-{filename}"
+	DefaultRemoveBackgroundKeyTemplate = "bg_removed_{unix}_{filename}"
+	DefaultGenerationKeyTemplate       = "generated/{yyyy}/{mm}/{dd}/{uuid}{ext}"
+)
+
+// DefaultTenant 未指定租户时使用的租户名
+const DefaultTenant = "default"
+
+// maxFilenameLength 清洗后文件名的最大长度
+const maxFilenameLength = 100
+
+// placeholderPattern 模板占位符
+var placeholderPattern = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// KeyParams 生成对象键的参数
+type KeyParams struct {
+	Tenant   string    // 租户
+	Source   string    // 写入对象的接口
+	Filename string    // 原文件名
+	Time     time.Time // 写入时间，零值时使用当前时间
+}
+
+// KeyTemplateFor 返回指定接口的对象键模板
+// 依次读取 KEY_TEMPLATE_<SOURCE>（如 KEY_TEMPLATE_REMOVE_BACKGROUND）、KEY_TEMPLATE，最后使用默认模板
+func KeyTemplateFor(source string) string {
+	envName := "KEY_TEMPLATE_" + strings.ToUpper(strings.ReplaceAll(source, "-", "_"))
+	if template := os.Getenv(envName); template != "" {
+		return template
+	}
+	if template := os.Getenv("KEY_TEMPLATE"); template != "" {
+		return template
+	}
+
+	switch source {
+	case SourceRemoveBackground:
+		return DefaultRemoveBackgroundKeyTemplate
+	case SourceGeneration:
+		return DefaultGenerationKeyTemplate
+	}
+	return DefaultUploadKeyTemplate
+}
+
+// BuildKey 按模板生成对象键
+// 支持的占位符：
+//
+//	{tenant} {source}              租户、来源接口
+//	{yyyy} {mm} {dd} {hh}          UTC日期分区
+//	{unix} {timestamp}             Unix秒、20060102150405格式时间
+//	{uuid} {rand}                  随机UUID、8位随机串
+//	{filename} {name} {ext}        清洗后的完整文件名、不含扩展名的文件名、小写扩展名（含点）
+func BuildKey(template string, params KeyParams) (string, error) {
+	now := params.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	utc := now.UTC()
+
+	filename := SanitizeFilename(params.Filename)
+	ext := filepath.Ext(filename)
+	tenant := sanitizeSegment(params.Tenant)
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+
+	values := map[string]string{
+		"tenant":    tenant,
+		"source":    sanitizeSegment(params.Source),
+		"yyyy":      utc.Format("2006"),
+		"mm":        utc.Format("01"),
+		"dd":        utc.Format("02"),
+		"hh":        utc.Format("15"),
+		"unix":      strconv.FormatInt(now.Unix(), 10),
+		"timestamp": now.Format("20060102150405"),
+		"uuid":      newUUID(),
+		"rand":      newID()[:8],
+		"filename":  filename,
+		"name":      strings.TrimSuffix(filename, ext),
+		"ext":       ext,
+	}
+
+	var unknown []string
+	key := placeholderPattern.ReplaceAllStringFunc(template, func(match string) string {
+		name := match[1 : len(match)-1]
+		value, ok := values[name]
+		if !ok {
+			unknown = append(unknown, match)
+		}
+		return value
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("对象键模板包含未知占位符: %s", strings.Join(unknown, ", "))
+	}
+
+	// 去掉空的路径段，避免出现 // 或以 / 开头的键
+	var segments []string
+	for _, segment := range strings.Split(key, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	key = strings.Join(segments, "/")
+	if key == "" {
+		return "", fmt.Errorf("对象键模板生成了空键: %s", template)
+	}
+	return key, nil
+}
+
+// SanitizeFilename 清洗文件名：去掉路径，将重音字符转换为ASCII，
+// 空白和其他非 [A-Za-z0-9._-] 字符替换为 -，并限制长度
+func SanitizeFilename(name string) string {
+	// 同时处理 / 和 \ 两种路径分隔符
+	if idx := strings.LastIndexAny(name, `/\`); idx >= 0 {
+		name = name[idx+1:]
+	}
+
+	ext := strings.ToLower(sanitizeSegment(filepath.Ext(name)))
+	if ext == "." || ext == "" {
+		ext = ""
+	} else if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	base := sanitizeSegment(strings.TrimSuffix(name, filepath.Ext(name)))
+	if base == "" {
+		base = "file"
+	}
+
+	if len(base)+len(ext) > maxFilenameLength {
+		base = base[:maxFilenameLength-len(ext)]
+	}
+	return base + ext
+}
+
+// sanitizeSegment 将字符串清洗为只包含 [A-Za-z0-9._-] 的路径段，且不以 . 或 - 开头结尾
+func sanitizeSegment(value string) string {
+	var builder strings.Builder
+	lastDash := false
+	for _, r := range norm.NFKD.String(value) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// 去掉分解后的重音符号
+			continue
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_'):
+			builder.WriteRune(r)
+			lastDash = false
+		case r == '-' || unicode.IsSpace(r) || r >= unicode.MaxASCII || unicode.IsPunct(r) || unicode.IsSymbol(r):
+			if !lastDash {
+				builder.WriteByte('-')
+				lastDash = true
+			}
+		}
+	}
+	return strings.Trim(builder.String(), ".-")
+}
+
+// newUUID 生成随机UUID（版本4）
+func newUUID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		// 系统随机数源不可用时无法安全生成ID
+		panic(fmt.Sprintf("生成随机数失败: %v", err))
+	}
+	buf[6] = (buf[6] & 0x0f) | 0x40
+	buf[8] = (buf[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:16])
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain", "photo.jpg", "photo.jpg"},
+		{"uppercase extension", "Photo.JPG", "Photo.jpg"},
+		{"spaces", "my  holiday photo.png", "my-holiday-photo.png"},
+		{"accents", "café crème.jpg", "cafe-creme.jpg"},
+		{"unix path", "../../etc/passwd", "passwd"},
+		{"windows path", `C:\Users\me\pic.gif`, "pic.gif"},
+		{"no extension", "README", "README"},
+		{"only extension", ".jpg", "file.jpg"},
+		{"punctuation", "a&b#c!.webp", "a-b-c.webp"},
+		{"leading and trailing dashes", "--name--.png", "name.png"},
+		{"non latin", "图片.png", "file.png"},
+		{"empty", "", "file"},
+		{"long", strings.Repeat("a", 150) + ".jpeg", strings.Repeat("a", 95) + ".jpeg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeFilename(tt.input); got != tt.want {
+				t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildKey(t *testing.T) {
+	at := time.Date(2024, 3, 5, 7, 8, 9, 0, time.UTC)
+	tests := []struct {
+		name     string
+		template string
+		params   KeyParams
+		want     string
+	}{
+		{"default upload", DefaultUploadKeyTemplate, KeyParams{Filename: "My Photo.JPG", Time: at}, "20240305070809-My-Photo.jpg"},
+		{"remove background", DefaultRemoveBackgroundKeyTemplate, KeyParams{Filename: "cat.png", Time: at}, "bg_removed_1709622489_cat.png"},
+		{"date partitions", "{yyyy}/{mm}/{dd}/{hh}/{name}{ext}", KeyParams{Filename: "a.webp", Time: at}, "2024/03/05/07/a.webp"},
+		{"default tenant", "{tenant}/{source}/{filename}", KeyParams{Source: "upload", Filename: "a.png", Time: at}, "default/upload/a.png"},
+		{"sanitized tenant", "{tenant}/{filename}", KeyParams{Tenant: "../Acme Corp", Filename: "a.png", Time: at}, "Acme-Corp/a.png"},
+		{"empty segments removed", "/{source}//{filename}/", KeyParams{Filename: "a.png", Time: at}, "a.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildKey(tt.template, tt.params)
+			if err != nil {
+				t.Fatalf("BuildKey(%q) error = %v", tt.template, err)
+			}
+			if got != tt.want {
+				t.Errorf("BuildKey(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildKeyRandom(t *testing.T) {
+	tests := []struct {
+		template string
+		pattern  string
+	}{
+		{DefaultGenerationKeyTemplate, `^generated/\d{4}/\d{2}/\d{2}/[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}\.png$`},
+		{"{rand}-{filename}", `^[0-9a-zA-Z]{8}-a\.png$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			got, err := BuildKey(tt.template, KeyParams{Filename: "a.png"})
+			if err != nil {
+				t.Fatalf("BuildKey(%q) error = %v", tt.template, err)
+			}
+			if !regexp.MustCompile(tt.pattern).MatchString(got) {
+				t.Errorf("BuildKey(%q) = %q, want match %s", tt.template, got, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestBuildKeyErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"unknown placeholder", "{tenant}/{unknown}"},
+		{"empty key", "{source}/"},
+		{"empty template", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := BuildKey(tt.template, KeyParams{}); err == nil {
+				t.Errorf("BuildKey(%q) = %q, want error", tt.template, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LocalRoutePrefix 本地存储对应的静态文件路由
+const LocalRoutePrefix = "/images"
+
+// localMetaDir 保存对象元数据的隐藏目录，不对外提供访问
+const localMetaDir = ".meta"
+
+// LocalStore 本地磁盘存储，文件通过 /images 静态路由访问
+type LocalStore struct {
+	root    string     // 存储根目录
+	baseURL string     // 访问URL前缀
+	mu      sync.Mutex // 串行化条件写入
+}
+
+// localMeta 本地对象的元数据文件内容
+type localMeta struct {
+	ContentType string            `json:"contentType"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// LocalRoot 返回本地存储根目录，默认 ./images
+func LocalRoot() string {
+	root := os.Getenv("LOCAL_STORAGE_ROOT")
+	if root == "" {
+		root = "./images"
+	}
+	return root
+}
+
+// LocalFileSystem 返回用于静态路由的文件系统，隐藏以 . 开头的文件和目录及内部对象，且不列出目录
+func LocalFileSystem() http.FileSystem {
+	return hiddenFileSystem{http.Dir(LocalRoot())}
+}
+
+// NewLocalStore 创建本地磁盘存储
+func NewLocalStore() (*LocalStore, error) {
+	root := LocalRoot()
+	// 可配置为完整地址，如 http://localhost:3000/images
+	baseURL := os.Getenv("LOCAL_STORAGE_BASE_URL")
+	if baseURL == "" {
+		baseURL = LocalRoutePrefix
+	}
+
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("创建存储目录失败: %v", err)
+	}
+
+	return &LocalStore{
+		root:    root,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}, nil
+}
+
+// Put 写入对象到本地磁盘
+func (l *LocalStore) Put(ctx context.Context, key string, body io.Reader, opts PutOptions) (*ObjectInfo, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %v", err)
+	}
+
+	// 先写入临时文件再重命名，避免读取到写了一半的文件
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("创建文件失败: %v", err)
+	}
+	_, err = io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("写入文件失败: %v", err)
+	}
+
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = getContentType(key)
+	}
+	if err := l.writeMeta(key, localMeta{ContentType: contentType, Metadata: opts.Metadata}); err != nil {
+		return nil, err
+	}
+
+	return l.Head(ctx, key)
+}
+
+// PutIfMatch 只在对象当前的ETag等于 etag 时写入
+func (l *LocalStore) PutIfMatch(ctx context.Context, key string, body io.Reader, etag string, opts PutOptions) (*ObjectInfo, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current, err := l.Head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if current.ETag != etag {
+		return nil, ErrPreconditionFailed
+	}
+	return l.Put(ctx, key, body, opts)
+}
+
+// Copy 复制本地对象
+func (l *LocalStore) Copy(ctx context.Context, srcKey, dstKey string, opts PutOptions) error {
+	reader, source, err := l.Get(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if opts.ContentType == "" {
+		opts.ContentType = source.ContentType
+	}
+	_, err = l.Put(ctx, dstKey, reader, opts)
+	return err
+}
+
+// Get 从本地磁盘读取对象
+func (l *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	info, err := l.Head(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	path, _ := l.path(key)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+	return file, info, nil
+}
+
+// Head 获取本地对象信息
+func (l *LocalStore) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	if stat.IsDir() {
+		return nil, ErrNotFound
+	}
+
+	meta := l.readMeta(key)
+	return &ObjectInfo{
+		Key:          key,
+		Size:         stat.Size(),
+		ContentType:  meta.ContentType,
+		LastModified: stat.ModTime(),
+		ETag:         fmt.Sprintf("%x-%x", stat.ModTime().UnixNano(), stat.Size()), // 由修改时间和大小生成
+		Metadata:     meta.Metadata,
+		URL:          l.PublicURL(key),
+	}, nil
+}
+
+// Delete 删除本地对象
+func (l *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除文件失败: %v", err)
+	}
+	os.Remove(l.metaPath(path))
+	return nil
+}
+
+// List 列举本地对象，分页令牌为上一页最后一个键
+func (l *LocalStore) List(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	var keys []string
+	err := filepath.WalkDir(l.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// 跳过元数据目录和临时文件
+		if strings.HasPrefix(d.Name(), ".") && path != l.root {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(l.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, opts.Prefix) && key > opts.ContinuationToken {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("列举文件失败: %v", err)
+	}
+	sort.Strings(keys)
+
+	maxKeys := opts.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	result := &ListResult{Objects: []ObjectInfo{}}
+	seenPrefixes := map[string]bool{}
+	for _, key := range keys {
+		// 按分隔符聚合"目录"
+		prefix := ""
+		if opts.Delimiter != "" {
+			rest := strings.TrimPrefix(key, opts.Prefix)
+			if idx := strings.Index(rest, opts.Delimiter); idx >= 0 {
+				prefix = opts.Prefix + rest[:idx+len(opts.Delimiter)]
+			}
+		}
+		if prefix != "" && seenPrefixes[prefix] {
+			result.NextContinuationToken = key
+			continue
+		}
+
+		if len(result.Objects)+len(result.CommonPrefixes) >= maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		result.NextContinuationToken = key
+
+		if prefix != "" {
+			seenPrefixes[prefix] = true
+			result.CommonPrefixes = append(result.CommonPrefixes, prefix)
+			continue
+		}
+
+		info, err := l.Head(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		result.Objects = append(result.Objects, *info)
+	}
+	if !result.IsTruncated {
+		result.NextContinuationToken = ""
+	}
+
+	return result, nil
+}
+
+// PublicURL 返回对象的访问URL
+func (l *LocalStore) PublicURL(key string) string {
+	return l.baseURL + "/" + key
+}
+
+// path 将对象键转换为本地路径，并阻止跳出根目录或访问隐藏文件
+func (l *LocalStore) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + filepath.FromSlash(key))
+	if cleaned == string(filepath.Separator) {
+		return "", fmt.Errorf("无效的对象键: %s", key)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(cleaned), "/") {
+		if strings.HasPrefix(part, ".") {
+			return "", fmt.Errorf("无效的对象键: %s", key)
+		}
+	}
+	return filepath.Join(l.root, cleaned), nil
+}
+
+// metaPath 返回对象对应的元数据文件路径
+func (l *LocalStore) metaPath(path string) string {
+	rel, _ := filepath.Rel(l.root, path)
+	return filepath.Join(l.root, localMetaDir, rel+".json")
+}
+
+// writeMeta 写入对象元数据
+func (l *LocalStore) writeMeta(key string, meta localMeta) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	metaPath := l.metaPath(path)
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0o755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("序列化元数据失败: %v", err)
+	}
+	if err := os.WriteFile(metaPath, data, 0o644); err != nil {
+		return fmt.Errorf("写入元数据失败: %v", err)
+	}
+	return nil
+}
+
+// readMeta 读取对象元数据，缺失时根据扩展名推断Content-Type
+func (l *LocalStore) readMeta(key string) localMeta {
+	var meta localMeta
+	if path, err := l.path(key); err == nil {
+		if data, err := os.ReadFile(l.metaPath(path)); err == nil {
+			json.Unmarshal(data, &meta)
+		}
+	}
+	if meta.ContentType == "" {
+		meta.ContentType = getContentType(key)
+	}
+	return meta
+}
+
+// hiddenFileSystem 隐藏以 . 开头的路径和内部对象（见 IsInternalKey）的文件系统
+type hiddenFileSystem struct {
+	fs http.FileSystem
+}
+
+// Open 打开文件，隐藏文件和目录返回不存在
+func (h hiddenFileSystem) Open(name string) (http.File, error) {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return nil, fs.ErrNotExist
+		}
+	}
+	if IsInternalKey(strings.TrimPrefix(path.Clean("/"+name), "/")) {
+		return nil, fs.ErrNotExist
+	}
+	file, err := h.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if stat, err := file.Stat(); err != nil || stat.IsDir() {
+		file.Close()
+		return nil, fs.ErrNotExist
+	}
+	return file, nil
+}
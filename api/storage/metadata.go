@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+)
+
+// 对象来源追踪元数据键
+const (
+	MetaUploader    = "uploader"     // 上传者标识
+	MetaSource      = "source"       // 写入对象的接口
+	MetaInputSHA256 = "input-sha256" // 生成该对象的输入内容哈希
+	MetaInputURL    = "input-url"    // 生成该对象的输入图片URL
+	MetaVariantOf   = "variant-of"   // 变体对应的原图对象键
+	MetaVariant     = "variant"      // 变体预设名称
+)
+
+// 图片信息元数据键，用于在图片加载前显示占位
+const (
+	MetaWidth         = "width"
+	MetaHeight        = "height"
+	MetaBlurHash      = "blurhash"
+	MetaDominantColor = "dominant-color"
+	MetaAverageColor  = "average-color"
+)
+
+// 对象来源
+const (
+	SourceUpload           = "upload"
+	SourceRemoveBackground = "remove-background"
+	SourceGeneration       = "generation"
+)
+
+// EscapeMetadata 转义元数据值，S3用户元数据只能包含ASCII字符
+func EscapeMetadata(value string) string {
+	return url.QueryEscape(value)
+}
+
+// UnescapeMetadata 还原经 EscapeMetadata 转义的元数据值
+func UnescapeMetadata(value string) string {
+	unescaped, err := url.QueryUnescape(value)
+	if err != nil {
+		return value
+	}
+	return unescaped
+}
+
+// UpdateMetadata 将附加元数据合并到已写入对象的元数据中
+// 通过服务端复制原地更新，不支持服务端复制的后端跳过
+func UpdateMetadata(ctx context.Context, store ObjectStore, info *ObjectInfo, extra map[string]string) error {
+	copier, ok := store.(Copier)
+	if !ok {
+		return nil
+	}
+
+	current, err := store.Head(ctx, info.Key)
+	if err != nil {
+		return err
+	}
+	opts := PutOptions{
+		ContentType: current.ContentType,
+		Metadata:    mergeMetadata(current.Metadata, extra),
+	}
+	if err := copier.Copy(ctx, info.Key, info.Key, opts); err != nil {
+		return err
+	}
+	info.Metadata = opts.Metadata
+	return nil
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// PresignedPut 预签名PUT上传请求
+type PresignedPut struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"` // 上传时必须携带的请求头
+}
+
+// PresignedPost 预签名POST表单上传请求
+type PresignedPost struct {
+	URL    string            `json:"url"`
+	Fields map[string]string `json:"fields"` // 表单字段，文件字段需放在最后
+}
+
+// PresignOptions 预签名上传选项
+type PresignOptions struct {
+	ContentType string        // 必须与上传时的Content-Type一致
+	Size        int64         // 大于0时PUT请求必须上传该大小的文件
+	MaxSize     int64         // POST表单允许的最大文件大小
+	Expires     time.Duration // 有效期
+	// Metadata 上传的对象必须携带的元数据，纳入签名，客户端无法修改
+	Metadata map[string]string
+}
+
+// Presigner 支持浏览器直传的存储后端
+type Presigner interface {
+	// PresignPut 生成预签名PUT上传地址
+	PresignPut(ctx context.Context, key string, opts PresignOptions) (*PresignedPut, error)
+	// PresignPost 生成带Content-Type和大小限制条件的POST表单上传策略
+	PresignPost(ctx context.Context, key string, opts PresignOptions) (*PresignedPost, error)
+}
@@ -0,0 +1,524 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go-api/api/imaging"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// 分片上传默认参数
+const (
+	defaultUploadPartSizeMB  = 8
+	defaultUploadConcurrency = 3
+)
+
+// R2Config R2存储配置
+type R2Config struct {
+	AccountID       string
+	AccessKeyID     string
+	SecretAccessKey string
+	BucketName      string
+	PartSize        int64  // 分片大小（字节），不小于5MB
+	Concurrency     int    // 分片上传并发数
+	PublicDomain    string // 自定义公开域名
+	DevDomain       string // R2.dev 公开域名
+}
+
+// R2Client R2客户端
+type R2Client struct {
+	client   *s3.Client
+	uploader *manager.Uploader
+	config   *R2Config
+}
+
+// NewR2Client 创建新的R2客户端
+func NewR2Client() (*R2Client, error) {
+	// 首先尝试从环境变量获取
+	cfg := &R2Config{
+		AccountID:       os.Getenv("R2_ACCOUNT_ID"),
+		AccessKeyID:     os.Getenv("R2_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("R2_SECRET_ACCESS_KEY"),
+		BucketName:      os.Getenv("R2_BUCKET_NAME"),
+		PartSize:        envInt64("R2_UPLOAD_PART_SIZE_MB", defaultUploadPartSizeMB) * 1024 * 1024,
+		Concurrency:     int(envInt64("R2_UPLOAD_CONCURRENCY", defaultUploadConcurrency)),
+		PublicDomain:    os.Getenv("R2_PUBLIC_DOMAIN"),
+		DevDomain:       os.Getenv("R2_DEV_DOMAIN"),
+	}
+	if cfg.PartSize < manager.MinUploadPartSize {
+		cfg.PartSize = manager.MinUploadPartSize
+	}
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+
+	// 再次验证配置
+	if cfg.AccountID == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" || cfg.BucketName == "" {
+		return nil, fmt.Errorf("R2配置无效")
+	}
+
+	// 创建AWS配置
+	awsCfg, err := config.LoadDefaultConfig(context.TODO(),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			cfg.AccessKeyID,
+			cfg.SecretAccessKey,
+			"",
+		)),
+		config.WithRegion("auto"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("创建AWS配置失败: %v", err)
+	}
+
+	// 创建S3客户端，指向R2端点
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(fmt.Sprintf("https://%s.r2.cloudflarestorage.com", cfg.AccountID))
+	})
+
+	// 上传管理器：小文件单次PutObject，大文件自动分片上传，内存占用约为 分片大小×并发数
+	uploader := manager.NewUploader(client, func(u *manager.Uploader) {
+		u.PartSize = cfg.PartSize
+		u.Concurrency = cfg.Concurrency
+	})
+
+	return &R2Client{
+		client:   client,
+		uploader: uploader,
+		config:   cfg,
+	}, nil
+}
+
+// UploadImage 上传图片到R2
+// 启用内容寻址时以SHA-256为键，相同内容只存储一次
+func (r2 *R2Client) UploadImage(imageData []byte, filename string) (string, error) {
+	return r2.UploadImageFromReader(context.TODO(), bytes.NewReader(imageData), filename)
+}
+
+// UploadImageFromReader 以流的方式上传图片到R2，不在内存中缓存完整文件
+// Content-Type 和扩展名按文件签名识别的格式确定，不信任文件名
+func (r2 *R2Client) UploadImageFromReader(ctx context.Context, body io.Reader, filename string) (string, error) {
+	image, err := imaging.NewReader(body)
+	if err != nil {
+		return "", err
+	}
+	filename = strings.TrimSuffix(filename, path.Ext(filename)) + image.Format.Extension()
+
+	key, err := BuildKey(KeyTemplateFor(SourceRemoveBackground), KeyParams{
+		Source:   SourceRemoveBackground,
+		Filename: filename,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	info, err := Save(ctx, r2, image, SaveOptions{
+		PutOptions: PutOptions{ContentType: image.Format.ContentType},
+		Key:        key,
+		Filename:   filename,
+	})
+	if err != nil {
+		return "", err
+	}
+	return info.URL, nil
+}
+
+// Put 上传对象到R2，请求体按分片流式发送
+func (r2 *R2Client) Put(ctx context.Context, key string, body io.Reader, opts PutOptions) (*ObjectInfo, error) {
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = getContentType(key)
+	}
+
+	counter := &countingReader{reader: body}
+	output, err := r2.uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(r2.config.BucketName),
+		Key:         aws.String(key),
+		Body:        counter,
+		ContentType: aws.String(contentType),
+		Metadata:    opts.Metadata,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("上传到R2失败: %v", err)
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         counter.n,
+		ContentType:  contentType,
+		LastModified: time.Now(),
+		ETag:         strings.Trim(aws.ToString(output.ETag), `"`),
+		Metadata:     opts.Metadata,
+		URL:          r2.buildPublicURL(key),
+	}, nil
+}
+
+// PutIfMatch 只在对象当前的ETag等于 etag 时写入，用于小对象，内容会读入内存
+func (r2 *R2Client) PutIfMatch(ctx context.Context, key string, body io.Reader, etag string, opts PutOptions) (*ObjectInfo, error) {
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = getContentType(key)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("读取上传内容失败: %v", err)
+	}
+
+	output, err := r2.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(r2.config.BucketName),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+		Metadata:    opts.Metadata,
+		IfMatch:     aws.String(`"` + etag + `"`),
+	})
+	if err != nil {
+		if isPreconditionFailed(err) {
+			return nil, ErrPreconditionFailed
+		}
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("上传到R2失败: %v", err)
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         int64(len(data)),
+		ContentType:  contentType,
+		LastModified: time.Now(),
+		ETag:         strings.Trim(aws.ToString(output.ETag), `"`),
+		Metadata:     opts.Metadata,
+		URL:          r2.buildPublicURL(key),
+	}, nil
+}
+
+// Copy 在存储桶内复制对象，并替换为新的Content-Type和元数据
+// 未指定Content-Type时沿用源对象的Content-Type
+func (r2 *R2Client) Copy(ctx context.Context, srcKey, dstKey string, opts PutOptions) error {
+	contentType := opts.ContentType
+	if contentType == "" {
+		source, err := r2.Head(ctx, srcKey)
+		if err != nil {
+			return err
+		}
+		contentType = source.ContentType
+	}
+
+	_, err := r2.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String(r2.config.BucketName),
+		Key:               aws.String(dstKey),
+		CopySource:        aws.String(r2.config.BucketName + "/" + strings.ReplaceAll(url.PathEscape(srcKey), "%2F", "/")),
+		ContentType:       aws.String(contentType),
+		Metadata:          opts.Metadata,
+		MetadataDirective: types.MetadataDirectiveReplace,
+	})
+	if err != nil {
+		return fmt.Errorf("复制R2对象失败: %v", err)
+	}
+	return nil
+}
+
+// Get 从R2读取对象
+func (r2 *R2Client) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	output, err := r2.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r2.config.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("读取R2对象失败: %v", err)
+	}
+
+	return output.Body, &ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(output.ContentLength),
+		ContentType:  aws.ToString(output.ContentType),
+		LastModified: aws.ToTime(output.LastModified),
+		ETag:         strings.Trim(aws.ToString(output.ETag), `"`),
+		Metadata:     output.Metadata,
+		URL:          r2.buildPublicURL(key),
+	}, nil
+}
+
+// Head 获取R2对象信息
+func (r2 *R2Client) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	output, err := r2.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(r2.config.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("获取R2对象信息失败: %v", err)
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(output.ContentLength),
+		ContentType:  aws.ToString(output.ContentType),
+		LastModified: aws.ToTime(output.LastModified),
+		ETag:         strings.Trim(aws.ToString(output.ETag), `"`),
+		Metadata:     output.Metadata,
+		URL:          r2.buildPublicURL(key),
+	}, nil
+}
+
+// Delete 删除R2对象
+func (r2 *R2Client) Delete(ctx context.Context, key string) error {
+	_, err := r2.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(r2.config.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("删除R2对象失败: %v", err)
+	}
+	return nil
+}
+
+// DeleteObjects 批量删除R2对象，单次最多1000个
+func (r2 *R2Client) DeleteObjects(ctx context.Context, keys []string) (map[string]string, error) {
+	objects := make([]types.ObjectIdentifier, 0, len(keys))
+	for _, key := range keys {
+		objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+	}
+
+	output, err := r2.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(r2.config.BucketName),
+		Delete: &types.Delete{
+			Objects: objects,
+			Quiet:   aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("批量删除R2对象失败: %v", err)
+	}
+
+	failures := map[string]string{}
+	for _, deleteErr := range output.Errors {
+		failures[aws.ToString(deleteErr.Key)] = aws.ToString(deleteErr.Message)
+	}
+	return failures, nil
+}
+
+// List 列举R2对象，ListObjectsV2 不返回Content-Type，结果中 ContentType 为空
+func (r2 *R2Client) List(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(r2.config.BucketName),
+	}
+	if opts.Prefix != "" {
+		input.Prefix = aws.String(opts.Prefix)
+	}
+	if opts.Delimiter != "" {
+		input.Delimiter = aws.String(opts.Delimiter)
+	}
+	if opts.ContinuationToken != "" {
+		input.ContinuationToken = aws.String(opts.ContinuationToken)
+	}
+	if opts.MaxKeys > 0 {
+		input.MaxKeys = aws.Int32(int32(opts.MaxKeys))
+	}
+
+	output, err := r2.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("列举R2对象失败: %v", err)
+	}
+
+	result := &ListResult{
+		Objects:               make([]ObjectInfo, 0, len(output.Contents)),
+		NextContinuationToken: aws.ToString(output.NextContinuationToken),
+		IsTruncated:           aws.ToBool(output.IsTruncated),
+	}
+	for _, object := range output.Contents {
+		key := aws.ToString(object.Key)
+		result.Objects = append(result.Objects, ObjectInfo{
+			Key:          key,
+			Size:         aws.ToInt64(object.Size),
+			LastModified: aws.ToTime(object.LastModified),
+			ETag:         strings.Trim(aws.ToString(object.ETag), `"`),
+			URL:          r2.buildPublicURL(key),
+		})
+	}
+	for _, prefix := range output.CommonPrefixes {
+		result.CommonPrefixes = append(result.CommonPrefixes, aws.ToString(prefix.Prefix))
+	}
+
+	return result, nil
+}
+
+// PublicURL 返回对象的公开访问URL
+func (r2 *R2Client) PublicURL(key string) string {
+	return r2.buildPublicURL(key)
+}
+
+// buildPublicURL 构建公开访问URL
+func (r2 *R2Client) buildPublicURL(filename string) string {
+	// 首先检查是否配置了自定义公开域名
+	publicDomain := r2.config.PublicDomain
+	if publicDomain != "" {
+		return fmt.Sprintf("https://%s/%s", publicDomain, filename)
+	}
+
+	// 检查是否配置了R2的公开访问域名
+	r2PublicDomain := r2.config.DevDomain
+	if r2PublicDomain != "" {
+		// 如果已经包含 https://，直接使用；否则添加
+		if strings.HasPrefix(r2PublicDomain, "https://") {
+			return fmt.Sprintf("%s/%s", r2PublicDomain, filename)
+		}
+		return fmt.Sprintf("https://%s/%s", r2PublicDomain, filename)
+	}
+
+	// 如果都没有配置，返回预签名URL（7天有效期）
+	presignedURL, err := r2.GeneratePresignedURL(filename, 7*24*time.Hour)
+	if err != nil {
+		// 如果预签名URL也失败，返回默认格式（可能无法直接访问）
+		return fmt.Sprintf("https://%s.%s.r2.cloudflarestorage.com/%s",
+			r2.config.BucketName,
+			r2.config.AccountID,
+			filename)
+	}
+
+	return presignedURL
+}
+
+// GeneratePresignedURL 生成预签名URL用于访问
+func (r2 *R2Client) GeneratePresignedURL(key string, expiration time.Duration) (string, error) {
+	presignClient := s3.NewPresignClient(r2.client)
+
+	presignResult, err := presignClient.PresignGetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(r2.config.BucketName),
+		Key:    aws.String(key),
+	}, func(opts *s3.PresignOptions) {
+		opts.Expires = expiration
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("生成预签名URL失败: %v", err)
+	}
+
+	return presignResult.URL, nil
+}
+
+// envInt64 读取整数环境变量，未配置或无效时返回默认值
+func envInt64(name string, defaultValue int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(name), 10, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// PresignPut 生成预签名PUT上传地址，浏览器可直接上传到R2
+func (r2 *R2Client) PresignPut(ctx context.Context, key string, opts PresignOptions) (*PresignedPut, error) {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(r2.config.BucketName),
+		Key:         aws.String(key),
+		ContentType: aws.String(opts.ContentType),
+		Metadata:    opts.Metadata,
+	}
+	// 指定大小时将Content-Length纳入签名
+	if opts.Size > 0 {
+		input.ContentLength = aws.Int64(opts.Size)
+	}
+
+	presignClient := s3.NewPresignClient(r2.client)
+	presignResult, err := presignClient.PresignPutObject(ctx, input, func(o *s3.PresignOptions) {
+		o.Expires = opts.Expires
+	})
+	if err != nil {
+		return nil, fmt.Errorf("生成预签名上传地址失败: %v", err)
+	}
+
+	headers := make(map[string]string)
+	for name, values := range presignResult.SignedHeader {
+		if strings.EqualFold(name, "Host") || len(values) == 0 {
+			continue
+		}
+		headers[name] = values[0]
+	}
+
+	return &PresignedPut{
+		URL:     presignResult.URL,
+		Method:  presignResult.Method,
+		Headers: headers,
+	}, nil
+}
+
+// PresignPost 生成预签名POST表单上传策略，限制Content-Type和文件大小
+func (r2 *R2Client) PresignPost(ctx context.Context, key string, opts PresignOptions) (*PresignedPost, error) {
+	conditions := []interface{}{
+		map[string]string{"Content-Type": opts.ContentType},
+	}
+	if opts.MaxSize > 0 {
+		conditions = append(conditions, []interface{}{"content-length-range", 1, opts.MaxSize})
+	}
+	for name, value := range opts.Metadata {
+		conditions = append(conditions, map[string]string{"x-amz-meta-" + name: value})
+	}
+
+	presignClient := s3.NewPresignClient(r2.client)
+	presignResult, err := presignClient.PresignPostObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(r2.config.BucketName),
+		Key:    aws.String(key),
+	}, func(o *s3.PresignPostOptions) {
+		o.Expires = opts.Expires
+		o.Conditions = conditions
+	})
+	if err != nil {
+		return nil, fmt.Errorf("生成预签名上传策略失败: %v", err)
+	}
+
+	fields := presignResult.Values
+	fields["Content-Type"] = opts.ContentType
+	for name, value := range opts.Metadata {
+		fields["x-amz-meta-"+name] = value
+	}
+
+	return &PresignedPost{
+		URL:    presignResult.URL,
+		Fields: fields,
+	}, nil
+}
+
+// isNotFound 判断S3错误是否表示对象不存在
+func isNotFound(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NotFound", "NoSuchKey":
+			return true
+		}
+	}
+	return false
+}
+
+// isPreconditionFailed 判断S3错误是否表示条件写入的条件不满足
+func isPreconditionFailed(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "PreconditionFailed", "ConditionalRequestConflict":
+			return true
+		}
+	}
+	return false
+}
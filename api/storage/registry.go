@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"go-api/api/lazy"
+	"log"
+	"os"
+	"strings"
+)
+
+// 各存储后端必需的环境变量
+var requiredEnv = map[string][]string{
+	BackendR2:    {"R2_ACCOUNT_ID", "R2_ACCESS_KEY_ID", "R2_SECRET_ACCESS_KEY", "R2_BUCKET_NAME"},
+	BackendBlob:  {"BLOB_READ_WRITE_TOKEN"},
+	BackendLocal: {},
+}
+
+// defaultStore 进程级共享的存储客户端
+var defaultStore lazy.Value[ObjectStore]
+
+// ConfigReport 存储配置检查结果
+type ConfigReport struct {
+	Backend string   `json:"backend"`
+	Missing []string `json:"missing,omitempty"` // 缺少的环境变量
+	Errors  []string `json:"errors,omitempty"`  // 其他配置错误
+}
+
+// OK 配置是否有效
+func (r ConfigReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Errors) == 0
+}
+
+// String 返回可读的检查结果
+func (r ConfigReport) String() string {
+	if r.OK() {
+		return fmt.Sprintf("存储后端 %s 配置有效", r.Backend)
+	}
+	var parts []string
+	if len(r.Missing) > 0 {
+		parts = append(parts, "缺少环境变量: "+strings.Join(r.Missing, ", "))
+	}
+	parts = append(parts, r.Errors...)
+	return fmt.Sprintf("存储后端 %s 配置无效，%s", r.Backend, strings.Join(parts, "；"))
+}
+
+// Backend 返回当前配置的存储后端名称
+func Backend() string {
+	backend := strings.ToLower(strings.TrimSpace(os.Getenv("STORAGE_BACKEND")))
+	if backend == "" {
+		return BackendR2
+	}
+	return backend
+}
+
+// ValidateConfig 检查存储相关配置，不创建任何客户端
+func ValidateConfig() ConfigReport {
+	report := ConfigReport{Backend: Backend()}
+
+	required, ok := requiredEnv[report.Backend]
+	if !ok {
+		report.Errors = append(report.Errors, fmt.Sprintf("不支持的存储后端: %s", report.Backend))
+	}
+	for _, name := range required {
+		if os.Getenv(name) == "" {
+			report.Missing = append(report.Missing, name)
+		}
+	}
+
+	for _, source := range []string{SourceUpload, SourceRemoveBackground, SourceGeneration} {
+		if _, err := BuildKey(KeyTemplateFor(source), KeyParams{Source: source, Filename: "check.png"}); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s 对象键模板无效: %v", source, err))
+		}
+	}
+
+	return report
+}
+
+// Init 在启动时检查配置并创建共享存储客户端，检查结果写入日志
+// 重复调用不会重新创建客户端
+func Init() ConfigReport {
+	report := ValidateConfig()
+	if report.OK() {
+		log.Println(report.String())
+	} else {
+		log.Println("⚠️ " + report.String())
+	}
+
+	Default()
+	return report
+}
+
+// Default 返回共享的存储客户端，首次调用时按环境变量创建，创建失败时下次调用重试
+func Default() (ObjectStore, error) {
+	return defaultStore.Get(func() (ObjectStore, error) {
+		store, err := NewObjectStore()
+		if err != nil {
+			// 优先返回配置检查结果，明确列出缺少的环境变量
+			if report := ValidateConfig(); !report.OK() {
+				err = errors.New(report.String())
+			}
+		}
+		return store, err
+	})
+}
+
+// SetDefault 替换共享存储客户端，测试时可注入本地存储
+func SetDefault(store ObjectStore) {
+	defaultStore.Set(store)
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// SaveOptions 写入选项
+type SaveOptions struct {
+	PutOptions
+	Key      string        // 未启用内容寻址时使用的对象键
+	Filename string        // 原文件名，内容寻址模式下用于保留扩展名
+	TTL      time.Duration // 大于0时对象到期后由清理任务删除
+	FixedKey bool          // 为 true 时始终使用 Key，不进行内容寻址，用于派生对象
+}
+
+// Save 写入对象的统一入口
+// 启用内容寻址时按SHA-256去重，设置TTL时记录过期时间并写入过期索引。
+// 未启用内容寻址时先计算SHA-256再随对象一次写入元数据：body 可随机读取时读取两遍，否则先缓存到临时文件
+func Save(ctx context.Context, store ObjectStore, body io.Reader, opts SaveOptions) (*ObjectInfo, error) {
+	putOpts := opts.PutOptions
+	var expiresAt time.Time
+	if opts.TTL > 0 {
+		expiresAt = time.Now().Add(opts.TTL).UTC()
+		putOpts.Metadata = mergeMetadata(putOpts.Metadata, map[string]string{
+			MetaExpiresAt: expiresAt.Format(time.RFC3339),
+		})
+	}
+
+	var info *ObjectInfo
+	var err error
+	if ContentAddressingEnabled() && !opts.FixedKey {
+		info, _, err = PutDeduplicatedStream(ctx, store, body, opts.Filename, putOpts)
+	} else {
+		var sum string
+		var cleanup func()
+		body, sum, cleanup, err = hashBody(body)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		putOpts.Metadata = mergeMetadata(putOpts.Metadata, map[string]string{MetaSHA256: sum})
+		info, err = store.Put(ctx, opts.Key, body, putOpts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.TTL > 0 {
+		if err := RecordExpiry(ctx, store, info.Key, expiresAt); err != nil {
+			return nil, err
+		}
+	}
+	return withURL(store, info), nil
+}
+
+// hashBody 计算内容的SHA-256并返回从头读取内容的 Reader，cleanup 用于删除临时文件
+// 可随机读取的内容读取后回到原位置，其他内容缓存到临时文件
+func hashBody(body io.Reader) (io.Reader, string, func(), error) {
+	hasher := sha256.New()
+	if seeker, ok := body.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("读取上传内容失败: %v", err)
+		}
+		if _, err := io.Copy(hasher, seeker); err != nil {
+			return nil, "", nil, err
+		}
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, "", nil, fmt.Errorf("读取上传内容失败: %v", err)
+		}
+		return seeker, hex.EncodeToString(hasher.Sum(nil)), func() {}, nil
+	}
+
+	tempFile, err := os.CreateTemp("", "save-*.tmp")
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	cleanup := func() {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+	}
+	size, err := io.Copy(io.MultiWriter(tempFile, hasher), body)
+	if err != nil {
+		cleanup()
+		return nil, "", nil, err
+	}
+	return io.NewSectionReader(tempFile, 0, size), hex.EncodeToString(hasher.Sum(nil)), cleanup, nil
+}
+
+// mergeMetadata 合并元数据，后者覆盖前者
+func mergeMetadata(base, extra map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(extra))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
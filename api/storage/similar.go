@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-api/api/imaging"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PHashIndexPrefix 感知哈希索引目录，与图片保存在同一个存储中，所有实例共享
+// 每个对象按哈希的8个字节各写一条空标记 _index/phash/bands/<字节序号>/<字节值>/<哈希>/<对象键>，
+// 并写入 _index/phash/objects/<对象键>（内容为哈希）用于按对象键查询和删除
+const PHashIndexPrefix = "_index/phash/"
+
+// MaxSimilarDistance 索引可查询的最大汉明距离，距离不超过7的两个64位哈希至少有一个字节相同
+const MaxSimilarDistance = 7
+
+// phashBands 哈希分段数
+const phashBands = 8
+
+// SimilarObject 相似图片
+type SimilarObject struct {
+	Key      string `json:"key"`
+	URL      string `json:"url"`
+	Hash     string `json:"hash"`
+	Distance int    `json:"distance"` // 与查询哈希的汉明距离
+}
+
+// IndexPHash 写入对象的感知哈希索引，对象已有索引时替换
+// 先写入按对象键查询的记录，分段标记写入失败时仍可通过 RemovePHash 清理
+func IndexPHash(ctx context.Context, store ObjectStore, key string, hash uint64) error {
+	if err := RemovePHash(ctx, store, key); err != nil {
+		return err
+	}
+	opts := PutOptions{ContentType: "text/plain"}
+	if _, err := store.Put(ctx, phashObjectKey(key), strings.NewReader(imaging.FormatHash(hash)), opts); err != nil {
+		return fmt.Errorf("写入感知哈希索引失败: %v", err)
+	}
+
+	// 分段标记互不依赖，并发写入
+	var wg sync.WaitGroup
+	errs := make([]error, phashBands)
+	for band := 0; band < phashBands; band++ {
+		wg.Add(1)
+		go func(band int) {
+			defer wg.Done()
+			_, errs[band] = store.Put(ctx, phashBandKey(band, hash, key), strings.NewReader(""), opts)
+		}(band)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("写入感知哈希索引失败: %v", err)
+	}
+	return nil
+}
+
+// LookupPHash 读取对象已索引的感知哈希，未索引时返回 ErrNotFound
+func LookupPHash(ctx context.Context, store ObjectStore, key string) (uint64, error) {
+	body, _, err := store.Get(ctx, phashObjectKey(key))
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, 64))
+	if err != nil {
+		return 0, fmt.Errorf("读取感知哈希索引失败: %v", err)
+	}
+	return imaging.ParseHash(strings.TrimSpace(string(data)))
+}
+
+// RemovePHash 删除对象的感知哈希索引，未索引时不报错
+func RemovePHash(ctx context.Context, store ObjectStore, key string) error {
+	hash, err := LookupPHash(ctx, store, key)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// 先删除分段标记，最后删除记录，删除失败时可以重试；已不存在的标记视为删除成功
+	var wg sync.WaitGroup
+	errs := make([]error, phashBands)
+	for band := 0; band < phashBands; band++ {
+		wg.Add(1)
+		go func(band int) {
+			defer wg.Done()
+			if err := store.Delete(ctx, phashBandKey(band, hash, key)); err != nil && !errors.Is(err, ErrNotFound) {
+				errs[band] = err
+			}
+		}(band)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("删除感知哈希索引失败: %v", err)
+	}
+	if err := store.Delete(ctx, phashObjectKey(key)); err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("删除感知哈希索引失败: %v", err)
+	}
+	return nil
+}
+
+// removePHashes 删除对象后清理感知哈希索引，失败只记录日志，不影响其他对象
+func removePHashes(ctx context.Context, store ObjectStore, keys []string) {
+	for _, key := range keys {
+		if err := RemovePHash(ctx, store, key); err != nil {
+			log.Printf("删除感知哈希索引失败: %s: %v", key, err)
+		}
+	}
+}
+
+// FindSimilar 查找与 hash 的汉明距离不超过 maxDistance 的对象，按距离排序，exclude 为需要排除的对象键
+// maxDistance 超过 MaxSimilarDistance 时按 MaxSimilarDistance 查询
+// 相似哈希至少有一个字节相同，只需列举8个分段目录；哈希记录在标记键中，不需要读取对象
+// 只查询索引，不检查对象是否存在；通过接口删除和过期清理的对象会同时删除索引
+func FindSimilar(ctx context.Context, store ObjectStore, hash uint64, maxDistance int, exclude string) ([]SimilarObject, error) {
+	maxDistance = min(maxDistance, MaxSimilarDistance)
+
+	candidates := map[string]uint64{}
+	for band := 0; band < phashBands; band++ {
+		prefix := phashBandPrefix(band, hash)
+		markers, err := listKeys(ctx, store, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("读取感知哈希索引失败: %v", err)
+		}
+		for _, marker := range markers {
+			hashText, key, ok := strings.Cut(strings.TrimPrefix(marker, prefix), "/")
+			if !ok || key == "" || key == exclude {
+				continue
+			}
+			candidate, err := imaging.ParseHash(hashText)
+			if err != nil || imaging.HashDistance(hash, candidate) > maxDistance {
+				continue
+			}
+			candidates[key] = candidate
+		}
+	}
+
+	similar := []SimilarObject{}
+	for key, candidate := range candidates {
+		similar = append(similar, SimilarObject{
+			Key:      key,
+			URL:      store.PublicURL(key),
+			Hash:     imaging.FormatHash(candidate),
+			Distance: imaging.HashDistance(hash, candidate),
+		})
+	}
+	sort.Slice(similar, func(i, j int) bool {
+		if similar[i].Distance != similar[j].Distance {
+			return similar[i].Distance < similar[j].Distance
+		}
+		return similar[i].Key < similar[j].Key
+	})
+	return similar, nil
+}
+
+// phashObjectKey 返回按对象键查询哈希的索引键
+func phashObjectKey(key string) string {
+	return PHashIndexPrefix + "objects/" + key
+}
+
+// phashBandPrefix 返回哈希第 band 个字节所在的分段目录
+func phashBandPrefix(band int, hash uint64) string {
+	value := byte(hash >> (8 * (phashBands - 1 - band)))
+	return fmt.Sprintf("%sbands/%d/%02x/", PHashIndexPrefix, band, value)
+}
+
+// phashBandKey 返回对象在第 band 个分段中的标记键
+func phashBandKey(band int, hash uint64, key string) string {
+	return phashBandPrefix(band, hash) + imaging.FormatHash(hash) + "/" + key
+}
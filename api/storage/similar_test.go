@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// failingGetStore 读取指定键时返回错误，用于测试索引清理的失败处理
+type failingGetStore struct {
+	ObjectStore
+	failKey string
+}
+
+func (s failingGetStore) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	if key == s.failKey {
+		return nil, nil, errors.New("read failed")
+	}
+	return s.ObjectStore.Get(ctx, key)
+}
+
+func TestFindSimilar(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	const hash = 0x802a7f2a6f2a7f2a
+
+	objects := map[string]uint64{
+		"same.png":  hash,
+		"near.png":  hash ^ 0b11,             // 距离2
+		"far.png":   hash ^ 0xff00ff00ff00ff, // 距离32，有相同的字节但超出距离
+		"other.png": ^uint64(hash),
+	}
+	for key, h := range objects {
+		if err := IndexPHash(ctx, store, key, h); err != nil {
+			t.Fatalf("IndexPHash(%s): %v", key, err)
+		}
+	}
+
+	similar, err := FindSimilar(ctx, store, hash, 5, "same.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(similar) != 1 || similar[0].Key != "near.png" || similar[0].Distance != 2 {
+		t.Fatalf("FindSimilar() = %+v, want only near.png at distance 2", similar)
+	}
+
+	got, err := LookupPHash(ctx, store, "near.png")
+	if err != nil || got != hash^0b11 {
+		t.Errorf("LookupPHash() = %x, %v", got, err)
+	}
+	if _, err := LookupPHash(ctx, store, "missing.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("LookupPHash(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestIndexPHashReplaces(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	const hash = 0x0123456789abcdef
+
+	if err := IndexPHash(ctx, store, "a.png", ^uint64(hash)); err != nil {
+		t.Fatal(err)
+	}
+	if err := IndexPHash(ctx, store, "a.png", hash); err != nil {
+		t.Fatal(err)
+	}
+
+	if similar, _ := FindSimilar(ctx, store, ^uint64(hash), MaxSimilarDistance, ""); len(similar) != 0 {
+		t.Errorf("old hash still indexed: %+v", similar)
+	}
+	if similar, _ := FindSimilar(ctx, store, hash, 0, ""); len(similar) != 1 {
+		t.Errorf("new hash not indexed: %+v", similar)
+	}
+}
+
+func TestRemovePHash(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	const hash = 0x0123456789abcdef
+
+	if err := IndexPHash(ctx, store, "a.png", hash); err != nil {
+		t.Fatal(err)
+	}
+	// 部分标记已不存在时仍能删除
+	if err := store.Delete(ctx, phashBandKey(3, hash, "a.png")); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemovePHash(ctx, store, "a.png"); err != nil {
+		t.Fatalf("RemovePHash() error = %v", err)
+	}
+	if err := RemovePHash(ctx, store, "a.png"); err != nil {
+		t.Errorf("RemovePHash() on unindexed key error = %v", err)
+	}
+
+	keys, err := listKeys(ctx, store, PHashIndexPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("index keys left: %v", keys)
+	}
+}
+
+func TestRemovePHashesContinuesAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	local := newTestStore(t)
+	for _, key := range []string{"a.png", "b.png", "c.png"} {
+		if err := IndexPHash(ctx, local, key, 0x0123456789abcdef); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := failingGetStore{ObjectStore: local, failKey: phashObjectKey("a.png")}
+	removePHashes(ctx, store, []string{"a.png", "b.png", "c.png"})
+
+	if _, err := LookupPHash(ctx, local, "a.png"); err != nil {
+		t.Errorf("a.png index should be kept after failure: %v", err)
+	}
+	for _, key := range []string{"b.png", "c.png"} {
+		if _, err := LookupPHash(ctx, local, key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s index not removed: %v", key, err)
+		}
+	}
+}
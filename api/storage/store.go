@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-api/api/imaging"
+	"io"
+	"time"
+)
+
+// 存储后端名称
+const (
+	BackendR2    = "r2"
+	BackendBlob  = "blob"
+	BackendLocal = "local"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// ErrPreconditionFailed 条件写入时对象已被其他请求修改
+var ErrPreconditionFailed = errors.New("对象已被修改")
+
+// ObjectInfo 对象信息
+type ObjectInfo struct {
+	Key          string            `json:"key"`
+	Size         int64             `json:"size"`
+	ContentType  string            `json:"contentType,omitempty"`
+	LastModified time.Time         `json:"lastModified,omitempty"`
+	ETag         string            `json:"etag,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	URL          string            `json:"url,omitempty"`
+}
+
+// PutOptions 上传选项
+type PutOptions struct {
+	ContentType string            // 为空时根据扩展名推断
+	Metadata    map[string]string // 用户自定义元数据
+}
+
+// ListOptions 列举选项
+type ListOptions struct {
+	Prefix            string // 键前缀
+	Delimiter         string // 分隔符，用于按"目录"聚合
+	ContinuationToken string // 分页令牌
+	MaxKeys           int    // 单页最大数量
+}
+
+// ListResult 列举结果
+type ListResult struct {
+	Objects               []ObjectInfo `json:"objects"`
+	CommonPrefixes        []string     `json:"commonPrefixes,omitempty"`
+	NextContinuationToken string       `json:"nextContinuationToken,omitempty"`
+	IsTruncated           bool         `json:"isTruncated"`
+}
+
+// ObjectStore 对象存储统一接口
+type ObjectStore interface {
+	// Put 写入对象，返回写入后的对象信息（包含访问URL）
+	Put(ctx context.Context, key string, body io.Reader, opts PutOptions) (*ObjectInfo, error)
+	// Get 读取对象内容，调用方负责关闭返回的 ReadCloser
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	// Head 获取对象信息，对象不存在时返回 ErrNotFound
+	Head(ctx context.Context, key string) (*ObjectInfo, error)
+	// Delete 删除对象
+	Delete(ctx context.Context, key string) error
+	// List 列举对象
+	List(ctx context.Context, opts ListOptions) (*ListResult, error)
+	// PublicURL 返回对象的访问URL
+	PublicURL(key string) string
+}
+
+// Copier 支持服务端复制的存储后端
+type Copier interface {
+	// Copy 复制对象，目标对象使用 opts 中的Content-Type和元数据，未指定Content-Type时沿用源对象的
+	Copy(ctx context.Context, srcKey, dstKey string, opts PutOptions) error
+}
+
+// ConditionalPutter 支持条件写入的存储后端
+type ConditionalPutter interface {
+	// PutIfMatch 只在对象当前的ETag等于 etag 时写入，条件不满足时返回 ErrPreconditionFailed
+	PutIfMatch(ctx context.Context, key string, body io.Reader, etag string, opts PutOptions) (*ObjectInfo, error)
+}
+
+// PutIfMatch 只在对象未被修改时写入，version 为之前读取的对象信息
+// 后端不支持条件写入时先读取对象信息比较版本再写入，两步之间的并发写入无法检测
+func PutIfMatch(ctx context.Context, store ObjectStore, key string, body io.Reader, version *ObjectInfo, opts PutOptions) (*ObjectInfo, error) {
+	if putter, ok := store.(ConditionalPutter); ok && version.ETag != "" {
+		return putter.PutIfMatch(ctx, key, body, version.ETag, opts)
+	}
+
+	current, err := store.Head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !sameVersion(current, version) {
+		return nil, ErrPreconditionFailed
+	}
+	if _, err := store.Put(ctx, key, body, opts); err != nil {
+		return nil, err
+	}
+	// 返回写入后后端记录的版本，供下一次条件写入比较
+	return store.Head(ctx, key)
+}
+
+// sameVersion 比较两次读取的对象信息是否为同一版本，没有ETag时比较修改时间和大小
+func sameVersion(a, b *ObjectInfo) bool {
+	if a.ETag != "" || b.ETag != "" {
+		return a.ETag == b.ETag
+	}
+	return a.LastModified.Equal(b.LastModified) && a.Size == b.Size
+}
+
+// KeepsMetadata 存储后端是否保存 PutOptions.Metadata，后端可实现 KeepsMetadata() bool 声明不保存
+func KeepsMetadata(store ObjectStore) bool {
+	if s, ok := store.(interface{ KeepsMetadata() bool }); ok {
+		return s.KeepsMetadata()
+	}
+	return true
+}
+
+// NewObjectStore 根据 STORAGE_BACKEND 环境变量创建存储后端，默认使用R2
+// 处理请求时应使用 Default 获取共享客户端
+func NewObjectStore() (ObjectStore, error) {
+	backend := Backend()
+	switch backend {
+	case BackendR2:
+		return NewR2Client()
+	case BackendBlob:
+		return NewBlobStore()
+	case BackendLocal:
+		return NewLocalStore()
+	default:
+		return nil, fmt.Errorf("不支持的存储后端: %s", backend)
+	}
+}
+
+// getContentType 根据文件扩展名获取Content-Type，无法识别时返回 application/octet-stream
+// 写入前应尽量通过 imaging.NewReader 识别实际格式并在 PutOptions 中指定
+func getContentType(filename string) string {
+	if format, ok := imaging.ByExtension(filename); ok {
+		return format.ContentType
+	}
+	return "application/octet-stream"
+}
+
+// countingReader 统计已读取字节数的Reader
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+// Read 读取数据并累加字节数
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// newID 生成随机ID
+func newID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		// 系统随机数源不可用时无法安全生成ID
+		panic(fmt.Sprintf("生成随机数失败: %v", err))
+	}
+	return hex.EncodeToString(buf)
+}
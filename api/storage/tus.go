@@ -0,0 +1,21 @@
+package storage
+
+import "fmt"
+
+// TusPrefix 可续传上传的状态和分片目录
+// 状态键为 _tus/<上传ID>/info.json，分片键为 _tus/<上传ID>/<20位起始偏移>-<写入令牌>
+const TusPrefix = "_tus/"
+
+// TusInfoKey 返回可续传上传的状态键
+func TusInfoKey(id string) string {
+	return TusPrefix + id + "/info.json"
+}
+
+// TusChunkKey 返回从 offset 开始的分片键，token 区分同一偏移处的并发写入，为空时不加后缀
+func TusChunkKey(id string, offset int64, token string) string {
+	key := fmt.Sprintf("%s%s/%020d", TusPrefix, id, offset)
+	if token != "" {
+		key += "-" + token
+	}
+	return key
+}
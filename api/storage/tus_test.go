@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestTusChunkKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		offset int64
+		token  string
+		want   string
+	}{
+		{"first chunk", "abc", 0, "", "_tus/abc/00000000000000000000"},
+		{"with token", "abc", 1024, "t1", "_tus/abc/00000000000000001024-t1"},
+		{"large offset", "abc", 1 << 40, "", "_tus/abc/00000001099511627776"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TusChunkKey(tt.id, tt.offset, tt.token); got != tt.want {
+				t.Errorf("TusChunkKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// 偏移补零到20位，分片键的字典序与偏移顺序一致
+func TestTusChunkKeyOrder(t *testing.T) {
+	offsets := []int64{0, 9, 10, 100, 5 << 20, 1 << 40}
+	keys := make([]string, len(offsets))
+	for i, offset := range offsets {
+		keys[i] = TusChunkKey("id", offset, "tok")
+	}
+	if !sort.StringsAreSorted(keys) {
+		t.Errorf("chunk keys not sorted by offset: %v", keys)
+	}
+	if TusInfoKey("id") != "_tus/id/info.json" {
+		t.Errorf("TusInfoKey() = %q", TusInfoKey("id"))
+	}
+}
@@ -0,0 +1,109 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// taskBucket 保存任务记录的bucket，键为任务ID，值为JSON
+var taskBucket = []byte("tasks")
+
+// BoltStore 基于BoltDB的任务存储，数据保存在单个文件中
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore 打开或创建BoltDB文件，文件被其他进程占用时1秒后返回错误
+func NewBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("创建任务数据库目录失败: %v", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("打开任务数据库失败: %v", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(taskBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化任务数据库失败: %v", err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Create 写入新记录
+func (s *BoltStore) Create(ctx context.Context, record *Record) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(taskBucket)
+		if bucket.Get([]byte(record.ID)) != nil {
+			return ErrExists
+		}
+		return putRecord(bucket, record)
+	})
+}
+
+// Get 读取记录
+func (s *BoltStore) Get(ctx context.Context, id string) (*Record, error) {
+	var record *Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		record, err = getRecord(tx.Bucket(taskBucket), id)
+		return err
+	})
+	return record, err
+}
+
+// Update 在写事务中读取、修改并写回记录
+func (s *BoltStore) Update(ctx context.Context, id string, fn func(*Record) error) (*Record, error) {
+	var record *Record
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(taskBucket)
+		var err error
+		if record, err = getRecord(bucket, id); err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+		return putRecord(bucket, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Close 关闭数据库文件
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// getRecord 读取并解析记录
+func getRecord(bucket *bolt.Bucket, id string) (*Record, error) {
+	data := bucket.Get([]byte(id))
+	if data == nil {
+		return nil, ErrNotFound
+	}
+	var record Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("解析任务记录失败: %v", err)
+	}
+	return &record, nil
+}
+
+// putRecord 序列化并写入记录
+func putRecord(bucket *bolt.Bucket, record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("序列化任务记录失败: %v", err)
+	}
+	return bucket.Put([]byte(record.ID), data)
+}
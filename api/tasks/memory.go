@@ -0,0 +1,84 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// MemoryStore 进程内的任务存储，重启后丢失，用于测试和离线开发
+// 保存序列化后的记录，调用方修改返回的记录不影响已保存的内容
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string][]byte
+}
+
+// NewMemoryStore 创建进程内任务存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[string][]byte{}}
+}
+
+// Create 写入新记录
+func (s *MemoryStore) Create(ctx context.Context, record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.records[record.ID]; ok {
+		return ErrExists
+	}
+	return s.put(record)
+}
+
+// Get 读取记录
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.get(id)
+}
+
+// Update 加锁后读取、修改并写回记录
+func (s *MemoryStore) Update(ctx context.Context, id string, fn func(*Record) error) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(record); err != nil {
+		return nil, err
+	}
+	if err := s.put(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Close 无需释放资源
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// get 读取并解析记录，调用方需持有锁
+func (s *MemoryStore) get(id string) (*Record, error) {
+	data, ok := s.records[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var record Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// put 序列化并保存记录，调用方需持有锁
+func (s *MemoryStore) put(record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.records[record.ID] = data
+	return nil
+}
@@ -0,0 +1,218 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-api/api/env"
+	"go-api/api/generator"
+	"go-api/api/lazy"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 任务存储后端名称
+const (
+	BackendBolt   = "bolt"
+	BackendMemory = "memory"
+)
+
+var (
+	// ErrNotFound 任务记录不存在
+	ErrNotFound = errors.New("任务记录不存在")
+	// ErrExists 任务记录已存在
+	ErrExists = errors.New("任务记录已存在")
+)
+
+// Record 生成任务记录，生成服务清除任务后仍可查询
+type Record struct {
+	ID           string       `json:"taskId"`
+	Provider     string       `json:"provider"` // 生成服务名称
+	Prompt       string       `json:"prompt"`
+	Size         string       `json:"size"`
+	ImageURLs    []string     `json:"imageUrls"`
+	CallbackURL  string       `json:"callbackUrl,omitempty"` // 提交者的回调地址
+	Caller       string       `json:"caller"`                // 提交者，取自 X-Uploader 请求头或客户端IP
+	Status       string       `json:"status"`                // 见 generator.Status* 常量
+	Progress     float64      `json:"progress"`
+	ResultURLs   []string     `json:"resultUrls,omitempty"`   // 生成服务提供的结果地址，可能会过期
+	HostedURLs   []string     `json:"hostedUrls,omitempty"`   // 保存到存储后端后的永久地址，与 ResultURLs 一一对应
+	HostError    string       `json:"hostError,omitempty"`    // 最近一次保存结果失败的原因
+	HostingAt    *time.Time   `json:"hostingAt,omitempty"`    // 最近一次开始保存结果的时间，保存期间其他请求不再重复保存
+	HostAttempts int          `json:"hostAttempts,omitempty"` // 已尝试保存结果的次数
+	Error        string       `json:"error,omitempty"`
+	CreatedAt    time.Time    `json:"createdAt"`
+	UpdatedAt    time.Time    `json:"updatedAt"`
+	CompletedAt  *time.Time   `json:"completedAt,omitempty"`
+	NotifiedAt   *time.Time   `json:"notifiedAt,omitempty"` // 通知提交者回调地址的时间
+	DeliveryID   string       `json:"deliveryId,omitempty"` // 对应的webhook投递记录
+	History      []Transition `json:"history"`              // 状态变化记录
+}
+
+// Transition 一次状态变化
+type Transition struct {
+	Status string    `json:"status"`
+	At     time.Time `json:"at"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// NewRecord 根据提交的请求和生成服务返回的任务创建记录
+func NewRecord(provider string, req generator.Request, task *generator.Task, caller string) *Record {
+	now := time.Now().UTC()
+	record := &Record{
+		ID:          task.ID,
+		Provider:    provider,
+		Prompt:      req.Prompt,
+		Size:        req.Size,
+		ImageURLs:   req.ImageURLs,
+		CallbackURL: req.CallbackURL,
+		Caller:      caller,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	record.Apply(task)
+	return record
+}
+
+// Apply 按生成服务返回的任务状态更新记录，状态变化时追加到 History
+// 已结束的任务不再更新，返回记录是否有变化
+func (r *Record) Apply(task *generator.Task) bool {
+	if r.Done() || task.Status == "" {
+		return false
+	}
+
+	changed := false
+	now := time.Now().UTC()
+	if task.Status != r.Status {
+		r.Status = task.Status
+		r.History = append(r.History, Transition{Status: task.Status, At: now, Error: task.Error})
+		changed = true
+	}
+	if task.Progress != r.Progress {
+		r.Progress = task.Progress
+		changed = true
+	}
+	if len(task.ResultURLs) > 0 && !slices.Equal(task.ResultURLs, r.ResultURLs) {
+		r.ResultURLs = append([]string(nil), task.ResultURLs...)
+		changed = true
+	}
+	if task.Error != r.Error {
+		r.Error = task.Error
+		changed = true
+	}
+	if r.Done() {
+		completedAt := task.CompletedAt
+		if completedAt.IsZero() {
+			completedAt = now
+		}
+		r.CompletedAt = &completedAt
+	}
+	if changed {
+		r.UpdatedAt = now
+	}
+	return changed
+}
+
+// Done 任务是否已结束
+func (r *Record) Done() bool {
+	return r.Status == generator.StatusSucceeded || r.Status == generator.StatusFailed || r.Status == generator.StatusCanceled
+}
+
+// NeedsHosting 任务已成功但结果尚未保存到存储后端
+func (r *Record) NeedsHosting() bool {
+	return r.Status == generator.StatusSucceeded && len(r.ResultURLs) > 0 && len(r.HostedURLs) == 0
+}
+
+// Task 转换为生成服务的任务状态，用于按原有格式响应
+// 结果已保存到存储后端时返回永久地址
+func (r *Record) Task() *generator.Task {
+	resultURLs := r.ResultURLs
+	if len(r.HostedURLs) > 0 {
+		resultURLs = r.HostedURLs
+	}
+	task := &generator.Task{
+		ID:         r.ID,
+		Status:     r.Status,
+		Progress:   r.Progress,
+		ResultURLs: resultURLs,
+		Error:      r.Error,
+		CreatedAt:  r.CreatedAt,
+	}
+	if r.CompletedAt != nil {
+		task.CompletedAt = *r.CompletedAt
+	}
+	return task
+}
+
+// Store 任务记录存储
+type Store interface {
+	// Create 写入新记录，ID已存在时返回 ErrExists
+	Create(ctx context.Context, record *Record) error
+	// Get 读取记录，不存在时返回 ErrNotFound
+	Get(ctx context.Context, id string) (*Record, error)
+	// Update 在同一事务中读取、修改并写回记录，fn 返回错误时不写入
+	Update(ctx context.Context, id string, fn func(*Record) error) (*Record, error)
+	// Close 关闭存储
+	Close() error
+}
+
+// defaultStore 进程级共享的任务存储
+var defaultStore lazy.Value[Store]
+
+// Backend 返回 TASK_STORE 配置的存储后端名称，默认使用BoltDB
+func Backend() string {
+	backend := strings.ToLower(strings.TrimSpace(os.Getenv("TASK_STORE")))
+	if backend == "" {
+		return BackendBolt
+	}
+	return backend
+}
+
+// DBPath 返回 TASK_DB_PATH 配置的BoltDB文件路径，开发环境未配置时位于临时目录，生产环境必须配置
+func DBPath() (string, error) {
+	return env.DataPath("TASK_DB_PATH", "go-api-tasks.db")
+}
+
+// New 根据 TASK_STORE 环境变量创建任务存储
+// 处理请求时应使用 Default 获取共享存储
+func New() (Store, error) {
+	backend := Backend()
+	switch backend {
+	case BackendBolt:
+		path, err := DBPath()
+		if err != nil {
+			return nil, err
+		}
+		store, err := NewBoltStore(path)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("不支持的任务存储: %s", backend)
+	}
+}
+
+// Init 在启动时创建共享任务存储，失败时写入日志，提交任务仍可进行但不会保存记录
+func Init() error {
+	if _, err := Default(); err != nil {
+		log.Println("⚠️ 任务存储不可用: " + err.Error())
+		return err
+	}
+	return nil
+}
+
+// Default 返回共享的任务存储，数据库文件打开失败（如被其他进程占用）时下次调用重试
+func Default() (Store, error) {
+	return defaultStore.Get(New)
+}
+
+// SetDefault 替换共享任务存储
+func SetDefault(store Store) {
+	defaultStore.Set(store)
+}
@@ -0,0 +1,185 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"go-api/api/generator"
+	"path/filepath"
+	"testing"
+)
+
+// testStores 返回需要测试的各存储后端
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bolt.Close() })
+	return map[string]Store{
+		BackendMemory: NewMemoryStore(),
+		BackendBolt:   bolt,
+	}
+}
+
+// newTestRecord 返回刚提交的任务记录
+func newTestRecord(id string) *Record {
+	req := generator.Request{
+		ImageURLs:   []string{"https://example.com/in.png"},
+		Prompt:      "a cat",
+		Size:        "1:1",
+		CallbackURL: "https://example.com/callback",
+	}
+	return NewRecord(generator.ProviderMock, req, &generator.Task{ID: id, Status: generator.StatusGenerating}, "alice")
+}
+
+func TestStore(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			if err := store.Create(ctx, newTestRecord("t1")); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Create(ctx, newTestRecord("t1")); !errors.Is(err, ErrExists) {
+				t.Errorf("duplicate Create() error = %v, want ErrExists", err)
+			}
+			if _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+			}
+
+			record, err := store.Get(ctx, "t1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record.Prompt != "a cat" || record.Caller != "alice" || len(record.ImageURLs) != 1 || record.Status != generator.StatusGenerating {
+				t.Errorf("Get() = %+v", record)
+			}
+
+			// 修改返回的记录不影响已保存的内容
+			record.Prompt = "changed"
+			if again, _ := store.Get(ctx, "t1"); again.Prompt != "a cat" {
+				t.Errorf("stored record modified: %q", again.Prompt)
+			}
+
+			updated, err := store.Update(ctx, "t1", func(record *Record) error {
+				record.Apply(&generator.Task{Status: generator.StatusSucceeded, ResultURLs: []string{"https://example.com/r.png"}})
+				return nil
+			})
+			if err != nil || !updated.Done() {
+				t.Fatalf("Update() = %+v, %v", updated, err)
+			}
+			if stored, _ := store.Get(ctx, "t1"); len(stored.ResultURLs) != 1 || stored.CompletedAt == nil {
+				t.Errorf("update not stored: %+v", stored)
+			}
+
+			// fn 返回错误时不写入
+			abort := errors.New("abort")
+			_, err = store.Update(ctx, "t1", func(record *Record) error {
+				record.Prompt = "changed"
+				return abort
+			})
+			if !errors.Is(err, abort) {
+				t.Errorf("Update() error = %v, want abort", err)
+			}
+			if stored, _ := store.Get(ctx, "t1"); stored.Prompt != "a cat" {
+				t.Errorf("aborted update stored: %q", stored.Prompt)
+			}
+
+			if _, err := store.Update(ctx, "missing", func(*Record) error { return nil }); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Update(missing) error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestBoltStorePersists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.db")
+
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(ctx, newTestRecord("t1")); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	store, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if record, err := store.Get(ctx, "t1"); err != nil || record.Prompt != "a cat" {
+		t.Errorf("Get() after reopen = %+v, %v", record, err)
+	}
+}
+
+func TestRecordApply(t *testing.T) {
+	record := newTestRecord("t1")
+	if len(record.History) != 1 || record.History[0].Status != generator.StatusGenerating {
+		t.Fatalf("initial history = %+v", record.History)
+	}
+
+	if !record.Apply(&generator.Task{Status: generator.StatusGenerating, Progress: 0.5}) {
+		t.Error("progress change not reported")
+	}
+	if len(record.History) != 1 {
+		t.Errorf("progress added a transition: %+v", record.History)
+	}
+	if record.Apply(&generator.Task{Status: generator.StatusGenerating, Progress: 0.5}) {
+		t.Error("unchanged task reported as changed")
+	}
+
+	record.Apply(&generator.Task{Status: generator.StatusFailed, Error: "bad prompt"})
+	if !record.Done() || record.CompletedAt == nil || len(record.History) != 2 || record.History[1].Error != "bad prompt" {
+		t.Errorf("failed record = %+v", record)
+	}
+
+	// 已结束的任务不再更新
+	if record.Apply(&generator.Task{Status: generator.StatusSucceeded}) || record.Status != generator.StatusFailed {
+		t.Errorf("finished record updated: %+v", record)
+	}
+}
+
+func TestRecordTaskPrefersHostedURLs(t *testing.T) {
+	record := newTestRecord("t1")
+	record.Apply(&generator.Task{Status: generator.StatusSucceeded, ResultURLs: []string{"https://provider.example/r.png"}})
+	if !record.NeedsHosting() {
+		t.Error("succeeded record with results should need hosting")
+	}
+	if urls := record.Task().ResultURLs; urls[0] != "https://provider.example/r.png" {
+		t.Errorf("ResultURLs before hosting = %v", urls)
+	}
+
+	record.HostedURLs = []string{"https://cdn.example/r.png"}
+	if record.NeedsHosting() {
+		t.Error("hosted record should not need hosting")
+	}
+	if urls := record.Task().ResultURLs; urls[0] != "https://cdn.example/r.png" {
+		t.Errorf("ResultURLs after hosting = %v", urls)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("TASK_STORE", "memory")
+	if store, err := New(); err != nil {
+		t.Errorf("New(memory) error = %v", err)
+	} else if _, ok := store.(*MemoryStore); !ok {
+		t.Errorf("New(memory) = %T", store)
+	}
+
+	t.Setenv("TASK_STORE", "bolt")
+	t.Setenv("TASK_DB_PATH", filepath.Join(t.TempDir(), "tasks.db"))
+	store, err := New()
+	if err != nil {
+		t.Fatalf("New(bolt) error = %v", err)
+	}
+	store.Close()
+
+	t.Setenv("TASK_STORE", "other")
+	if _, err := New(); err == nil {
+		t.Error("New(other) should fail")
+	}
+}
@@ -0,0 +1,132 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// deliveryBucket 保存投递记录的bucket，键为投递ID（按创建时间排序），值为JSON
+var deliveryBucket = []byte("deliveries")
+
+// BoltStore 基于BoltDB的投递记录存储，数据保存在单个文件中
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore 打开或创建BoltDB文件，文件被其他进程占用时1秒后返回错误
+func NewBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("创建投递记录目录失败: %v", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("打开投递记录数据库失败: %v", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(deliveryBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化投递记录数据库失败: %v", err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Create 写入新记录
+func (s *BoltStore) Create(ctx context.Context, delivery *Delivery) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(deliveryBucket)
+		if bucket.Get([]byte(delivery.ID)) != nil {
+			return ErrExists
+		}
+		return putDelivery(bucket, delivery)
+	})
+}
+
+// Get 读取记录
+func (s *BoltStore) Get(ctx context.Context, id string) (*Delivery, error) {
+	var delivery *Delivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		delivery, err = getDelivery(tx.Bucket(deliveryBucket), []byte(id))
+		return err
+	})
+	return delivery, err
+}
+
+// Update 在写事务中读取、修改并写回记录
+func (s *BoltStore) Update(ctx context.Context, id string, fn func(*Delivery) error) (*Delivery, error) {
+	var delivery *Delivery
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(deliveryBucket)
+		var err error
+		if delivery, err = getDelivery(bucket, []byte(id)); err != nil {
+			return err
+		}
+		if err := fn(delivery); err != nil {
+			return err
+		}
+		return putDelivery(bucket, delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// List 从最新的记录开始遍历，返回满足条件的记录
+func (s *BoltStore) List(ctx context.Context, opts ListOptions) ([]*Delivery, error) {
+	var deliveries []*Delivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(deliveryBucket)
+		cursor := bucket.Cursor()
+		for key, _ := cursor.Last(); key != nil; key, _ = cursor.Prev() {
+			if opts.Limit > 0 && len(deliveries) >= opts.Limit {
+				break
+			}
+			delivery, err := getDelivery(bucket, key)
+			if err != nil {
+				return err
+			}
+			if opts.Match(delivery) {
+				deliveries = append(deliveries, delivery)
+			}
+		}
+		return nil
+	})
+	return deliveries, err
+}
+
+// Close 关闭数据库文件
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// getDelivery 读取并解析记录
+func getDelivery(bucket *bolt.Bucket, id []byte) (*Delivery, error) {
+	data := bucket.Get(id)
+	if data == nil {
+		return nil, ErrNotFound
+	}
+	var delivery Delivery
+	if err := json.Unmarshal(data, &delivery); err != nil {
+		return nil, fmt.Errorf("解析投递记录失败: %v", err)
+	}
+	return &delivery, nil
+}
+
+// putDelivery 序列化并写入记录
+func putDelivery(bucket *bolt.Bucket, delivery *Delivery) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("序列化投递记录失败: %v", err)
+	}
+	return bucket.Put([]byte(delivery.ID), data)
+}
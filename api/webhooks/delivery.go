@@ -0,0 +1,172 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-api/api/env"
+	"go-api/api/lazy"
+	"os"
+	"strings"
+	"time"
+)
+
+// 投递记录存储后端名称
+const (
+	BackendBolt   = "bolt"
+	BackendMemory = "memory"
+)
+
+// 事件类型
+const (
+	EventGenerationCompleted       = "generation.completed"        // 生成任务结束（成功、失败或取消）
+	EventRemoveBackgroundCompleted = "remove-background.completed" // 背景移除结束（成功或失败）
+)
+
+// 投递状态
+const (
+	StatusPending   = "pending"   // 等待投递或等待下一次重试
+	StatusInFlight  = "in_flight" // 正在投递
+	StatusSucceeded = "succeeded" // 回调地址返回2xx
+	StatusFailed    = "failed"    // 重试次数用完仍未成功
+)
+
+var (
+	// ErrNotFound 投递记录不存在
+	ErrNotFound = errors.New("投递记录不存在")
+	// ErrExists 投递记录已存在
+	ErrExists = errors.New("投递记录已存在")
+	// ErrInFlight 投递记录正在投递
+	ErrInFlight = errors.New("投递记录正在投递")
+)
+
+// Delivery 一次webhook投递，包含每次尝试的结果
+type Delivery struct {
+	ID            string          `json:"id"`
+	Event         string          `json:"event"`  // 见 Event* 常量
+	Client        string          `json:"client"` // 接收方，取自回调地址的域名
+	URL           string          `json:"url"`
+	Payload       json.RawMessage `json:"payload"`
+	Signed        bool            `json:"signed"` // 是否配置了签名密钥
+	Status        string          `json:"status"` // 见 Status* 常量
+	Attempts      []Attempt       `json:"attempts"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	UpdatedAt     time.Time       `json:"updatedAt"`
+	NextAttemptAt *time.Time      `json:"nextAttemptAt,omitempty"` // 下一次重试的时间
+}
+
+// Due 记录是否需要在 now 投递：等待投递且已到重试时间，或投递中断超过 inFlightTimeout
+func (d *Delivery) Due(now time.Time) bool {
+	switch d.Status {
+	case StatusPending:
+		return d.NextAttemptAt == nil || !d.NextAttemptAt.After(now)
+	case StatusInFlight:
+		return !now.Before(d.UpdatedAt.Add(inFlightTimeout))
+	default:
+		return false
+	}
+}
+
+// Attempt 一次投递尝试
+type Attempt struct {
+	Number     int       `json:"number"` // 从1开始，重放时继续累加
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	Response   string    `json:"response,omitempty"` // 响应内容，最多保留 maxResponseLog 字节
+	Replay     bool      `json:"replay,omitempty"`   // 是否由重放触发
+}
+
+// ListOptions 查询投递记录的条件
+type ListOptions struct {
+	Event  string // 为空时不按事件过滤
+	Status string // 为空时不按状态过滤
+	Limit  int    // 最多返回的记录数
+}
+
+// Match 记录是否满足查询条件
+func (o ListOptions) Match(delivery *Delivery) bool {
+	return (o.Event == "" || delivery.Event == o.Event) && (o.Status == "" || delivery.Status == o.Status)
+}
+
+// Store 投递记录存储
+type Store interface {
+	// Create 写入新记录，ID已存在时返回 ErrExists
+	Create(ctx context.Context, delivery *Delivery) error
+	// Get 读取记录，不存在时返回 ErrNotFound
+	Get(ctx context.Context, id string) (*Delivery, error)
+	// Update 在同一事务中读取、修改并写回记录，fn 返回错误时不写入
+	Update(ctx context.Context, id string, fn func(*Delivery) error) (*Delivery, error)
+	// List 按创建时间倒序返回满足条件的记录
+	List(ctx context.Context, opts ListOptions) ([]*Delivery, error)
+	// Close 关闭存储
+	Close() error
+}
+
+// newDeliveryID 生成按创建时间排序的投递ID
+func newDeliveryID() string {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		// 系统随机数源不可用时无法安全生成ID
+		panic(fmt.Sprintf("生成随机数失败: %v", err))
+	}
+	return fmt.Sprintf("whd_%016x%s", time.Now().UnixNano(), hex.EncodeToString(buf))
+}
+
+// defaultDispatcher 进程级共享的投递器
+var defaultDispatcher lazy.Value[*Dispatcher]
+
+// Backend 返回 WEBHOOK_STORE 配置的存储后端名称，默认使用BoltDB
+func Backend() string {
+	backend := strings.ToLower(strings.TrimSpace(os.Getenv("WEBHOOK_STORE")))
+	if backend == "" {
+		return BackendBolt
+	}
+	return backend
+}
+
+// DBPath 返回 WEBHOOK_DB_PATH 配置的BoltDB文件路径，生产环境必须配置
+func DBPath() (string, error) {
+	return env.DataPath("WEBHOOK_DB_PATH", "go-api-webhooks.db")
+}
+
+// NewStore 根据 WEBHOOK_STORE 环境变量创建投递记录存储
+func NewStore() (Store, error) {
+	backend := Backend()
+	switch backend {
+	case BackendBolt:
+		path, err := DBPath()
+		if err != nil {
+			return nil, err
+		}
+		store, err := NewBoltStore(path)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("不支持的投递记录存储: %s", backend)
+	}
+}
+
+// Default 返回使用 WEBHOOK_STORE 存储投递记录的共享投递器
+func Default() (*Dispatcher, error) {
+	return defaultDispatcher.Get(func() (*Dispatcher, error) {
+		store, err := NewStore()
+		if err != nil {
+			return nil, err
+		}
+		return NewDispatcher(store), nil
+	})
+}
+
+// SetDefault 替换共享投递器，可注入使用内存存储的投递器
+func SetDefault(dispatcher *Dispatcher) {
+	defaultDispatcher.Set(dispatcher)
+}
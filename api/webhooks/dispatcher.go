@@ -0,0 +1,327 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-api/api/safehttp"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 重试参数默认值，重试由定时扫描发送，间隔不宜小于扫描周期
+const (
+	defaultMaxAttempts = 5
+	defaultBaseDelay   = 30 * time.Second
+	defaultMaxDelay    = time.Hour
+)
+
+// inFlightTimeout 正在投递的记录超过该时间未更新时视为投递中断，扫描时重新投递
+const inFlightTimeout = 2 * time.Minute
+
+// errNotDue 未到重试时间
+var errNotDue = errors.New("未到重试时间")
+
+// maxResponseLog 投递记录中保留的响应内容字节数
+const maxResponseLog = 512
+
+// 投递请求头
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Dispatcher 签名并投递webhook，每次尝试写入投递记录
+// 非2xx响应时按指数退避加随机抖动计算下一次重试时间，由 SweepDue 发送到期的重试
+type Dispatcher struct {
+	Store       Store
+	Client      *http.Client
+	MaxAttempts int                             // 每轮投递的最多尝试次数
+	BaseDelay   time.Duration                   // 第一次重试前的等待时间，之后每次翻倍
+	MaxDelay    time.Duration                   // 重试等待时间上限
+	Secret      func(callbackURL string) string // 返回回调地址对应的签名密钥，为空时不签名
+}
+
+// SweepResult 一次重试扫描的结果
+type SweepResult struct {
+	Succeeded []string          `json:"succeeded"`
+	Retrying  []string          `json:"retrying"` // 仍未成功，等待下一次扫描
+	Failed    []string          `json:"failed"`   // 本轮尝试次数用完
+	Skipped   int               `json:"skipped"`  // 未到重试时间或正在投递的记录数量
+	Errors    map[string]string `json:"errors,omitempty"`
+}
+
+// NewDispatcher 创建投递器，最多尝试次数可通过 WEBHOOK_MAX_ATTEMPTS 配置
+// 只连接公网地址，配置 WEBHOOK_ALLOWED_HOSTS 时只投递到其中的域名
+func NewDispatcher(store Store) *Dispatcher {
+	maxAttempts := defaultMaxAttempts
+	if value, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS")); err == nil && value > 0 {
+		maxAttempts = value
+	}
+	return &Dispatcher{
+		Store:       store,
+		Client:      safehttp.NewClient(10*time.Second, AllowedHosts()),
+		MaxAttempts: maxAttempts,
+		BaseDelay:   defaultBaseDelay,
+		MaxDelay:    defaultMaxDelay,
+		Secret:      SecretFor,
+	}
+}
+
+// AllowedHosts 返回 WEBHOOK_ALLOWED_HOSTS 配置的回调域名，为空时允许任意公网域名
+func AllowedHosts() []string {
+	return safehttp.ParseHosts(os.Getenv("WEBHOOK_ALLOWED_HOSTS"))
+}
+
+// SecretFor 返回回调地址对应的签名密钥
+// 依次读取 WEBHOOK_SECRETS（格式为 host:secret,host:secret）中与回调地址域名一致的密钥和 WEBHOOK_SECRET；
+// 密钥只按服务端配置的域名选择，提交者无法让其他接收方的密钥签名发往自己地址的内容
+func SecretFor(callbackURL string) string {
+	host := receiverHost(callbackURL)
+	for _, entry := range strings.Split(os.Getenv("WEBHOOK_SECRETS"), ",") {
+		name, secret, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if ok && host != "" && strings.ToLower(strings.Trim(name, ".")) == host && secret != "" {
+			return secret
+		}
+	}
+	return os.Getenv("WEBHOOK_SECRET")
+}
+
+// receiverHost 返回回调地址的域名（小写，不含端口），地址无效时返回空字符串
+func receiverHost(callbackURL string) string {
+	parsed, err := url.Parse(callbackURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+}
+
+// Sign 计算签名：对 "<timestamp>.<body>" 做 HMAC-SHA256，返回十六进制字符串
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureHeader 构建 X-Webhook-Signature 请求头：t=<Unix秒>,v1=<签名>
+func SignatureHeader(secret string, timestamp int64, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, Sign(secret, timestamp, body))
+}
+
+// Dispatch 写入投递记录并立即尝试一次投递，返回最新记录
+// 失败时在记录中写入下一次重试时间，后续重试由 SweepDue 发送；尝试失败不返回错误
+func (d *Dispatcher) Dispatch(ctx context.Context, event, callbackURL string, payload interface{}) (*Delivery, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("序列化投递内容失败: %v", err)
+	}
+
+	now := time.Now().UTC()
+	delivery := &Delivery{
+		ID:            newDeliveryID(),
+		Event:         event,
+		Client:        receiverHost(callbackURL),
+		URL:           callbackURL,
+		Payload:       body,
+		Signed:        d.Secret(callbackURL) != "",
+		Status:        StatusPending,
+		Attempts:      []Attempt{},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		NextAttemptAt: &now,
+	}
+	if err := d.Store.Create(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("写入投递记录失败: %v", err)
+	}
+
+	updated, err := d.run(ctx, delivery.ID, false)
+	if err != nil {
+		// 记录仍为 pending 且已到重试时间，下一次扫描时投递
+		log.Printf("webhook投递失败: %s: %v", delivery.ID, err)
+		return delivery, nil
+	}
+	return updated, nil
+}
+
+// Replay 使用原内容和当前密钥重新投递一次并开始新一轮重试，返回最新记录
+// 记录正在投递时返回 ErrInFlight
+func (d *Dispatcher) Replay(ctx context.Context, id string) (*Delivery, error) {
+	return d.run(ctx, id, true)
+}
+
+// SweepDue 发送到期的重试，最多处理 limit 条记录，limit 不大于0时不限制
+// 同时重新投递中断超过 inFlightTimeout 的记录；记录先标记为 in_flight，并发扫描不会重复投递
+func (d *Dispatcher) SweepDue(ctx context.Context, now time.Time, limit int) (SweepResult, error) {
+	result := SweepResult{Succeeded: []string{}, Retrying: []string{}, Failed: []string{}, Errors: map[string]string{}}
+	processed := 0
+	for _, status := range []string{StatusPending, StatusInFlight} {
+		deliveries, err := d.Store.List(ctx, ListOptions{Status: status})
+		if err != nil {
+			return result, fmt.Errorf("读取投递记录失败: %v", err)
+		}
+		for _, delivery := range deliveries {
+			if !delivery.Due(now) {
+				result.Skipped++
+				continue
+			}
+			if limit > 0 && processed >= limit {
+				return result, nil
+			}
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			processed++
+
+			updated, err := d.run(ctx, delivery.ID, false)
+			switch {
+			case errors.Is(err, ErrInFlight), errors.Is(err, errNotDue):
+				result.Skipped++
+			case err != nil:
+				result.Errors[delivery.ID] = err.Error()
+			case updated.Status == StatusSucceeded:
+				result.Succeeded = append(result.Succeeded, updated.ID)
+			case updated.Status == StatusFailed:
+				result.Failed = append(result.Failed, updated.ID)
+			default:
+				result.Retrying = append(result.Retrying, updated.ID)
+			}
+		}
+	}
+	return result, nil
+}
+
+// run 将记录标记为 in_flight 后尝试一次投递，按结果写入状态和下一次重试时间，返回最新记录
+// 记录正在投递时返回 ErrInFlight；非重放时记录未到重试时间返回 errNotDue
+func (d *Dispatcher) run(ctx context.Context, id string, replay bool) (*Delivery, error) {
+	// 请求结束后仍完成本次尝试，避免记录停留在 in_flight
+	ctx = context.WithoutCancel(ctx)
+
+	now := time.Now().UTC()
+	delivery, err := d.Store.Update(ctx, id, func(delivery *Delivery) error {
+		if delivery.Status == StatusInFlight && now.Before(delivery.UpdatedAt.Add(inFlightTimeout)) {
+			return ErrInFlight
+		}
+		if !replay && !delivery.Due(now) {
+			return errNotDue
+		}
+		delivery.Status = StatusInFlight
+		delivery.Signed = d.Secret(delivery.URL) != ""
+		delivery.UpdatedAt = now
+		delivery.NextAttemptAt = nil
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	round := roundAttempts(delivery.Attempts) + 1
+	if replay {
+		round = 1
+	}
+	attempt := d.attempt(ctx, delivery, len(delivery.Attempts)+1)
+	attempt.Replay = replay
+	succeeded := attempt.Error == "" && attempt.StatusCode >= 200 && attempt.StatusCode < 300
+
+	var wait time.Duration
+	status := StatusPending
+	switch {
+	case succeeded:
+		status = StatusSucceeded
+	case round >= max(1, d.MaxAttempts):
+		status = StatusFailed
+	default:
+		wait = d.backoff(round)
+	}
+
+	updated, err := d.Store.Update(ctx, id, func(delivery *Delivery) error {
+		now := time.Now().UTC()
+		delivery.Attempts = append(delivery.Attempts, attempt)
+		delivery.Status = status
+		delivery.UpdatedAt = now
+		delivery.NextAttemptAt = nil
+		if status == StatusPending {
+			next := now.Add(wait)
+			delivery.NextAttemptAt = &next
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("更新投递记录失败: %v", err)
+	}
+	if status == StatusFailed {
+		log.Printf("webhook投递失败: %s %s: 本轮已尝试 %d 次", updated.ID, updated.URL, round)
+	}
+	return updated, nil
+}
+
+// roundAttempts 返回本轮已尝试的次数，重放开始新的一轮
+func roundAttempts(attempts []Attempt) int {
+	for i := len(attempts) - 1; i >= 0; i-- {
+		if attempts[i].Replay {
+			return len(attempts) - i
+		}
+	}
+	return len(attempts)
+}
+
+// attempt 发送一次签名请求并返回结果
+// 每次尝试使用当前时间签名，接收方可据此拒绝过期的请求
+func (d *Dispatcher) attempt(ctx context.Context, delivery *Delivery, number int) (attempt Attempt) {
+	start := time.Now()
+	attempt = Attempt{Number: number, At: start.UTC()}
+	defer func() {
+		attempt.DurationMs = time.Since(start).Milliseconds()
+	}()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = fmt.Sprintf("创建请求失败: %v", err)
+		return attempt
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderID, delivery.ID)
+	request.Header.Set(HeaderEvent, delivery.Event)
+	if secret := d.Secret(delivery.URL); secret != "" {
+		request.Header.Set(HeaderSignature, SignatureHeader(secret, start.Unix(), delivery.Payload))
+	}
+
+	response, err := d.Client.Do(request)
+	if err != nil {
+		attempt.Error = fmt.Sprintf("发送请求失败: %v", err)
+		return attempt
+	}
+	defer response.Body.Close()
+
+	attempt.StatusCode = response.StatusCode
+	content, _ := io.ReadAll(io.LimitReader(response.Body, maxResponseLog))
+	attempt.Response = string(content)
+	return attempt
+}
+
+// backoff 返回第 n 次失败后的等待时间：BaseDelay*2^(n-1)，不超过 MaxDelay，
+// 并在后一半区间内随机抖动，避免大量重试同时到达
+func (d *Dispatcher) backoff(n int) time.Duration {
+	delay := d.MaxDelay
+	if shift := n - 1; shift < 32 && d.BaseDelay<<shift > 0 && d.BaseDelay<<shift < d.MaxDelay {
+		delay = d.BaseDelay << shift
+	}
+	if delay <= 1 {
+		return delay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)))
+}
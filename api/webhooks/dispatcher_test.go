@@ -0,0 +1,153 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      string
+		want      string
+	}{
+		{"json", "secret", 1700000000, `{"a":1}`, "49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686"},
+		{"empty", "", 0, "", "b849d5a581847b281957065739df36df2463d1977ea8d6e1e4e6cf33fadc68c3"},
+		{"text", "k", 1, "hello", "993e991279fd1820ecdbd02d99636b217bb0e62646d3088ae56c3fce5260bd3f"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Errorf("Sign() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignatureHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      string
+		want      string
+	}{
+		{"json", "secret", 1700000000, `{"a":1}`, "t=1700000000,v1=49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686"},
+		{"text", "k", 1, "hello", "t=1,v1=993e991279fd1820ecdbd02d99636b217bb0e62646d3088ae56c3fce5260bd3f"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SignatureHeader(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Errorf("SignatureHeader() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignDependsOnInputs(t *testing.T) {
+	base := Sign("secret", 1700000000, []byte("body"))
+	tests := []struct {
+		name string
+		got  string
+	}{
+		{"secret", Sign("other", 1700000000, []byte("body"))},
+		{"timestamp", Sign("secret", 1700000001, []byte("body"))},
+		{"body", Sign("secret", 1700000000, []byte("body2"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got == base {
+				t.Errorf("changing %s did not change the signature", tt.name)
+			}
+		})
+	}
+}
+
+func TestSecretFor(t *testing.T) {
+	t.Setenv("WEBHOOK_SECRETS", "hooks.a.com:secret_a, Hooks.B.com.:secret_b,empty.com:")
+	t.Setenv("WEBHOOK_SECRET", "default")
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://hooks.a.com/callback", "secret_a"},
+		{"https://HOOKS.A.COM:8443/callback", "secret_a"},
+		{"https://hooks.b.com/callback", "secret_b"},
+		// 只按完全一致的域名选择，子域名和相似域名使用默认密钥
+		{"https://x.hooks.a.com/callback", "default"},
+		{"https://hooks.a.com.evil.com/callback", "default"},
+		{"https://evil.com/hooks.a.com", "default"},
+		{"https://empty.com/callback", "default"},
+		{"://invalid", "default"},
+	}
+	for _, tt := range tests {
+		if got := SecretFor(tt.url); got != tt.want {
+			t.Errorf("SecretFor(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestDispatchSignsByCallbackHost(t *testing.T) {
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(HeaderSignature)
+	}))
+	defer server.Close()
+	t.Setenv("WEBHOOK_SECRETS", "127.0.0.1:local_secret")
+	t.Setenv("WEBHOOK_SECRET", "")
+
+	dispatcher := NewDispatcher(NewMemoryStore())
+	// 测试服务监听回环地址，不使用只连接公网地址的客户端
+	dispatcher.Client = server.Client()
+
+	delivery, err := dispatcher.Dispatch(context.Background(), EventGenerationCompleted, server.URL+"/hook", map[string]string{"taskId": "t1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != StatusSucceeded || delivery.Client != "127.0.0.1" || !delivery.Signed {
+		t.Fatalf("delivery = %+v", delivery)
+	}
+	var timestamp int64
+	var got string
+	if _, err := fmt.Sscanf(strings.Replace(signature, ",v1=", " ", 1), "t=%d %s", &timestamp, &got); err != nil {
+		t.Fatalf("signature header %q: %v", signature, err)
+	}
+	if got != Sign("local_secret", timestamp, delivery.Payload) {
+		t.Errorf("signature %q not made with the callback host's secret", signature)
+	}
+	if time.Since(time.Unix(timestamp, 0)) > time.Minute {
+		t.Errorf("signature timestamp %d too old", timestamp)
+	}
+}
+
+func TestDispatchWithoutSecret(t *testing.T) {
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(HeaderSignature)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	t.Setenv("WEBHOOK_SECRETS", "hooks.a.com:secret_a")
+	t.Setenv("WEBHOOK_SECRET", "")
+
+	dispatcher := NewDispatcher(NewMemoryStore())
+	dispatcher.Client = server.Client()
+
+	delivery, err := dispatcher.Dispatch(context.Background(), EventGenerationCompleted, server.URL, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature != "" || delivery.Signed {
+		t.Errorf("unconfigured host signed: header %q, delivery %+v", signature, delivery)
+	}
+	if delivery.Status != StatusPending || delivery.NextAttemptAt == nil || len(delivery.Attempts) != 1 {
+		t.Errorf("failed delivery = %+v, want pending retry", delivery)
+	}
+}
@@ -0,0 +1,112 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// MemoryStore 进程内的投递记录存储，重启后丢失，用于测试和离线开发
+// 保存序列化后的记录，调用方修改返回的记录不影响已保存的内容
+type MemoryStore struct {
+	mu         sync.Mutex
+	deliveries map[string][]byte
+}
+
+// NewMemoryStore 创建进程内投递记录存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{deliveries: map[string][]byte{}}
+}
+
+// Create 写入新记录
+func (s *MemoryStore) Create(ctx context.Context, delivery *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deliveries[delivery.ID]; ok {
+		return ErrExists
+	}
+	return s.put(delivery)
+}
+
+// Get 读取记录
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.get(id)
+}
+
+// Update 加锁后读取、修改并写回记录
+func (s *MemoryStore) Update(ctx context.Context, id string, fn func(*Delivery) error) (*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(delivery); err != nil {
+		return nil, err
+	}
+	if err := s.put(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// List 按ID倒序（即创建时间倒序）返回满足条件的记录
+func (s *MemoryStore) List(ctx context.Context, opts ListOptions) ([]*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.deliveries))
+	for id := range s.deliveries {
+		ids = append(ids, id)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+	var deliveries []*Delivery
+	for _, id := range ids {
+		if opts.Limit > 0 && len(deliveries) >= opts.Limit {
+			break
+		}
+		delivery, err := s.get(id)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Match(delivery) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+// Close 无需释放资源
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// get 读取并解析记录，调用方需持有锁
+func (s *MemoryStore) get(id string) (*Delivery, error) {
+	data, ok := s.deliveries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var delivery Delivery
+	if err := json.Unmarshal(data, &delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// put 序列化并保存记录，调用方需持有锁
+func (s *MemoryStore) put(delivery *Delivery) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	s.deliveries[delivery.ID] = data
+	return nil
+}
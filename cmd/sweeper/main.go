@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go-api/api/storage"
+)
+
+// 清理存储中已过期的对象，可由 cron 等定时任务调用
+func main() {
+	store, err := storage.Default()
+	if err != nil {
+		log.Fatalf("❌ 创建存储客户端失败: %v", err)
+	}
+
+	result, err := storage.SweepExpired(context.Background(), store, time.Now())
+	if err != nil {
+		log.Fatalf("❌ 清理过期对象失败: %v", err)
+	}
+
+	for _, key := range result.Deleted {
+		fmt.Printf("🗑️  已删除: %s\n", key)
+	}
+	for key, message := range result.Errors {
+		fmt.Printf("❌ 删除失败: %s (%s)\n", key, message)
+	}
+	fmt.Printf("✨ 清理完成：删除 %d 个，跳过 %d 个，失败 %d 个\n", len(result.Deleted), result.Skipped, len(result.Errors))
+}
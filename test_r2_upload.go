@@ -6,7 +6,7 @@ import (
 	"os"
 	"path/filepath"
 
-	"go-api/api/controllers"
+	"go-api/api/storage"
 )
 
 func main() {
@@ -31,7 +31,7 @@ func main() {
 
 	// 创建R2客户端
 	fmt.Println("\n🔗 连接到Cloudflare R2...")
-	r2Client, err := controllers.NewR2Client()
+	r2Client, err := storage.NewR2Client()
 	if err != nil {
 		log.Fatalf("❌ 创建R2客户端失败: %v", err)
 	}